@@ -0,0 +1,41 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fileAccess is what the user the provider acts as on the target host may
+// do with a path.
+type fileAccess struct {
+	user       string
+	readable   bool
+	writable   bool
+	executable bool
+	owned      bool
+}
+
+// accessScript prints the name of the user running it, then whether the
+// path in $1 is readable, writable, executable and owned by that user, one
+// per line as 1 or 0. test(1) decides with the effective IDs, as the kernel
+// does when the path is used.
+const accessScript = `[ -e "$1" ] || { echo "$1: No such file or directory" >&2; exit 1; }
+id -un
+for op in r w x O; do
+	if [ -$op "$1" ]; then echo 1; else echo 0; fi
+done`
+
+// parseAccess parses the output of accessScript.
+func parseAccess(out string) (*fileAccess, error) {
+	lines := strings.Fields(out)
+	if len(lines) != 5 {
+		return nil, fmt.Errorf("unexpected output of access check: %q", out)
+	}
+	return &fileAccess{
+		user:       lines[0],
+		readable:   lines[1] == "1",
+		writable:   lines[2] == "1",
+		executable: lines[3] == "1",
+		owned:      lines[4] == "1",
+	}, nil
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+package provider
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// localAccess asks the kernel with the effective IDs of the provider, so
+// that ACLs, read-only mounts and the privileges of root are accounted for.
+func localAccess(path string) (*fileAccess, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	euid := os.Geteuid()
+	uid, _, ok := localFileOwner(info)
+	access := &fileAccess{
+		user:  userName(euid),
+		owned: ok && uid == euid,
+	}
+	for mode, allowed := range map[uint32]*bool{
+		unix.R_OK: &access.readable,
+		unix.W_OK: &access.writable,
+		unix.X_OK: &access.executable,
+	} {
+		*allowed = unix.Faccessat(unix.AT_FDCWD, path, mode, unix.AT_EACCESS) == nil
+	}
+	return access, nil
+}
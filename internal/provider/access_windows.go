@@ -0,0 +1,53 @@
+//go:build windows
+
+package provider
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// localAccess approximates access on Windows, where the ACLs that decide it
+// aren't evaluated: a path is readable when it opens, writable unless it is
+// read-only and executable when it is a directory or has an extension in
+// PATHEXT. It is owned when its owner is the user running the provider.
+func localAccess(path string) (*fileAccess, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	access := &fileAccess{writable: info.Mode().Perm()&0200 != 0}
+	if u, err := user.Current(); err == nil {
+		access.user = u.Username
+	}
+
+	if f, err := os.Open(path); err == nil {
+		f.Close()
+		access.readable = true
+	}
+
+	if info.IsDir() {
+		access.executable = true
+	} else {
+		ext := strings.ToLower(filepath.Ext(path))
+		for _, e := range filepath.SplitList(strings.ToLower(os.Getenv("PATHEXT"))) {
+			if ext != "" && ext == e {
+				access.executable = true
+			}
+		}
+	}
+
+	tu, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return access, nil
+	}
+	if acl, err := getACL(path); err == nil {
+		access.owned = acl.owner == tu.User.Sid.String()
+	}
+	return access, nil
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// accountFiles resolves users and groups of a remote target from its
+// /etc/passwd and /etc/group, which are read once per provider instance.
+type accountFiles struct {
+	once       sync.Once
+	uids       map[string]int
+	userNames  map[int]string
+	gids       map[string]int
+	groupNames map[int]string
+}
+
+func (a *accountFiles) load(fsys fileSystem) {
+	a.once.Do(func() {
+		a.uids, a.userNames = parseAccountFile(fsys, "/etc/passwd")
+		a.gids, a.groupNames = parseAccountFile(fsys, "/etc/group")
+	})
+}
+
+func (a *accountFiles) lookupUID(fsys fileSystem, owner string) (int, error) {
+	if id, err := strconv.Atoi(owner); err == nil {
+		return id, nil
+	}
+
+	a.load(fsys)
+	id, ok := a.uids[owner]
+	if !ok {
+		return 0, fmt.Errorf("error looking up user %s: unknown user on remote host", owner)
+	}
+	return id, nil
+}
+
+func (a *accountFiles) lookupGID(fsys fileSystem, group string) (int, error) {
+	if id, err := strconv.Atoi(group); err == nil {
+		return id, nil
+	}
+
+	a.load(fsys)
+	id, ok := a.gids[group]
+	if !ok {
+		return 0, fmt.Errorf("error looking up group %s: unknown group on remote host", group)
+	}
+	return id, nil
+}
+
+func (a *accountFiles) userName(fsys fileSystem, uid int) string {
+	a.load(fsys)
+	if name, ok := a.userNames[uid]; ok {
+		return name
+	}
+	return strconv.Itoa(uid)
+}
+
+func (a *accountFiles) groupName(fsys fileSystem, gid int) string {
+	a.load(fsys)
+	if name, ok := a.groupNames[gid]; ok {
+		return name
+	}
+	return strconv.Itoa(gid)
+}
+
+// parseAccountFile maps names to IDs and back from a colon-separated file
+// in the passwd(5)/group(5) format. Hosts using other name services only
+// resolve numeric IDs.
+func parseAccountFile(fsys fileSystem, path string) (map[string]int, map[int]string) {
+	ids := map[string]int{}
+	names := map[int]string{}
+
+	content, err := fsys.ReadFile(path)
+	if err != nil {
+		return ids, names
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 3 {
+			continue
+		}
+		id, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+
+		ids[fields[0]] = id
+		if _, ok := names[id]; !ok {
+			names[id] = fields[0]
+		}
+	}
+	return ids, names
+}
@@ -0,0 +1,271 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// fileACL is the owner and the explicit, non-inherited access control
+// entries of a file or directory on Windows.
+type fileACL struct {
+	// owner is a SID string. An empty owner is left unchanged.
+	owner     string
+	protected bool
+	entries   []aclEntry
+}
+
+type aclEntry struct {
+	sid         string
+	deny        bool
+	mask        uint32
+	inheritance uint8
+}
+
+// aclRights are the standard file rights shown in the Windows security
+// dialog. Any other access mask can be given in hexadecimal.
+var aclRights = map[string]uint32{
+	"full_control":     0x1f01ff,
+	"modify":           0x1301bf,
+	"read_and_execute": 0x1200a9,
+	"read":             0x120089,
+	"write":            0x100116,
+}
+
+var aclInheritance = map[string]uint8{
+	"object":       0x1,
+	"container":    0x2,
+	"no_propagate": 0x4,
+	"inherit_only": 0x8,
+}
+
+func aclSchema() *schema.Schema {
+	inheritance := make([]string, 0, len(aclInheritance))
+	for k := range aclInheritance {
+		inheritance = append(inheritance, k)
+	}
+	sort.Strings(inheritance)
+
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: "The Windows owner and access control list. Only supported on Windows",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"owner": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "The owning account name (e.g., 'BUILTIN\\Administrators') or SID",
+				},
+				"protected": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Default:     false,
+					Description: "Don't inherit entries from the parent directory",
+				},
+				"entry": {
+					Type:        schema.TypeSet,
+					Optional:    true,
+					Description: "Explicit access control entries. Inherited entries are not managed",
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"principal": {
+								Type:        schema.TypeString,
+								Required:    true,
+								Description: "The account name or SID the entry applies to",
+							},
+							"type": {
+								Type:         schema.TypeString,
+								Optional:     true,
+								Default:      "allow",
+								ValidateFunc: validation.StringInSlice([]string{"allow", "deny"}, false),
+								Description:  "Either 'allow' or 'deny'",
+							},
+							"rights": {
+								Type:     schema.TypeSet,
+								Required: true,
+								Elem: &schema.Schema{
+									Type:         schema.TypeString,
+									ValidateFunc: validateACLRight,
+								},
+								Description: "'full_control', 'modify', 'read_and_execute', 'read', 'write' or a hexadecimal access mask (e.g., '0x1f01ff')",
+							},
+							"inheritance": {
+								Type:     schema.TypeSet,
+								Optional: true,
+								Elem: &schema.Schema{
+									Type:         schema.TypeString,
+									ValidateFunc: validation.StringInSlice(inheritance, false),
+								},
+								Description: "Any of 'object', 'container', 'no_propagate' and 'inherit_only'",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func parseACLRight(right string) (uint32, error) {
+	if mask, ok := aclRights[right]; ok {
+		return mask, nil
+	}
+	if strings.HasPrefix(right, "0x") {
+		mask, err := strconv.ParseUint(right[2:], 16, 32)
+		if err == nil {
+			return uint32(mask), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid access right: %s", right)
+}
+
+func validateACLRight(v interface{}, k string) ([]string, []error) {
+	if _, err := parseACLRight(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: %s", k, err)}
+	}
+	return nil, nil
+}
+
+// aclEntryFromMap resolves a configured "entry" block.
+func aclEntryFromMap(fsys fileSystem, m map[string]interface{}) (aclEntry, error) {
+	sid, err := fsys.LookupSID(m["principal"].(string))
+	if err != nil {
+		return aclEntry{}, err
+	}
+
+	entry := aclEntry{sid: sid, deny: m["type"].(string) == "deny"}
+	for _, right := range m["rights"].(*schema.Set).List() {
+		mask, err := parseACLRight(right.(string))
+		if err != nil {
+			return aclEntry{}, err
+		}
+		entry.mask |= mask
+	}
+	if v, ok := m["inheritance"].(*schema.Set); ok {
+		for _, flag := range v.List() {
+			entry.inheritance |= aclInheritance[flag.(string)]
+		}
+	}
+
+	return entry, nil
+}
+
+// aclEntryToMap is the inverse of aclEntryFromMap, using the SID as the
+// principal and the shortest spelling of the rights.
+func aclEntryToMap(entry aclEntry) map[string]interface{} {
+	entryType := "allow"
+	if entry.deny {
+		entryType = "deny"
+	}
+
+	rights := []interface{}{fmt.Sprintf("0x%x", entry.mask)}
+	for name, mask := range aclRights {
+		if mask == entry.mask {
+			rights = []interface{}{name}
+		}
+	}
+
+	inheritance := []interface{}{}
+	for name, flag := range aclInheritance {
+		if entry.inheritance&flag != 0 {
+			inheritance = append(inheritance, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"principal":   entry.sid,
+		"type":        entryType,
+		"rights":      rights,
+		"inheritance": inheritance,
+	}
+}
+
+// aclFromResourceData resolves the "acl" block, returning nil when it isn't
+// set.
+func aclFromResourceData(fsys fileSystem, d *schema.ResourceData) (*fileACL, error) {
+	v := d.Get("acl").([]interface{})
+	if len(v) == 0 || v[0] == nil {
+		return nil, nil
+	}
+	m := v[0].(map[string]interface{})
+
+	acl := &fileACL{protected: m["protected"].(bool)}
+	if owner := m["owner"].(string); owner != "" {
+		sid, err := fsys.LookupSID(owner)
+		if err != nil {
+			return nil, err
+		}
+		acl.owner = sid
+	}
+
+	for _, e := range m["entry"].(*schema.Set).List() {
+		entry, err := aclEntryFromMap(fsys, e.(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+		acl.entries = append(acl.entries, entry)
+	}
+
+	return acl, nil
+}
+
+// setACLFromResourceData applies the "acl" block, if any.
+func setACLFromResourceData(fsys fileSystem, d *schema.ResourceData, path string) error {
+	acl, err := aclFromResourceData(fsys, d)
+	if err != nil || acl == nil {
+		return err
+	}
+	return fsys.SetACL(path, acl)
+}
+
+// readACLIntoResourceData reports drift of the configured "acl" block.
+// Entries are kept as configured while they match an entry on disk; any
+// other entry on disk is recorded by SID.
+func readACLIntoResourceData(fsys fileSystem, d *schema.ResourceData, path string) diag.Diagnostics {
+	v := d.Get("acl").([]interface{})
+	if len(v) == 0 || v[0] == nil {
+		return nil
+	}
+	configured := v[0].(map[string]interface{})
+
+	acl, err := fsys.GetACL(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading ACL of %s: %s", path, err))
+	}
+
+	owner := configured["owner"].(string)
+	if owner != "" {
+		if sid, err := fsys.LookupSID(owner); err != nil || sid != acl.owner {
+			owner = acl.owner
+		}
+	}
+
+	known := map[aclEntry]interface{}{}
+	for _, e := range configured["entry"].(*schema.Set).List() {
+		if entry, err := aclEntryFromMap(fsys, e.(map[string]interface{})); err == nil {
+			known[entry] = e
+		}
+	}
+
+	entries := []interface{}{}
+	for _, entry := range acl.entries {
+		if e, ok := known[entry]; ok {
+			entries = append(entries, e)
+			continue
+		}
+		entries = append(entries, aclEntryToMap(entry))
+	}
+
+	return diag.FromErr(d.Set("acl", []interface{}{map[string]interface{}{
+		"owner":     owner,
+		"protected": acl.protected,
+		"entry":     entries,
+	}}))
+}
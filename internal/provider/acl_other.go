@@ -0,0 +1,20 @@
+//go:build !windows
+
+package provider
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func getACL(path string) (*fileACL, error) {
+	return nil, fmt.Errorf("ACLs are not supported on %s", runtime.GOOS)
+}
+
+func setACL(path string, acl *fileACL) error {
+	return fmt.Errorf("ACLs are not supported on %s", runtime.GOOS)
+}
+
+func lookupSID(account string) (string, error) {
+	return "", fmt.Errorf("ACLs are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,129 @@
+//go:build windows
+
+package provider
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+func getACL(path string) (*fileACL, error) {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return nil, err
+	}
+
+	acl := &fileACL{}
+
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return nil, err
+	}
+	if owner != nil {
+		acl.owner = owner.String()
+	}
+
+	control, _, err := sd.Control()
+	if err != nil {
+		return nil, err
+	}
+	acl.protected = control&windows.SE_DACL_PROTECTED != 0
+
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return nil, err
+	}
+	if dacl == nil {
+		return acl, nil
+	}
+
+	for i := uint32(0); i < uint32(dacl.AceCount); i++ {
+		var ace *windows.ACCESS_ALLOWED_ACE
+		if err := windows.GetAce(dacl, i, &ace); err != nil {
+			return nil, err
+		}
+
+		// Only explicit allow and deny entries are managed
+		if ace.Header.AceFlags&windows.INHERITED_ACE != 0 {
+			continue
+		}
+		if ace.Header.AceType != windows.ACCESS_ALLOWED_ACE_TYPE && ace.Header.AceType != windows.ACCESS_DENIED_ACE_TYPE {
+			continue
+		}
+
+		sid := (*windows.SID)(unsafe.Pointer(&ace.SidStart))
+		acl.entries = append(acl.entries, aclEntry{
+			sid:         sid.String(),
+			deny:        ace.Header.AceType == windows.ACCESS_DENIED_ACE_TYPE,
+			mask:        uint32(ace.Mask),
+			inheritance: ace.Header.AceFlags & (windows.OBJECT_INHERIT_ACE | windows.CONTAINER_INHERIT_ACE | windows.NO_PROPAGATE_INHERIT_ACE | windows.INHERIT_ONLY_ACE),
+		})
+	}
+
+	return acl, nil
+}
+
+func setACL(path string, acl *fileACL) error {
+	entries := make([]windows.EXPLICIT_ACCESS, 0, len(acl.entries))
+	for _, entry := range acl.entries {
+		sid, err := windows.StringToSid(entry.sid)
+		if err != nil {
+			return err
+		}
+
+		mode := windows.ACCESS_MODE(windows.GRANT_ACCESS)
+		if entry.deny {
+			mode = windows.DENY_ACCESS
+		}
+
+		entries = append(entries, windows.EXPLICIT_ACCESS{
+			AccessPermissions: windows.ACCESS_MASK(entry.mask),
+			AccessMode:        mode,
+			Inheritance:       uint32(entry.inheritance),
+			Trustee: windows.TRUSTEE{
+				TrusteeForm:  windows.TRUSTEE_IS_SID,
+				TrusteeType:  windows.TRUSTEE_IS_UNKNOWN,
+				TrusteeValue: windows.TrusteeValueFromSID(sid),
+			},
+		})
+	}
+
+	// Entries are put in canonical order: deny before allow
+	dacl, err := windows.ACLFromEntries(entries, nil)
+	if err != nil {
+		return err
+	}
+
+	info := windows.SECURITY_INFORMATION(windows.DACL_SECURITY_INFORMATION)
+	if acl.protected {
+		info |= windows.PROTECTED_DACL_SECURITY_INFORMATION
+	} else {
+		info |= windows.UNPROTECTED_DACL_SECURITY_INFORMATION
+	}
+
+	var owner *windows.SID
+	if acl.owner != "" {
+		owner, err = windows.StringToSid(acl.owner)
+		if err != nil {
+			return err
+		}
+		info |= windows.OWNER_SECURITY_INFORMATION
+	}
+
+	return windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, info, owner, nil, dacl, nil)
+}
+
+// lookupSID resolves an account name or SID string to a SID string.
+func lookupSID(account string) (string, error) {
+	if sid, err := windows.StringToSid(account); err == nil {
+		return sid.String(), nil
+	}
+
+	sid, _, _, err := windows.LookupSID("", account)
+	if err != nil {
+		return "", fmt.Errorf("error looking up account %s: %s", account, err)
+	}
+	return sid.String(), nil
+}
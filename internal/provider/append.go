@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"os"
+	"strings"
+)
+
+// appendContent returns data with the last occurrence of old taken out and
+// content added at the end, starting on a line of its own. Everything else
+// in data is kept as it is.
+func appendContent(data, old, content string) string {
+	data = removeAppended(data, old)
+	if data != "" && !strings.HasSuffix(data, "\n") {
+		data += "\n"
+	}
+	return data + content
+}
+
+// removeAppended takes the last occurrence of content out of data.
+func removeAppended(data, content string) string {
+	if content == "" {
+		return data
+	}
+	if i := strings.LastIndex(data, content); i >= 0 {
+		return data[:i] + data[i+len(content):]
+	}
+	return data
+}
+
+// appendedTail returns the end of data that content, when appended, takes
+// up.
+func appendedTail(data, content string) string {
+	if len(data) < len(content) {
+		return data
+	}
+	return data[len(data)-len(content):]
+}
+
+// readExisting returns the decoded content of path, or nothing when it
+// doesn't exist yet.
+func readExisting(fsys fileSystem, path string, codec textCodec) (string, error) {
+	data, err := fsys.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return codec.decode(data)
+}
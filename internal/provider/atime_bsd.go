@@ -0,0 +1,19 @@
+//go:build darwin || freebsd || netbsd
+
+package provider
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// localFileAccessTime returns the last access time of a file, falling back to
+// the modification time when it isn't available.
+func localFileAccessTime(info os.FileInfo) time.Time {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(int64(stat.Atimespec.Sec), int64(stat.Atimespec.Nsec))
+}
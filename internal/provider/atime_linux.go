@@ -0,0 +1,19 @@
+//go:build linux
+
+package provider
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// localFileAccessTime returns the last access time of a file, falling back to
+// the modification time when it isn't available.
+func localFileAccessTime(info os.FileInfo) time.Time {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec))
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !netbsd
+
+package provider
+
+import (
+	"os"
+	"time"
+)
+
+// localFileAccessTime falls back to the modification time on platforms where
+// the access time isn't exposed.
+func localFileAccessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
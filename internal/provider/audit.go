@@ -0,0 +1,181 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// auditLog appends a JSON line for every create, update and delete to a
+// file on the machine running Terraform. A nil log records nothing.
+type auditLog struct {
+	path  string
+	runID string
+	mu    sync.Mutex
+}
+
+// auditEntry is a line of the audit log. Modes and checksums are those of
+// the path before and after the operation, and are left out when it didn't
+// exist. Checksums are only taken of regular files.
+type auditEntry struct {
+	Time      string `json:"time"`
+	RunID     string `json:"run_id"`
+	Resource  string `json:"resource"`
+	Operation string `json:"operation"`
+	Path      string `json:"path"`
+	OldMode   string `json:"old_mode,omitempty"`
+	NewMode   string `json:"new_mode,omitempty"`
+	OldSHA256 string `json:"old_sha256,omitempty"`
+	NewSHA256 string `json:"new_sha256,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// newRunID returns the ID of the Terraform run entries are recorded for:
+// that of the HCP Terraform run, or one generated when the provider starts.
+func newRunID() string {
+	if id := os.Getenv("TFC_RUN_ID"); id != "" {
+		return id
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// snapshotPath returns the permissions of path, and the checksum of its
+// content when it is a regular file. Both are empty when it can't be read.
+func snapshotPath(fsys fileSystem, path string) (mode string, sum string) {
+	info, err := fsys.Lstat(path)
+	if err != nil {
+		return "", ""
+	}
+	mode = formatPermissions(info.Mode())
+	if info.Mode().IsRegular() {
+		if b, err := checksumFile(fsys, path, sha256.New()); err == nil {
+			sum = hex.EncodeToString(b)
+		}
+	}
+	return mode, sum
+}
+
+// begin snapshots path before resource applies operation to it.
+func (a *auditLog) begin(fsys fileSystem, resource, operation, path string) *auditEntry {
+	if a == nil {
+		return nil
+	}
+	e := &auditEntry{RunID: a.runID, Resource: resource, Operation: operation, Path: path}
+	e.OldMode, e.OldSHA256 = snapshotPath(fsys, path)
+	return e
+}
+
+// end snapshots the path of e again once the operation is done, failed
+// with err or not, and appends e to the log.
+func (a *auditLog) end(fsys fileSystem, e *auditEntry, err error) error {
+	if a == nil || e == nil {
+		return nil
+	}
+	e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	e.NewMode, e.NewSHA256 = snapshotPath(fsys, e.Path)
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	// Entries are written with a single append each, so that they don't
+	// interleave with those of other resources or processes
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// record applies operation to path with apply, and records it in the log.
+// An error writing the log is returned when apply succeeded.
+func (a *auditLog) record(fsys fileSystem, resource, operation, path string, apply func() error) error {
+	entry := a.begin(fsys, resource, operation, path)
+	err := apply()
+	if aerr := a.end(fsys, entry, err); aerr != nil && err == nil {
+		err = fmt.Errorf("error writing audit log %s: %s", a.path, aerr)
+	}
+	return err
+}
+
+// pathKeys are the attributes naming the path a resource manages, by
+// preference.
+var pathKeys = []string{"path", "destination", "device"}
+
+// pathKey returns the attribute naming the path r manages, or "" when it
+// has none.
+func pathKey(r *schema.Resource) string {
+	for _, k := range pathKeys {
+		if _, ok := r.Schema[k]; ok {
+			return k
+		}
+	}
+	return ""
+}
+
+// withAudit records the creates, updates and deletes of a resource in the
+// audit log of the provider, when it has one.
+func withAudit(name string, r *schema.Resource) *schema.Resource {
+	key := pathKey(r)
+	if key == "" {
+		return r
+	}
+
+	wrap := func(operation string, f func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+		return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+			conf := meta.(*providerConfig)
+			if conf.audit == nil {
+				return f(ctx, d, meta)
+			}
+			fsys := withContext(ctx, conf.fs)
+
+			entry := conf.audit.begin(fsys, name, operation, d.Get(key).(string))
+			diags := f(ctx, d, meta)
+
+			var failure error
+			for _, v := range diags {
+				if v.Severity == diag.Error {
+					failure = errors.New(v.Summary)
+					break
+				}
+			}
+			if err := conf.audit.end(fsys, entry, failure); err != nil {
+				diags = append(diags, diag.Errorf("error writing audit log %s: %s", conf.audit.path, err)...)
+			}
+			return diags
+		}
+	}
+
+	if r.CreateContext != nil {
+		r.CreateContext = wrap("create", r.CreateContext)
+	}
+	if r.UpdateContext != nil {
+		r.UpdateContext = wrap("update", r.UpdateContext)
+	}
+	if r.DeleteContext != nil {
+		r.DeleteContext = wrap("delete", r.DeleteContext)
+	}
+	return r
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// withBecome adds the become argument to a resource, which runs its
+// operations with the provider's become block.
+func withBecome(r *schema.Resource) *schema.Resource {
+	r.Schema["become"] = &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "Perform the operations of this resource with the privileges of the provider's become block, such as through sudo as root",
+	}
+
+	wrap := func(f func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+		return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+			conf, err := meta.(*providerConfig).becoming(ctx, d.Get("become").(bool))
+			if err != nil {
+				return diag.FromErr(err)
+			}
+			return f(ctx, d, conf)
+		}
+	}
+
+	// A resource that is replaced on every other change only has become
+	// to update, which changes nothing on disk
+	if r.UpdateContext == nil {
+		r.UpdateContext = schema.UpdateContextFunc(r.ReadContext)
+	}
+
+	r.CreateContext = wrap(r.CreateContext)
+	r.ReadContext = wrap(r.ReadContext)
+	r.UpdateContext = wrap(r.UpdateContext)
+	r.DeleteContext = wrap(r.DeleteContext)
+
+	if r.Importer != nil && r.Importer.StateContext != nil {
+		importer := r.Importer.StateContext
+		r.Importer.StateContext = func(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+			conf, err := meta.(*providerConfig).becoming(ctx, d.Get("become").(bool))
+			if err != nil {
+				return nil, err
+			}
+			return importer(ctx, d, conf)
+		}
+	}
+
+	if customize := r.CustomizeDiff; customize != nil {
+		r.CustomizeDiff = func(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+			conf, ok := meta.(*providerConfig)
+			if !ok {
+				return customize(ctx, d, meta)
+			}
+			conf, err := conf.becoming(ctx, d.Get("become").(bool))
+			if err != nil {
+				return err
+			}
+			return customize(ctx, d, conf)
+		}
+	}
+	return r
+}
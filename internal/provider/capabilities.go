@@ -0,0 +1,215 @@
+package provider
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// capabilityXattr is the extended attribute that Linux keeps the
+// capabilities of a file in.
+const capabilityXattr = "security.capability"
+
+// capabilityNames are the Linux capabilities by number.
+var capabilityNames = []string{
+	"cap_chown", "cap_dac_override", "cap_dac_read_search", "cap_fowner",
+	"cap_fsetid", "cap_kill", "cap_setgid", "cap_setuid", "cap_setpcap",
+	"cap_linux_immutable", "cap_net_bind_service", "cap_net_broadcast",
+	"cap_net_admin", "cap_net_raw", "cap_ipc_lock", "cap_ipc_owner",
+	"cap_sys_module", "cap_sys_rawio", "cap_sys_chroot", "cap_sys_ptrace",
+	"cap_sys_pacct", "cap_sys_admin", "cap_sys_boot", "cap_sys_nice",
+	"cap_sys_resource", "cap_sys_time", "cap_sys_tty_config", "cap_mknod",
+	"cap_lease", "cap_audit_write", "cap_audit_control", "cap_setfcap",
+	"cap_mac_override", "cap_mac_admin", "cap_syslog", "cap_wake_alarm",
+	"cap_block_suspend", "cap_audit_read", "cap_perfmon", "cap_bpf",
+	"cap_checkpoint_restore",
+}
+
+// fileCapabilities are the capability sets of a file, one bit per
+// capability number. A file has a single effective flag rather than a set,
+// so effective is either empty or all of permitted and inheritable.
+type fileCapabilities struct {
+	effective, permitted, inheritable uint64
+}
+
+const (
+	capRevision1    = 0x01000000
+	capRevision2    = 0x02000000
+	capRevision3    = 0x03000000
+	capRevisionMask = 0xff000000
+	capEffective    = 0x000001
+)
+
+// parseCapabilities parses capabilities in the text format of setcap and
+// cap_from_text(3), such as "cap_net_bind_service=+ep" or
+// "cap_net_admin,cap_net_raw=ep cap_sys_nice+p".
+func parseCapabilities(s string) (fileCapabilities, error) {
+	var caps fileCapabilities
+	all := uint64(1)<<len(capabilityNames) - 1
+
+	for _, clause := range strings.Fields(s) {
+		i := strings.IndexAny(clause, "=+-")
+		if i < 0 {
+			return caps, fmt.Errorf("invalid capabilities %q: %q has no operator", s, clause)
+		}
+
+		// An empty list, or "all", is every capability
+		var list uint64
+		if names := clause[:i]; names == "" || names == "all" {
+			list = all
+		} else {
+			for _, name := range strings.Split(names, ",") {
+				n := capabilityNumber(name)
+				if n < 0 {
+					return caps, fmt.Errorf("invalid capabilities %q: unknown capability %q", s, name)
+				}
+				list |= 1 << n
+			}
+		}
+
+		for rest := clause[i:]; rest != ""; {
+			op := rest[0]
+			end := strings.IndexAny(rest[1:], "=+-") + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			flags := rest[1:end]
+			rest = rest[end:]
+			if flags == "" && op != '=' {
+				return caps, fmt.Errorf("invalid capabilities %q: %q has no flags", s, clause)
+			}
+
+			sets := map[byte]*uint64{'e': &caps.effective, 'p': &caps.permitted, 'i': &caps.inheritable}
+			if op == '=' {
+				for _, set := range sets {
+					*set &^= list
+				}
+			}
+			for j := 0; j < len(flags); j++ {
+				set, ok := sets[flags[j]]
+				if !ok {
+					return caps, fmt.Errorf("invalid capabilities %q: unknown flag %q", s, flags[j])
+				}
+				if op == '-' {
+					*set &^= list
+				} else {
+					*set |= list
+				}
+			}
+		}
+	}
+
+	if caps.effective != 0 && caps.effective != caps.permitted|caps.inheritable {
+		return caps, fmt.Errorf("invalid capabilities %q: files have a single effective flag, so either all of their capabilities are effective or none", s)
+	}
+	return caps, nil
+}
+
+func capabilityNumber(name string) int {
+	name = strings.ToLower(name)
+	for n, c := range capabilityNames {
+		if c == name {
+			return n
+		}
+	}
+	return -1
+}
+
+func validateCapabilities(v interface{}, k string) ([]string, []error) {
+	if _, err := parseCapabilities(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: %s", k, err)}
+	}
+	return nil, nil
+}
+
+// diffSuppressCapabilities ignores changes between different spellings of
+// the same capabilities, such as "cap_chown+ep" and "cap_chown=ep".
+func diffSuppressCapabilities(k, old, new string, d *schema.ResourceData) bool {
+	o, err := parseCapabilities(old)
+	if err != nil {
+		return false
+	}
+	n, err := parseCapabilities(new)
+	return err == nil && o == n
+}
+
+// String formats capabilities the way getcap does, grouping capabilities
+// with the same flags.
+func (c fileCapabilities) String() string {
+	groups := map[string][]string{}
+	for n, name := range capabilityNames {
+		flags := ""
+		for _, f := range []struct {
+			set  uint64
+			flag string
+		}{{c.effective, "e"}, {c.inheritable, "i"}, {c.permitted, "p"}} {
+			if f.set&(1<<n) != 0 {
+				flags += f.flag
+			}
+		}
+		if flags != "" {
+			groups[flags] = append(groups[flags], name)
+		}
+	}
+
+	clauses := make([]string, 0, len(groups))
+	for flags, names := range groups {
+		// Every capability is written as an empty list, as in "=ep"
+		if len(names) == len(capabilityNames) {
+			names = nil
+		}
+		clauses = append(clauses, strings.Join(names, ",")+"="+flags)
+	}
+	sort.Strings(clauses)
+	return strings.Join(clauses, " ")
+}
+
+// encode returns c as a revision 2 security.capability value, which
+// applies in every user namespace.
+func (c fileCapabilities) encode() []byte {
+	b := make([]byte, 20)
+	magic := uint32(capRevision2)
+	if c.effective != 0 {
+		magic |= capEffective
+	}
+	binary.LittleEndian.PutUint32(b[0:], magic)
+	binary.LittleEndian.PutUint32(b[4:], uint32(c.permitted))
+	binary.LittleEndian.PutUint32(b[8:], uint32(c.inheritable))
+	binary.LittleEndian.PutUint32(b[12:], uint32(c.permitted>>32))
+	binary.LittleEndian.PutUint32(b[16:], uint32(c.inheritable>>32))
+	return b
+}
+
+// decodeCapabilities parses a security.capability value of any revision.
+// The root user ID of revision 3 is ignored.
+func decodeCapabilities(b []byte) (fileCapabilities, error) {
+	var c fileCapabilities
+	if len(b) < 4 {
+		return c, fmt.Errorf("invalid %s value", capabilityXattr)
+	}
+
+	magic := binary.LittleEndian.Uint32(b)
+	words := 2
+	switch magic & capRevisionMask {
+	case capRevision1:
+		words = 1
+	case capRevision2, capRevision3:
+	default:
+		return c, fmt.Errorf("unsupported %s revision %#x", capabilityXattr, magic&capRevisionMask)
+	}
+	if len(b) < 4+8*words {
+		return c, fmt.Errorf("invalid %s value", capabilityXattr)
+	}
+
+	for w := 0; w < words; w++ {
+		c.permitted |= uint64(binary.LittleEndian.Uint32(b[4+8*w:])) << (32 * w)
+		c.inheritable |= uint64(binary.LittleEndian.Uint32(b[8+8*w:])) << (32 * w)
+	}
+	if magic&capEffective != 0 {
+		c.effective = c.permitted | c.inheritable
+	}
+	return c, nil
+}
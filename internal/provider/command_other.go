@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package provider
+
+import "os/exec"
+
+// stopProcessGroup leaves cmd to exec.CommandContext, which only stops the
+// command itself.
+func stopProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,16 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package provider
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// stopProcessGroup makes cmd stop along with the processes it starts, which
+// would otherwise keep running, and holding its output open, once the shell
+// running them is killed.
+func stopProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+}
@@ -0,0 +1,273 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const (
+	// contentDiffContext is how many unchanged lines surround each change
+	contentDiffContext = 3
+
+	// contentDiffMaxLines truncates the diffs of large rewrites
+	contentDiffMaxLines = 200
+
+	// contentDiffMaxEdits gives up on diffs of files that are mostly
+	// rewritten, as finding the shortest diff takes quadratic time then
+	contentDiffMaxEdits = 2000
+)
+
+// secretLine matches lines that assign secrets, such as "password = ..." or
+// "api_key: ...", up to where the value starts.
+var secretLine = regexp.MustCompile(`(?i)^[^:=]*(password|passwd|secret|token|api[_-]?key|private[_-]?key|credential)[^:=]*[:=]`)
+
+// customizeDiffContentDiff plans content_diff, a unified diff between the
+// file as it is and the content the plan writes, when show_content_diff is
+// set. It is left empty for encrypted files and appended content, and when
+// the content is only known during the apply.
+func customizeDiffContentDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" || !d.Get("show_content_diff").(bool) || !d.Get("manage_content").(bool) {
+		return nil
+	}
+	if !d.HasChange("sha256") || len(d.Get("encrypt").([]interface{})) > 0 || d.Get("append").(bool) {
+		return nil
+	}
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	content, err := plannedContentFromResourceDiff(fsys, d)
+	if err != nil || !content.known {
+		return err
+	}
+
+	codec := textCodec{
+		encoding:    d.Get("encoding").(string),
+		bom:         d.Get("byte_order_mark").(bool),
+		lineEndings: d.Get("line_endings").(string),
+		compress:    d.Get("compress").(string),
+	}
+
+	// The file is still where it was when it moves
+	o, _ := d.GetChange("path")
+	path := o.(string)
+	if stream := d.Get("stream").(string); stream != "" {
+		path += ":" + stream
+	}
+	existing, err := readExisting(fsys, path, codec)
+	if err != nil {
+		return nil
+	}
+
+	return d.SetNew("content_diff", unifiedDiff(path, existing, codec.convert(content.value)))
+}
+
+// unifiedDiff returns the lines that change from a to b in unified format,
+// with the values of lines that look like they assign secrets redacted.
+func unifiedDiff(name, a, b string) string {
+	if strings.Contains(a, "PRIVATE KEY-----") || strings.Contains(b, "PRIVATE KEY-----") {
+		return "(diff not shown, as the file holds a private key)\n"
+	}
+	if binaryContent(a) || binaryContent(b) {
+		return "(diff not shown, as the file is binary)\n"
+	}
+
+	oldLines, newLines := strings.SplitAfter(a, "\n"), strings.SplitAfter(b, "\n")
+	if oldLines[len(oldLines)-1] == "" {
+		oldLines = oldLines[:len(oldLines)-1]
+	}
+	if newLines[len(newLines)-1] == "" {
+		newLines = newLines[:len(newLines)-1]
+	}
+
+	edits, ok := diffLines(oldLines, newLines, contentDiffMaxEdits)
+	if !ok {
+		return "(diff not shown, as most of the file changes)\n"
+	}
+
+	var out []string
+	for _, h := range diffHunks(edits, contentDiffContext) {
+		out = append(out, fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(h.oldStart, h.oldLines), hunkRange(h.newStart, h.newLines)))
+		for _, e := range h.edits {
+			line := e.line
+			if m := secretLine.FindString(line); m != "" {
+				line = m + " (redacted)\n"
+			}
+			if !strings.HasSuffix(line, "\n") {
+				line += "\n\\ No newline at end of file\n"
+			}
+			out = append(out, string(e.op)+line)
+		}
+	}
+	if len(out) == 0 {
+		return ""
+	}
+
+	header := fmt.Sprintf("--- %s\n+++ %s\n", name, name)
+	if len(out) > contentDiffMaxLines {
+		more := len(out) - contentDiffMaxLines
+		return header + strings.Join(out[:contentDiffMaxLines], "") + fmt.Sprintf("... %d more lines not shown\n", more)
+	}
+	return header + strings.Join(out, "")
+}
+
+// lineEdit is a line of a diff: ' ' for a line both sides have, '-' for one
+// that is removed and '+' for one that is added.
+type lineEdit struct {
+	op   byte
+	line string
+}
+
+// diffLines returns the shortest edit from a to b with Myers' algorithm,
+// or false when it takes more than maxEdits insertions and deletions.
+func diffLines(a, b []string, maxEdits int) ([]lineEdit, bool) {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	// trace[d] holds v before round d, for k from -d to d
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		if d > maxEdits {
+			return nil, false
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrackEdits(a, b, trace), true
+			}
+		}
+	}
+	return backtrackEdits(a, b, trace), true
+}
+
+// backtrackEdits follows the trace of diffLines back from the end of both
+// sides to their start.
+func backtrackEdits(a, b []string, trace [][]int) []lineEdit {
+	var edits []lineEdit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		// The value of v for diagonal k before round d
+		at := func(k int) int { return trace[d][k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, lineEdit{' ', a[x-1]})
+			x, y = x-1, y-1
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, lineEdit{'+', b[y-1]})
+			} else {
+				edits = append(edits, lineEdit{'-', a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// diffHunk is a run of changes along with the unchanged lines around them.
+type diffHunk struct {
+	oldStart, oldLines int
+	newStart, newLines int
+	edits              []lineEdit
+}
+
+// diffHunks groups edits into hunks, merging changes that are no more than
+// twice context unchanged lines apart.
+func diffHunks(edits []lineEdit, context int) []diffHunk {
+	var hunks []diffHunk
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			oldLine, newLine, i = oldLine+1, newLine+1, i+1
+			continue
+		}
+
+		// Start context lines before the change
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		h := diffHunk{oldStart: oldLine - (i - start), newStart: newLine - (i - start)}
+
+		// Extend the hunk until a run of unchanged lines is too long to bridge
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].op != ' ' {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+		end += context
+		if end > len(edits) {
+			end = len(edits)
+		}
+
+		h.edits = edits[start:end]
+		for _, e := range h.edits {
+			if e.op != '+' {
+				h.oldLines++
+			}
+			if e.op != '-' {
+				h.newLines++
+			}
+		}
+		for _, e := range edits[i:end] {
+			if e.op != '+' {
+				oldLine++
+			}
+			if e.op != '-' {
+				newLine++
+			}
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+	return hunks
+}
+
+// hunkRange formats the lines a hunk spans on one side. An empty side is
+// given by the line before it.
+func hunkRange(start, lines int) string {
+	if lines == 0 {
+		start--
+	}
+	if lines == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
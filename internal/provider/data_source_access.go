@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceAccess() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceAccessRead,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The path to check access to",
+			},
+			"user": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The user the provider acts as on the target host, which is the SSH or container user on remote targets",
+			},
+			"exists": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the path exists. The other attributes are false or empty when it doesn't",
+			},
+			"readable": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the user may read the file or list the directory",
+			},
+			"writable": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the user may write the file or create and delete entries in the directory",
+			},
+			"executable": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the user may execute the file or enter the directory",
+			},
+			"owned": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the user owns the path, and so may change its permissions",
+			},
+		},
+	}
+}
+
+func dataSourceAccessRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Symlinks are followed, as they are when the path is used
+	exists := true
+	access, err := fsys.Access(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return diag.FromErr(fmt.Errorf("error checking access to %s: %s", path, err))
+		}
+		exists, access = false, &fileAccess{}
+	}
+
+	for k, v := range map[string]interface{}{
+		"user":       access.user,
+		"exists":     exists,
+		"readable":   access.readable,
+		"writable":   access.writable,
+		"executable": access.executable,
+		"owned":      access.owned,
+	} {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	// Generate an ID based on path
+	hash := sha256.Sum256([]byte(path))
+	d.SetId(hex.EncodeToString(hash[:]))
+
+	return diags
+}
@@ -0,0 +1,357 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func validateGlob(v interface{}, k string) ([]string, []error) {
+	if _, err := filepath.Match(v.(string), ""); err != nil {
+		return nil, []error{fmt.Errorf("%s: invalid glob %q: %s", k, v, err)}
+	}
+	return nil, nil
+}
+
+func dataSourceCheck() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceCheckRead,
+
+		Schema: map[string]*schema.Schema{
+			"file": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Expectations on the permissions and ownership of paths",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validateGlob,
+							Description:  "The paths to check, whose elements may hold globs (e.g., '/etc/cron.*')",
+						},
+						"recursive": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Description: "Also check everything below the directories that path matches. Symlinks aren't followed",
+						},
+						"must_exist": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Description: "Fail when path matches nothing",
+						},
+						"max_permissions": {
+							Type:             schema.TypeString,
+							Optional:         true,
+							ValidateDiagFunc: validatePermissions,
+							Description:      "The most permissive mode allowed in octal format (e.g., '0640'). Setuid, setgid and sticky bits are only allowed when given here",
+						},
+						"owner": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "The user name or numeric ID that must own the paths",
+						},
+						"group": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "The group name or numeric ID that must own the paths",
+						},
+					},
+				},
+			},
+			"absent": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString, ValidateFunc: validateGlob},
+				Description: "Paths, whose elements may hold globs, that must not exist (e.g., '/home/*/.rhosts')",
+			},
+			"mount": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "Mount points that must be mounted with the given options",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The mount point, which must be a separate mount",
+						},
+						"options": {
+							Type:        schema.TypeList,
+							Required:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "The options the mount must have (e.g., 'nodev', 'nosuid' and 'noexec')",
+						},
+					},
+				},
+			},
+			"warn_only": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Report violations as warnings rather than failing the run",
+			},
+			"violations": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The expectations that aren't met, one message each",
+			},
+		},
+	}
+}
+
+// hasGlobMeta reports whether path holds any of the special characters of
+// filepath.Match.
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[`)
+}
+
+// globPaths returns the paths matching pattern, in which every element may
+// hold the wildcards of filepath.Match, in lexical order. Symlinks are
+// followed, as they are when the paths are used.
+func globPaths(fsys fileSystem, pattern string) ([]string, error) {
+	pattern = filepath.Clean(pattern)
+	if !hasGlobMeta(pattern) {
+		if _, err := fsys.Stat(pattern); err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return []string{pattern}, nil
+	}
+
+	dir, name := filepath.Split(pattern)
+	dir = filepath.Clean(dir)
+	dirs := []string{dir}
+	if hasGlobMeta(dir) {
+		var err error
+		dirs, err = globPaths(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var matches []string
+	for _, dir := range dirs {
+		info, err := fsys.Stat(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if !info.IsDir() {
+			continue
+		}
+
+		entries, err := fsys.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if ok, _ := filepath.Match(name, entry.Name()); ok {
+				matches = append(matches, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// fileExpectation is a file block of filesystem_check, with its owner and
+// group resolved.
+type fileExpectation struct {
+	maxPermissions string
+	uid, gid       int
+	owner, group   string
+}
+
+// check returns what info, the metadata of path, violates.
+func (e fileExpectation) check(fsys fileSystem, path string, info os.FileInfo) ([]string, error) {
+	var violations []string
+
+	if e.maxPermissions != "" {
+		max, err := parsePermissions(e.maxPermissions)
+		if err != nil {
+			return nil, err
+		}
+		if unixPermissions(info.Mode())&^unixPermissions(max) != 0 {
+			violations = append(violations, fmt.Sprintf("%s has permissions %s, which allow more than %s", path, formatPermissions(info.Mode()), formatPermissions(max)))
+		}
+	}
+
+	if e.owner == "" && e.group == "" {
+		return violations, nil
+	}
+	uid, gid, ok := fileOwner(info)
+	if !ok {
+		return nil, fmt.Errorf("error reading ownership of %s", path)
+	}
+	if e.owner != "" && uid != e.uid {
+		violations = append(violations, fmt.Sprintf("%s is owned by %s, not %s", path, fsys.UserName(uid), e.owner))
+	}
+	if e.group != "" && gid != e.gid {
+		violations = append(violations, fmt.Sprintf("%s belongs to group %s, not %s", path, fsys.GroupName(gid), e.group))
+	}
+	return violations, nil
+}
+
+// checkFiles returns the violations of the expectations of a file block.
+func checkFiles(fsys fileSystem, block map[string]interface{}) ([]string, error) {
+	pattern := block["path"].(string)
+	e := fileExpectation{
+		maxPermissions: block["max_permissions"].(string),
+		owner:          block["owner"].(string),
+		group:          block["group"].(string),
+	}
+
+	var err error
+	if e.owner != "" {
+		e.uid, err = fsys.LookupUID(e.owner)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if e.group != "" {
+		e.gid, err = fsys.LookupGID(e.group)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	paths, err := globPaths(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 && block["must_exist"].(bool) {
+		return []string{fmt.Sprintf("%s does not exist", pattern)}, nil
+	}
+
+	var violations []string
+	for _, path := range paths {
+		info, err := fsys.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		v, err := e.check(fsys, path, info)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, v...)
+
+		if !block["recursive"].(bool) || !info.IsDir() {
+			continue
+		}
+		err = walkDir(fsys, path, func(p string, entry fs.DirEntry, err error) error {
+			if err != nil || p == path || entry.Type()&os.ModeSymlink != 0 {
+				return err
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			v, err := e.check(fsys, p, info)
+			violations = append(violations, v...)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return violations, nil
+}
+
+// checkMount returns the violations of a mount block against mounts.
+func checkMount(mounts []mountInfo, block map[string]interface{}) []string {
+	path := filepath.Clean(block["path"].(string))
+
+	// The last mount on a mount point hides the ones before it
+	var found *mountInfo
+	for i := range mounts {
+		if mounts[i].mountpoint == path {
+			found = &mounts[i]
+		}
+	}
+	if found == nil {
+		return []string{fmt.Sprintf("%s is not a separate mount", path)}
+	}
+
+	options := map[string]bool{}
+	for _, o := range append(append([]string(nil), found.options...), found.superOptions...) {
+		options[o] = true
+	}
+	var missing []string
+	for _, o := range block["options"].([]interface{}) {
+		if !options[o.(string)] {
+			missing = append(missing, o.(string))
+		}
+	}
+	if len(missing) > 0 {
+		return []string{fmt.Sprintf("%s is mounted without %s", path, strings.Join(missing, ", "))}
+	}
+	return nil
+}
+
+func dataSourceCheckRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+
+	violations := []string{}
+	for _, v := range d.Get("file").([]interface{}) {
+		block := v.(map[string]interface{})
+		found, err := checkFiles(fsys, block)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error checking %s: %s", block["path"], err))
+		}
+		violations = append(violations, found...)
+	}
+
+	for _, v := range d.Get("absent").([]interface{}) {
+		paths, err := globPaths(fsys, v.(string))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error checking %s: %s", v, err))
+		}
+		for _, path := range paths {
+			violations = append(violations, fmt.Sprintf("%s must not exist", path))
+		}
+	}
+
+	if mounts := d.Get("mount").([]interface{}); len(mounts) > 0 {
+		mounted, err := fsys.Mounts()
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error listing mounts: %s", err))
+		}
+		for _, v := range mounts {
+			violations = append(violations, checkMount(mounted, v.(map[string]interface{}))...)
+		}
+	}
+
+	if err := d.Set("violations", violations); err != nil {
+		return diag.FromErr(err)
+	}
+
+	severity := diag.Error
+	if d.Get("warn_only").(bool) {
+		severity = diag.Warning
+	}
+	for _, v := range violations {
+		diags = append(diags, diag.Diagnostic{
+			Severity: severity,
+			Summary:  "Filesystem check failed",
+			Detail:   v,
+		})
+	}
+
+	d.SetId("check")
+
+	return diags
+}
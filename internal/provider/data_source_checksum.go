@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+func dataSourceChecksum() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceChecksumRead,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The path to the file",
+			},
+			"algorithm": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "sha256",
+				ValidateFunc: validation.StringInSlice([]string{"md5", "sha1", "sha256", "sha512"}, false),
+				Description:  "The hash algorithm: 'md5', 'sha1', 'sha256' or 'sha512'",
+			},
+			"hex": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The hex-encoded checksum",
+			},
+			"base64": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The base64-encoded checksum",
+			},
+		},
+	}
+}
+
+func dataSourceChecksumRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+	algorithm := d.Get("algorithm").(string)
+
+	sum, err := checksumFile(fsys, path, checksumAlgorithms[algorithm]())
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error computing checksum of %s: %s", path, err))
+	}
+
+	if err := d.Set("hex", hex.EncodeToString(sum)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("base64", base64.StdEncoding.EncodeToString(sum)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Generate an ID based on path and algorithm
+	id := sha256.Sum256([]byte(algorithm + ":" + path))
+	d.SetId(hex.EncodeToString(id[:]))
+
+	return diags
+}
+
+// checksumFile streams a file through h so that large files are never held
+// in memory.
+func checksumFile(fsys fileSystem, path string, h hash.Hash) ([]byte, error) {
+	if err := hashFile(fsys, path, h); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// hashFile streams a file into w, which may feed several hashes at once.
+func hashFile(fsys fileSystem, path string, w io.Writer) error {
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("path %s is a directory, not a file", path)
+	}
+
+	f, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = streamCopy(w, f, "hashing", path, info.Size())
+	return err
+}
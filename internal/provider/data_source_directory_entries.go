@@ -0,0 +1,193 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceDirectoryEntries() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceDirectoryEntriesRead,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The path to the directory",
+			},
+			"recursive": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Also list the contents of subdirectories",
+			},
+			"pattern": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only return entries whose name matches this glob (e.g., '*.conf')",
+				ValidateFunc: func(v interface{}, k string) ([]string, []error) {
+					if _, err := filepath.Match(v.(string), ""); err != nil {
+						return nil, []error{fmt.Errorf("%s: invalid glob %q: %s", k, v, err)}
+					}
+					return nil, nil
+				},
+			},
+			"regex": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsValidRegExp,
+				Description:  "Only return entries whose path relative to 'path' matches this regular expression",
+			},
+			"entries": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The matching entries in lexical order, parents before their children",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The base name of the entry",
+						},
+						"relative_path": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The path of the entry relative to 'path', using forward slashes",
+						},
+						"path": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The full path of the entry",
+						},
+						"type": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "One of 'file', 'directory', 'symlink', 'device', 'fifo', 'socket' or 'other'",
+						},
+						"size": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "The size of the entry in bytes",
+						},
+						"permissions": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Permissions in octal format (e.g., '0644')",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceDirectoryEntriesRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+	recursive := d.Get("recursive").(bool)
+	pattern := d.Get("pattern").(string)
+
+	var re *regexp.Regexp
+	if expr := d.Get("regex").(string); expr != "" {
+		re = regexp.MustCompile(expr)
+	}
+
+	// Ensure it's a directory, not a file
+	fileInfo, err := fsys.Stat(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading directory %s: %s", path, err))
+	}
+	if !fileInfo.IsDir() {
+		return diag.FromErr(fmt.Errorf("path %s is a file, not a directory", path))
+	}
+
+	var entries []interface{}
+	err = walkDir(fsys, path, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == path {
+			return nil
+		}
+
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		// Don't descend unless asked to, but still list the directory itself
+		skip := error(nil)
+		if entry.IsDir() && !recursive {
+			skip = filepath.SkipDir
+		}
+
+		if pattern != "" {
+			if ok, _ := filepath.Match(pattern, entry.Name()); !ok {
+				return skip
+			}
+		}
+		if re != nil && !re.MatchString(rel) {
+			return skip
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, map[string]interface{}{
+			"name":          entry.Name(),
+			"relative_path": rel,
+			"path":          p,
+			"type":          fileType(info.Mode()),
+			"size":          int(info.Size()),
+			"permissions":   formatPermissions(info.Mode()),
+		})
+		return skip
+	})
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error listing directory %s: %s", path, err))
+	}
+
+	if err := d.Set("entries", entries); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Generate an ID based on path
+	hash := sha256.Sum256([]byte(path))
+	d.SetId(hex.EncodeToString(hash[:]))
+
+	return diags
+}
+
+func fileType(mode os.FileMode) string {
+	switch {
+	case mode.IsRegular():
+		return "file"
+	case mode.IsDir():
+		return "directory"
+	case mode&os.ModeSymlink != 0:
+		return "symlink"
+	case mode&os.ModeDevice != 0:
+		return "device"
+	case mode&os.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	default:
+		return "other"
+	}
+}
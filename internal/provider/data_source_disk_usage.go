@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+type filesystemUsage struct {
+	totalBytes     uint64
+	freeBytes      uint64
+	availableBytes uint64
+	totalInodes    uint64
+	freeInodes     uint64
+	hasInodes      bool
+}
+
+func dataSourceDiskUsage() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceDiskUsageRead,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Any path on the filesystem to report on",
+			},
+			"total_bytes": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The size of the filesystem in bytes",
+			},
+			"free_bytes": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Free bytes, including blocks reserved for root",
+			},
+			"available_bytes": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Bytes available to unprivileged users",
+			},
+			"used_bytes": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Bytes in use",
+			},
+			"total_inodes": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of inodes, or 0 where the filesystem has none",
+			},
+			"free_inodes": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of free inodes",
+			},
+			"used_inodes": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of inodes in use",
+			},
+		},
+	}
+}
+
+func dataSourceDiskUsageRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	usage, err := fsys.DiskUsage(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading disk usage of %s: %s", path, err))
+	}
+
+	values := map[string]interface{}{
+		"total_bytes":     int(usage.totalBytes),
+		"free_bytes":      int(usage.freeBytes),
+		"available_bytes": int(usage.availableBytes),
+		"used_bytes":      int(usage.totalBytes - usage.freeBytes),
+	}
+	if usage.hasInodes {
+		values["total_inodes"] = int(usage.totalInodes)
+		values["free_inodes"] = int(usage.freeInodes)
+		values["used_inodes"] = int(usage.totalInodes - usage.freeInodes)
+	}
+
+	for k, v := range values {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	// Generate an ID based on path
+	hash := sha256.Sum256([]byte(path))
+	d.SetId(hex.EncodeToString(hash[:]))
+
+	return diags
+}
@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceExists() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceExistsRead,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The path to check",
+			},
+			"exists": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the path exists",
+			},
+			"is_file": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the path is a regular file",
+			},
+			"is_dir": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the path is a directory",
+			},
+		},
+	}
+}
+
+func dataSourceExistsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	exists, isFile, isDir := true, false, false
+
+	// Symlinks are followed, so a dangling link doesn't exist
+	fileInfo, err := fsys.Stat(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return diag.FromErr(fmt.Errorf("error reading %s: %s", path, err))
+		}
+		exists = false
+	} else {
+		isFile = fileInfo.Mode().IsRegular()
+		isDir = fileInfo.IsDir()
+	}
+
+	if err := d.Set("exists", exists); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("is_file", isFile); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("is_dir", isDir); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Generate an ID based on path
+	hash := sha256.Sum256([]byte(path))
+	d.SetId(hex.EncodeToString(hash[:]))
+
+	return diags
+}
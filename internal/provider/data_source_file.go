@@ -0,0 +1,184 @@
+package provider
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceFile() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceFileRead,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The path to the file",
+			},
+			"encoding": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "utf-8",
+				ValidateFunc: validation.StringInSlice(textEncodings, false),
+				Description:  "The character encoding content is decoded from: utf-8, utf-16le, utf-16be or latin-1",
+			},
+			"byte_order_mark": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Remove the byte order mark the file starts with from content",
+			},
+			"line_endings": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "none",
+				ValidateFunc: validation.StringInSlice(lineEndings, false),
+				Description:  "Convert the line endings of content to lf, crlf or those of the platform Terraform runs on. none leaves them as they are",
+			},
+			"content": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The content of the file",
+			},
+			"content_base64": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The content of the file, base64 encoded for binary files",
+			},
+			"permissions": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "File permissions in octal format (e.g., '0644')",
+			},
+			"owner": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The name of the user owning the file",
+			},
+			"group": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The name of the group owning the file",
+			},
+			"uid": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The numeric ID of the user owning the file",
+			},
+			"gid": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The numeric ID of the group owning the file",
+			},
+			"size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The size of the file in bytes",
+			},
+			"md5": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Hex-encoded MD5 checksum of the content",
+			},
+			"sha1": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Hex-encoded SHA-1 checksum of the content",
+			},
+			"sha256": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Hex-encoded SHA-256 checksum of the content",
+			},
+			"sha512": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Hex-encoded SHA-512 checksum of the content",
+			},
+		},
+	}
+}
+
+func dataSourceFileRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Check if the file exists
+	fileInfo, err := fsys.Stat(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+	}
+
+	// Ensure it's a file, not a directory
+	if fileInfo.IsDir() {
+		return diag.FromErr(fmt.Errorf("path %s is a directory, not a file", path))
+	}
+
+	// Read the file content
+	content, err := fsys.ReadFile(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+	}
+
+	// Decode the content, while content_base64 and the checksums stay those of the file
+	codec := textCodec{
+		encoding:    d.Get("encoding").(string),
+		bom:         d.Get("byte_order_mark").(bool),
+		lineEndings: d.Get("line_endings").(string),
+	}
+	if err := codec.validate(); err != nil {
+		return diag.FromErr(err)
+	}
+	text, err := codec.decode(content)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error decoding file %s: %s", path, err))
+	}
+	text = codec.convert(text)
+
+	md5Sum := md5.Sum(content)
+	sha1Sum := sha1.Sum(content)
+	sha256Sum := sha256.Sum256(content)
+	sha512Sum := sha512.Sum512(content)
+
+	values := map[string]interface{}{
+		"content":        text,
+		"content_base64": base64.StdEncoding.EncodeToString(content),
+		"permissions":    formatPermissions(fileInfo.Mode()),
+		"size":           int(fileInfo.Size()),
+		"md5":            hex.EncodeToString(md5Sum[:]),
+		"sha1":           hex.EncodeToString(sha1Sum[:]),
+		"sha256":         hex.EncodeToString(sha256Sum[:]),
+		"sha512":         hex.EncodeToString(sha512Sum[:]),
+	}
+
+	if uid, gid, ok := fileOwner(fileInfo); ok {
+		values["uid"] = uid
+		values["gid"] = gid
+		values["owner"] = fsys.UserName(uid)
+		values["group"] = fsys.GroupName(gid)
+	}
+
+	for k, v := range values {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	// Generate an ID based on path
+	hash := sha256.Sum256([]byte(path))
+	d.SetId(hex.EncodeToString(hash[:]))
+
+	return diags
+}
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceFileLines() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceFileLinesRead,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The path to the file",
+			},
+			"encoding": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "utf-8",
+				ValidateFunc: validation.StringInSlice(textEncodings, false),
+				Description:  "The character encoding the file is decoded from: utf-8, utf-16le, utf-16be or latin-1. A byte order mark is removed",
+			},
+			"regex": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsValidRegExp,
+				Description:  "Only return lines matching this regular expression. Its capture groups are exposed in matches",
+			},
+			"lines": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The matching lines in order, without their line endings",
+			},
+			"matches": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The matching lines along with their line numbers and what the capture groups of regex matched",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"line_number": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "The number of the line, starting at 1",
+						},
+						"line": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The line without its line ending",
+						},
+						"groups": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "What the capture groups matched, in order. Groups that didn't take part in the match are empty",
+						},
+						"named_groups": {
+							Type:        schema.TypeMap,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "What the named capture groups, such as (?P<id>...), matched by name",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// splitLines splits text into lines without their line endings. A final
+// line ending doesn't start another line.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+func dataSourceFileLinesRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	var re *regexp.Regexp
+	if expr := d.Get("regex").(string); expr != "" {
+		re = regexp.MustCompile(expr)
+	}
+
+	// Ensure it's a file, not a directory
+	fileInfo, err := fsys.Stat(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+	}
+	if fileInfo.IsDir() {
+		return diag.FromErr(fmt.Errorf("path %s is a directory, not a file", path))
+	}
+
+	content, err := fsys.ReadFile(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+	}
+
+	encoding := d.Get("encoding").(string)
+	codec := textCodec{encoding: encoding, bom: byteOrderMarks[encoding] != nil}
+	text, err := codec.decode(content)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error decoding file %s: %s", path, err))
+	}
+
+	lines := []interface{}{}
+	matches := []interface{}{}
+	for i, line := range splitLines(text) {
+		groups := []interface{}{}
+		named := map[string]interface{}{}
+		if re != nil {
+			submatches := re.FindStringSubmatch(line)
+			if submatches == nil {
+				continue
+			}
+			for j, name := range re.SubexpNames()[1:] {
+				groups = append(groups, submatches[j+1])
+				if name != "" {
+					named[name] = submatches[j+1]
+				}
+			}
+		}
+
+		lines = append(lines, line)
+		matches = append(matches, map[string]interface{}{
+			"line_number":  i + 1,
+			"line":         line,
+			"groups":       groups,
+			"named_groups": named,
+		})
+	}
+
+	if err := d.Set("lines", lines); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("matches", matches); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Generate an ID based on path
+	hash := sha256.Sum256([]byte(path))
+	d.SetId(hex.EncodeToString(hash[:]))
+
+	return diags
+}
@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// sniffSize is how much of a file its type is detected from, which is all
+// that content type sniffing looks at.
+const sniffSize = 512
+
+func dataSourceFileType() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceFileTypeRead,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The path to the file",
+			},
+			"mime_type": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The MIME type detected from the first 512 bytes of the file (e.g., 'image/png', 'application/pdf' or 'text/plain'), without parameters. Files that aren't recognized are 'application/octet-stream'",
+			},
+			"binary": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the file is binary rather than text",
+			},
+			"charset": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The character encoding of text files: utf-8, utf-16le, utf-16be or latin-1, as the encoding of the filesystem_file data source takes. Empty for binary files",
+			},
+		},
+	}
+}
+
+// sniffFile returns the start of a file, reading no more than its type is
+// detected from.
+func sniffFile(fsys fileSystem, path string) ([]byte, error) {
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("path %s is a directory, not a file", path)
+	}
+
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, sniffSize))
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// detectFileType returns the MIME type and, for text, the character
+// encoding of a file that starts with data.
+func detectFileType(data []byte) (mimeType string, charset string) {
+	mimeType, params, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil || !strings.HasPrefix(mimeType, "text/") {
+		return mimeType, ""
+	}
+
+	// Sniffing reports utf-8 for anything without a byte order mark
+	switch cs := params["charset"]; {
+	case cs == "utf-16le" || cs == "utf-16be":
+		return mimeType, cs
+	case validUTF8Prefix(data, len(data) == sniffSize):
+		return mimeType, "utf-8"
+	default:
+		return mimeType, "latin-1"
+	}
+}
+
+// validUTF8Prefix reports whether data is valid UTF-8, up to a character
+// cut off at its end when it is truncated.
+func validUTF8Prefix(data []byte, truncated bool) bool {
+	for i := 0; truncated && i < utf8.UTFMax && i < len(data); i++ {
+		if utf8.RuneStart(data[len(data)-1-i]) {
+			if !utf8.FullRune(data[len(data)-1-i:]) {
+				data = data[:len(data)-1-i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(bytes.TrimPrefix(data, byteOrderMarks["utf-8"]))
+}
+
+func dataSourceFileTypeRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	data, err := sniffFile(fsys, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+	}
+	mimeType, charset := detectFileType(data)
+
+	values := map[string]interface{}{
+		"mime_type": mimeType,
+		"binary":    charset == "",
+		"charset":   charset,
+	}
+	for k, v := range values {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	// Generate an ID based on path
+	hash := sha256.Sum256([]byte(path))
+	d.SetId(hex.EncodeToString(hash[:]))
+
+	return diags
+}
@@ -0,0 +1,167 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceFind() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceFindRead,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The directory to search",
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only match entries whose base name matches this glob (e.g., '*.log')",
+				ValidateFunc: func(v interface{}, k string) ([]string, []error) {
+					if _, err := filepath.Match(v.(string), ""); err != nil {
+						return nil, []error{fmt.Errorf("%s: invalid glob %q: %s", k, v, err)}
+					}
+					return nil, nil
+				},
+			},
+			"type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"file", "directory", "symlink", "device", "fifo", "socket", "other"}, false),
+				Description:  "Only match entries of this type",
+			},
+			"min_size": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Only match entries of at least this many bytes",
+			},
+			"max_size": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      -1,
+				ValidateFunc: validation.IntAtLeast(-1),
+				Description:  "Only match entries of at most this many bytes. -1 means no limit",
+			},
+			"modified_after": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsRFC3339Time,
+				Description:  "Only match entries modified after this RFC 3339 timestamp",
+			},
+			"modified_before": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsRFC3339Time,
+				Description:  "Only match entries modified before this RFC 3339 timestamp",
+			},
+			"max_depth": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      -1,
+				ValidateFunc: validation.IntAtLeast(-1),
+				Description:  "How many directory levels below 'path' to descend. -1 means no limit",
+			},
+			"paths": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The sorted paths of all matching entries",
+			},
+		},
+	}
+}
+
+func dataSourceFindRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	root := d.Get("path").(string)
+	name := d.Get("name").(string)
+	entryType := d.Get("type").(string)
+	minSize := int64(d.Get("min_size").(int))
+	maxSize := int64(d.Get("max_size").(int))
+	maxDepth := d.Get("max_depth").(int)
+
+	var after, before time.Time
+	if v := d.Get("modified_after").(string); v != "" {
+		after, _ = time.Parse(time.RFC3339, v)
+	}
+	if v := d.Get("modified_before").(string); v != "" {
+		before, _ = time.Parse(time.RFC3339, v)
+	}
+
+	paths := []string{}
+	err := walkDir(fsys, root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+
+		// Stop descending once max_depth is reached
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		depth := strings.Count(filepath.ToSlash(rel), "/") + 1
+		skip := error(nil)
+		if entry.IsDir() && maxDepth >= 0 && depth >= maxDepth {
+			skip = filepath.SkipDir
+		}
+		if maxDepth >= 0 && depth > maxDepth {
+			return skip
+		}
+
+		if name != "" {
+			if ok, _ := filepath.Match(name, entry.Name()); !ok {
+				return skip
+			}
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entryType != "" && fileType(info.Mode()) != entryType:
+		case info.Size() < minSize:
+		case maxSize >= 0 && info.Size() > maxSize:
+		case !after.IsZero() && !info.ModTime().After(after):
+		case !before.IsZero() && !info.ModTime().Before(before):
+		default:
+			paths = append(paths, p)
+		}
+
+		return skip
+	})
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error searching %s: %s", root, err))
+	}
+
+	sort.Strings(paths)
+	if err := d.Set("paths", paths); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Generate an ID based on path
+	hash := sha256.Sum256([]byte(root))
+	d.SetId(hex.EncodeToString(hash[:]))
+
+	return diags
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+type mountInfo struct {
+	device       string
+	mountpoint   string
+	root         string
+	fstype       string
+	options      []string
+	superOptions []string
+}
+
+func dataSourceMounts() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceMountsRead,
+
+		Schema: map[string]*schema.Schema{
+			"mounts": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The mounted filesystems in mount order",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"device": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The mounted device or source (e.g., '/dev/sda1', 'tmpfs')",
+						},
+						"mountpoint": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The directory the filesystem is mounted on",
+						},
+						"root": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The directory within the filesystem that forms the root of the mount",
+						},
+						"fstype": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The filesystem type (e.g., 'ext4', 'apfs', 'NTFS')",
+						},
+						"options": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Per-mount options (e.g., 'rw', 'noatime')",
+						},
+						"super_options": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Filesystem-wide options, where the platform reports them separately",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceMountsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+
+	mounts, err := fsys.Mounts()
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error listing mounts: %s", err))
+	}
+
+	var values []interface{}
+	for _, m := range mounts {
+		values = append(values, map[string]interface{}{
+			"device":        m.device,
+			"mountpoint":    m.mountpoint,
+			"root":          m.root,
+			"fstype":        m.fstype,
+			"options":       m.options,
+			"super_options": m.superOptions,
+		})
+	}
+
+	if err := d.Set("mounts", values); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId("mounts")
+
+	return diags
+}
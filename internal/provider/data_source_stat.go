@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceStat() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceStatRead,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The path to inspect",
+			},
+			"follow_symlinks": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Report on the target of a symlink instead of the link itself",
+			},
+			"exists": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the path exists",
+			},
+			"type": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "One of 'file', 'directory', 'symlink', 'device', 'fifo', 'socket' or 'other'",
+			},
+			"permissions": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Permissions in octal format (e.g., '0644')",
+			},
+			"uid": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The numeric ID of the owning user",
+			},
+			"gid": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The numeric ID of the owning group",
+			},
+			"owner": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The name of the owning user",
+			},
+			"group": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The name of the owning group",
+			},
+			"size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The size in bytes",
+			},
+			"mtime": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The modification time in RFC 3339 format",
+			},
+			"inode": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The inode number",
+			},
+			"symlink_target": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The target of the symlink, if the path is one",
+			},
+		},
+	}
+}
+
+func dataSourceStatRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Generate an ID based on path
+	hash := sha256.Sum256([]byte(path))
+	d.SetId(hex.EncodeToString(hash[:]))
+
+	stat := fsys.Lstat
+	if d.Get("follow_symlinks").(bool) {
+		stat = fsys.Stat
+	}
+
+	fileInfo, err := stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if err := d.Set("exists", false); err != nil {
+				return diag.FromErr(err)
+			}
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading %s: %s", path, err))
+	}
+
+	values := map[string]interface{}{
+		"exists":      true,
+		"type":        fileType(fileInfo.Mode()),
+		"permissions": formatPermissions(fileInfo.Mode()),
+		"size":        int(fileInfo.Size()),
+		"mtime":       fileInfo.ModTime().UTC().Format(time.RFC3339),
+	}
+
+	if uid, gid, ok := fileOwner(fileInfo); ok {
+		values["uid"] = uid
+		values["gid"] = gid
+		values["owner"] = fsys.UserName(uid)
+		values["group"] = fsys.GroupName(gid)
+	}
+
+	if inode, ok := fileInode(fileInfo); ok {
+		values["inode"] = int(inode)
+	}
+
+	if fileInfo.Mode()&os.ModeSymlink != 0 {
+		target, err := fsys.Readlink(path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading symlink %s: %s", path, err))
+		}
+		values["symlink_target"] = target
+	}
+
+	for k, v := range values {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
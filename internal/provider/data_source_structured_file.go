@@ -0,0 +1,265 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"gopkg.in/yaml.v3"
+)
+
+// structuredFile reads a JSON, YAML or TOML file and decodes it into a
+// Terraform value, in place of file() and jsondecode() or yamldecode(), which
+// only work on the machine running Terraform.
+type structuredFile struct {
+	provider *frameworkProvider
+}
+
+type structuredFileModel struct {
+	Path   types.String  `tfsdk:"path"`
+	Format types.String  `tfsdk:"format"`
+	Value  types.Dynamic `tfsdk:"value"`
+}
+
+// structuredFormats are the formats structured files are decoded from, by
+// the file extensions they are detected with.
+var structuredFormats = map[string]string{
+	".json": "json",
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".toml": "toml",
+}
+
+func newStructuredFile() datasource.DataSource {
+	return &structuredFile{}
+}
+
+var (
+	_ datasource.DataSourceWithConfigure      = &structuredFile{}
+	_ datasource.DataSourceWithValidateConfig = &structuredFile{}
+)
+
+func (d *structuredFile) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_structured_file"
+}
+
+func (d *structuredFile) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Read a JSON, YAML or TOML file and decode it into an object",
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Required:    true,
+				Description: "The path to the file",
+			},
+			"format": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The format the file is decoded from: json, yaml or toml. Defaults to the format of the file extension, .json, .yaml, .yml or .toml",
+			},
+			"value": schema.DynamicAttribute{
+				Computed:    true,
+				Description: "The decoded content of the file. Objects decode to objects, arrays to tuples, and null to null",
+			},
+		},
+	}
+}
+
+func (d *structuredFile) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if p, ok := req.ProviderData.(*frameworkProvider); ok {
+		d.provider = p
+	}
+}
+
+func (d *structuredFile) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var model structuredFileModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if v := model.Format.ValueString(); v != "" && v != "json" && v != "yaml" && v != "toml" {
+		resp.Diagnostics.AddAttributeError(path.Root("format"), "Invalid format", fmt.Sprintf("format must be json, yaml or toml, got %q", v))
+	}
+}
+
+func (d *structuredFile) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var model structuredFileModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	conf := d.provider.config()
+	if conf == nil {
+		resp.Diagnostics.AddError("Provider not configured", "the filesystem provider must be configured before data sources are read")
+		return
+	}
+	fsys := withContext(ctx, conf.fs)
+	name := model.Path.ValueString()
+
+	// Detect the format by the file extension
+	format := model.Format.ValueString()
+	if format == "" {
+		format = structuredFormats[strings.ToLower(filepath.Ext(name))]
+		if format == "" {
+			resp.Diagnostics.AddAttributeError(path.Root("format"), "Unknown format", fmt.Sprintf("can't detect the format of %s by its extension, set format to json, yaml or toml", name))
+			return
+		}
+	}
+
+	data, err := fsys.ReadFile(name)
+	if err != nil {
+		resp.Diagnostics.AddError("Error reading file", fmt.Sprintf("error reading file %s: %s", name, err))
+		return
+	}
+
+	decoded, err := decodeStructured(format, data)
+	if err != nil {
+		resp.Diagnostics.AddError("Error decoding file", fmt.Sprintf("error decoding %s as %s: %s", name, format, err))
+		return
+	}
+	value, err := structuredValue(decoded)
+	if err != nil {
+		resp.Diagnostics.AddError("Error decoding file", fmt.Sprintf("error decoding %s as %s: %s", name, format, err))
+		return
+	}
+
+	model.Format = types.StringValue(format)
+	model.Value = types.DynamicValue(value)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+// decodeStructured decodes data in format into maps, slices and scalars.
+// JSON numbers are kept as they are written, so that large integers don't
+// lose precision.
+func decodeStructured(format string, data []byte) (interface{}, error) {
+	var v interface{}
+	switch format {
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		if dec.More() {
+			return nil, fmt.Errorf("unexpected content after the top-level value")
+		}
+	case "yaml":
+		// An empty document decodes to null
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+	case "toml":
+		var table map[string]interface{}
+		if err := toml.Unmarshal(data, &table); err != nil {
+			return nil, err
+		}
+		v = table
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	return v, nil
+}
+
+// structuredTimeLayouts are the layouts of dates and times by their
+// location. TOML local dates and times are decoded with the locations named
+// after them, and are formatted without an offset.
+var structuredTimeLayouts = map[string]string{
+	"datetime-local": "2006-01-02T15:04:05.999999999",
+	"date-local":     "2006-01-02",
+	"time-local":     "15:04:05.999999999",
+}
+
+// structuredValue converts a decoded value to a Terraform value the way
+// jsondecode does: maps become objects, slices tuples, and dates and times
+// strings.
+func structuredValue(v interface{}) (attr.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return types.DynamicNull(), nil
+	case string:
+		return types.StringValue(v), nil
+	case bool:
+		return types.BoolValue(v), nil
+	case json.Number:
+		f, _, err := big.ParseFloat(v.String(), 10, 512, big.ToNearestEven)
+		if err != nil {
+			return nil, err
+		}
+		return types.NumberValue(f), nil
+	case int:
+		return types.NumberValue(new(big.Float).SetInt64(int64(v))), nil
+	case int64:
+		return types.NumberValue(new(big.Float).SetInt64(v)), nil
+	case uint64:
+		return types.NumberValue(new(big.Float).SetUint64(v)), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("%v is not a number Terraform supports", v)
+		}
+		return types.NumberValue(big.NewFloat(v)), nil
+	case time.Time:
+		layout, ok := structuredTimeLayouts[v.Location().String()]
+		if !ok {
+			layout = time.RFC3339Nano
+		}
+		return types.StringValue(v.Format(layout)), nil
+	case []map[string]interface{}:
+		elems := make([]interface{}, len(v))
+		for i, e := range v {
+			elems[i] = e
+		}
+		return structuredValue(elems)
+	case []interface{}:
+		elemTypes := make([]attr.Type, len(v))
+		elems := make([]attr.Value, len(v))
+		for i, e := range v {
+			value, err := structuredValue(e)
+			if err != nil {
+				return nil, err
+			}
+			elemTypes[i], elems[i] = value.Type(context.Background()), value
+		}
+		tuple, diags := types.TupleValue(elemTypes, elems)
+		if diags.HasError() {
+			return nil, fmt.Errorf("%s", diags.Errors()[0].Detail())
+		}
+		return tuple, nil
+	case map[interface{}]interface{}:
+		// YAML allows keys that aren't strings, which become strings as
+		// object attribute names
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = e
+		}
+		return structuredValue(m)
+	case map[string]interface{}:
+		attrTypes := make(map[string]attr.Type, len(v))
+		attrs := make(map[string]attr.Value, len(v))
+		for k, e := range v {
+			value, err := structuredValue(e)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", k, err)
+			}
+			attrTypes[k], attrs[k] = value.Type(context.Background()), value
+		}
+		object, diags := types.ObjectValue(attrTypes, attrs)
+		if diags.HasError() {
+			return nil, fmt.Errorf("%s", diags.Errors()[0].Detail())
+		}
+		return object, nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", v)
+}
@@ -0,0 +1,189 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceTreeHash() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceTreeHashRead,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The directory to hash",
+			},
+			"excludes": excludesSchema("Gitignore-style patterns, relative to the directory, of what isn't hashed (e.g., '*.log' or '.git/')"),
+			"include_modes": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Include the permissions of every entry in the hash, so that changing them changes it too",
+			},
+			"sha256": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The hex-encoded SHA-256 of the tree. Ownership and timestamps don't change it",
+			},
+			"files": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of regular files hashed",
+			},
+			"size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The total size in bytes of the regular files hashed",
+			},
+		},
+	}
+}
+
+// treeNode is an entry of a hashed tree. The sum of a directory is that of
+// its children, which are only known once all of them are hashed.
+type treeNode struct {
+	name     string
+	kind     string
+	mode     string
+	sum      string
+	children []*treeNode
+}
+
+// treeHash returns a Merkle hash of the directory at root: every directory
+// is hashed over the sorted names, kinds, modes and hashes of its children,
+// and files by their content, up to parallelism of them at once. Symlinks
+// are hashed by their target rather than followed.
+func treeHash(fsys fileSystem, root string, excludes *excludeMatcher, modes bool, parallelism int) (sum string, files int, size int64, err error) {
+	// A symlink to the directory is walked as the directory
+	root, err = resolveSymlinks(fsys, filepath.Clean(root), true)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	info, err := fsys.Stat(root)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if !info.IsDir() {
+		return "", 0, 0, fmt.Errorf("path %s is not a directory", root)
+	}
+
+	// Entries are walked one at a time, so only the sums of files are set
+	// concurrently
+	dirs := map[string]*treeNode{root: {kind: "dir"}}
+	pool := newWorkerPool(parallelism)
+	err = walkDirExcluding(fsys, root, excludes, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		node := &treeNode{name: entry.Name()}
+		if modes {
+			node.mode = formatPermissions(info.Mode())
+		}
+
+		switch {
+		case info.IsDir():
+			node.kind = "dir"
+			dirs[path] = node
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := fsys.Readlink(path)
+			if err != nil {
+				return err
+			}
+			hash := sha256.Sum256([]byte(target))
+			node.kind, node.sum = "link", hex.EncodeToString(hash[:])
+		case info.Mode().IsRegular():
+			node.kind = "file"
+			files++
+			size += info.Size()
+			err = pool.Go(func() error {
+				sum, err := checksumFile(fsys, path, sha256.New())
+				node.sum = hex.EncodeToString(sum)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		default:
+			// Device nodes, sockets and pipes have no content to hash
+			node.kind = "special"
+		}
+
+		// Directories are walked before what they hold
+		parent := dirs[filepath.Dir(path)]
+		parent.children = append(parent.children, node)
+		return nil
+	})
+	if werr := pool.Wait(); err == nil {
+		err = werr
+	}
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	return hashTreeNode(dirs[root]), files, size, nil
+}
+
+// hashTreeNode returns the sum of a directory from those of its children,
+// each given by a line of "<kind> <mode> <name>\x00<sum>".
+func hashTreeNode(dir *treeNode) string {
+	sort.Slice(dir.children, func(i, j int) bool { return dir.children[i].name < dir.children[j].name })
+
+	hash := sha256.New()
+	for _, child := range dir.children {
+		if child.kind == "dir" {
+			child.sum = hashTreeNode(child)
+		}
+		fmt.Fprintf(hash, "%s %s %s\x00%s\n", child.kind, child.mode, child.name, child.sum)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func dataSourceTreeHashRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	path := d.Get("path").(string)
+
+	excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	sum, files, size, err := treeHash(fsys, path, excludes, d.Get("include_modes").(bool), conf.parallelism)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error hashing %s: %s", path, err))
+	}
+
+	for k, v := range map[string]interface{}{
+		"sha256": sum,
+		"files":  files,
+		"size":   int(size),
+	} {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	// Generate an ID based on path
+	hash := sha256.Sum256([]byte(path))
+	d.SetId(hex.EncodeToString(hash[:]))
+
+	return diags
+}
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceWaitFor() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceWaitForRead,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The path to wait for",
+			},
+			"min_size": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Also wait until the file holds at least this many bytes",
+			},
+			"content_regex": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsValidRegExp,
+				Description:  "Also wait until the content of the file matches this regular expression",
+			},
+			"timeout": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "5m",
+				Description: "How long to wait before failing (e.g., '10m'). Data sources are read for at most 20 minutes",
+			},
+			"poll_interval": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "1s",
+				Description: "How often the path is checked (e.g., '1s')",
+			},
+			"size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The size of the path in bytes once it was ready",
+			},
+		},
+	}
+}
+
+func dataSourceWaitForRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	path := d.Get("path").(string)
+	minSize := int64(d.Get("min_size").(int))
+
+	var re *regexp.Regexp
+	if expr := d.Get("content_regex").(string); expr != "" {
+		re = regexp.MustCompile(expr)
+	}
+
+	timeout, err := time.ParseDuration(d.Get("timeout").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("invalid timeout %q: %s", d.Get("timeout"), err))
+	}
+
+	interval, err := time.ParseDuration(d.Get("poll_interval").(string))
+	if err == nil && interval <= 0 {
+		err = fmt.Errorf("must be positive")
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("invalid poll_interval %q: %s", d.Get("poll_interval"), err))
+	}
+
+	// Poll until the path is ready or the timeout expires
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+
+	// The reason is kept from the last check that completed, as the one cut
+	// short by the timeout has none
+	var size int64
+	reason := ""
+	for {
+		current, why, err := waitForReason(fsys, path, minSize, re)
+		if err == nil {
+			if why == "" {
+				size = current
+				break
+			}
+			reason = why
+		} else if ctx.Err() == nil {
+			return diag.FromErr(fmt.Errorf("error reading %s: %s", path, err))
+		}
+
+		select {
+		case <-ctx.Done():
+			if reason == "" {
+				reason = ctx.Err().Error()
+			}
+			return diag.FromErr(fmt.Errorf("timed out after %s waiting for %s: %s", timeout, path, reason))
+		case <-time.After(interval):
+		}
+	}
+
+	if err := d.Set("size", int(size)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Generate an ID based on path
+	hash := sha256.Sum256([]byte(path))
+	d.SetId(hex.EncodeToString(hash[:]))
+
+	return diags
+}
+
+// waitForReason returns why path isn't ready yet, or an empty reason once
+// it exists with at least minSize bytes and content matching re.
+func waitForReason(fsys fileSystem, path string, minSize int64, re *regexp.Regexp) (int64, string, error) {
+	// Symlinks are followed, so a dangling link isn't ready
+	info, err := fsys.Stat(path)
+	if os.IsNotExist(err) {
+		return 0, "it does not exist", nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	if minSize == 0 && re == nil {
+		return info.Size(), "", nil
+	}
+	if info.IsDir() {
+		return 0, "", fmt.Errorf("path %s is a directory, not a file", path)
+	}
+
+	if info.Size() < minSize {
+		return info.Size(), fmt.Sprintf("it holds %d of at least %d bytes", info.Size(), minSize), nil
+	}
+
+	if re != nil {
+		content, err := fsys.ReadFile(path)
+		if os.IsNotExist(err) {
+			return 0, "it does not exist", nil
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		if !re.Match(content) {
+			return info.Size(), "its content does not match content_regex", nil
+		}
+	}
+
+	return info.Size(), "", nil
+}
@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"context"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// providerDefaults holds the provider-wide fallbacks for file and directory
+// resources that don't set permissions, owner or group themselves.
+type providerDefaults struct {
+	filePermissions      os.FileMode
+	directoryPermissions os.FileMode
+	owner                string
+	group                string
+	umask                os.FileMode
+}
+
+// filePerm returns the default file permissions with the umask applied.
+func (p providerDefaults) filePerm() os.FileMode {
+	return p.filePermissions &^ p.umask
+}
+
+// dirPerm returns the default directory permissions with the umask applied.
+func (p providerDefaults) dirPerm() os.FileMode {
+	return p.directoryPermissions &^ p.umask
+}
+
+// customizeDiffDefaults plans the provider defaults for the "permissions",
+// "owner" and "group" attributes left out of the configuration, so that the
+// plan shows the values that will actually be applied.
+func customizeDiffDefaults(perm func(providerDefaults) os.FileMode) schema.CustomizeDiffFunc {
+	return func(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+		conf, ok := meta.(*providerConfig)
+		if !ok {
+			return nil
+		}
+
+		config := d.GetRawConfig()
+		if config.IsNull() || !config.IsKnown() {
+			return nil
+		}
+
+		values := map[string]string{
+			"permissions": formatPermissions(perm(conf.defaults)),
+			"owner":       conf.defaults.owner,
+			"group":       conf.defaults.group,
+		}
+		for k, v := range values {
+			if v == "" || !config.GetAttr(k).IsNull() || d.Get(k).(string) == v {
+				continue
+			}
+			if err := d.SetNew(k, v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func mknod(path string, nodeType string, perm os.FileMode, major, minor uint32) error {
+	return fmt.Errorf("device nodes are not supported on %s", runtime.GOOS)
+}
+
+func deviceNumbers(info os.FileInfo) (major, minor uint32, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,28 @@
+//go:build linux || darwin
+
+package provider
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func mknod(path string, nodeType string, perm os.FileMode, major, minor uint32) error {
+	mode := uint32(unix.S_IFCHR)
+	if nodeType == "block" {
+		mode = unix.S_IFBLK
+	}
+
+	return unix.Mknod(path, mode|uint32(perm), int(unix.Mkdev(major, minor)))
+}
+
+// deviceNumbers returns the major and minor number of a device node.
+func deviceNumbers(info os.FileInfo) (major, minor uint32, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return unix.Major(uint64(stat.Rdev)), unix.Minor(uint64(stat.Rdev)), true
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package provider
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func diskUsage(path string) (*filesystemUsage, error) {
+	return nil, fmt.Errorf("disk usage is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,32 @@
+//go:build linux || darwin || freebsd
+
+package provider
+
+import "golang.org/x/sys/unix"
+
+func diskUsage(path string) (*filesystemUsage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return nil, err
+	}
+
+	// Field widths and signedness differ between platforms
+	bsize := uint64(st.Bsize)
+	available := int64(st.Bavail)
+	if available < 0 {
+		available = 0
+	}
+	freeInodes := int64(st.Ffree)
+	if freeInodes < 0 {
+		freeInodes = 0
+	}
+
+	return &filesystemUsage{
+		totalBytes:     uint64(st.Blocks) * bsize,
+		freeBytes:      uint64(st.Bfree) * bsize,
+		availableBytes: uint64(available) * bsize,
+		totalInodes:    uint64(st.Files),
+		freeInodes:     uint64(freeInodes),
+		hasInodes:      true,
+	}, nil
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package provider
+
+import "golang.org/x/sys/windows"
+
+func diskUsage(path string) (*filesystemUsage, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var available, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &available, &total, &free); err != nil {
+		return nil, err
+	}
+
+	// NTFS has no fixed inode table
+	return &filesystemUsage{
+		totalBytes:     total,
+		freeBytes:      free,
+		availableBytes: available,
+	}, nil
+}
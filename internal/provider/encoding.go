@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// textEncodings are the values of the encoding attribute.
+var textEncodings = []string{"utf-8", "utf-16le", "utf-16be", "latin-1"}
+
+var byteOrderMarks = map[string][]byte{
+	"utf-8":    {0xef, 0xbb, 0xbf},
+	"utf-16le": {0xff, 0xfe},
+	"utf-16be": {0xfe, 0xff},
+}
+
+// textCodec converts between content as it's written in the configuration
+// and the bytes of the file.
+type textCodec struct {
+	encoding    string
+	bom         bool
+	lineEndings string
+	compress    string
+	level       int
+}
+
+func textCodecFromResourceData(d *schema.ResourceData) textCodec {
+	return textCodec{
+		encoding:    d.Get("encoding").(string),
+		bom:         d.Get("byte_order_mark").(bool),
+		lineEndings: d.Get("line_endings").(string),
+		compress:    d.Get("compress").(string),
+		level:       d.Get("compression_level").(int),
+	}
+}
+
+// raw reports whether the content is the file as it is, so that it can be
+// hashed without being decoded first.
+func (c textCodec) raw() bool {
+	return (c.encoding == "" || c.encoding == "utf-8") && !c.bom &&
+		(c.lineEndings == "" || c.lineEndings == "none") && (c.compress == "" || c.compress == "none")
+}
+
+func (c textCodec) validate() error {
+	if c.bom && byteOrderMarks[c.encoding] == nil {
+		return fmt.Errorf("byte_order_mark is not supported with encoding %s", c.encoding)
+	}
+	return nil
+}
+
+func (c textCodec) encode(content string) ([]byte, error) {
+	var data []byte
+	if c.bom {
+		data = append(data, byteOrderMarks[c.encoding]...)
+	}
+
+	switch c.encoding {
+	case "utf-16le", "utf-16be":
+		order := utf16ByteOrder(c.encoding)
+		unit := make([]byte, 2)
+		for _, u := range utf16.Encode([]rune(content)) {
+			order.PutUint16(unit, u)
+			data = append(data, unit...)
+		}
+	case "latin-1":
+		for i, r := range content {
+			if r > 0xff {
+				return nil, fmt.Errorf("character %q at offset %d can't be encoded as latin-1", r, i)
+			}
+			data = append(data, byte(r))
+		}
+	default:
+		data = append(data, content...)
+	}
+
+	if c.compress == "gzip" {
+		return gzipData(data, c.level)
+	}
+	return data, nil
+}
+
+// decode returns the content of a file. A byte order mark is only removed
+// when byte_order_mark is set, so that one that shouldn't be there shows up
+// as drift.
+func (c textCodec) decode(data []byte) (string, error) {
+	if c.compress == "gzip" {
+		var err error
+		if data, err = gunzipData(data); err != nil {
+			return "", err
+		}
+	}
+
+	if c.bom {
+		data = bytes.TrimPrefix(data, byteOrderMarks[c.encoding])
+	}
+
+	switch c.encoding {
+	case "utf-16le", "utf-16be":
+		if len(data)%2 != 0 {
+			return "", fmt.Errorf("file has an odd number of bytes and isn't valid %s", c.encoding)
+		}
+		order := utf16ByteOrder(c.encoding)
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		return string(utf16.Decode(units)), nil
+	case "latin-1":
+		buf := make([]byte, 0, len(data))
+		for _, b := range data {
+			buf = utf8.AppendRune(buf, rune(b))
+		}
+		return string(buf), nil
+	default:
+		return string(data), nil
+	}
+}
+
+// binaryContent reports whether content is binary rather than text, as
+// it holds NUL bytes or isn't valid UTF-8. The state can't hold the latter
+// and diffs of either are unreadable.
+func binaryContent(content string) bool {
+	return strings.IndexByte(content, 0) >= 0 || !utf8.ValidString(content)
+}
+
+func utf16ByteOrder(encoding string) binary.ByteOrder {
+	if encoding == "utf-16be" {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	agearmor "filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func encryptSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: "Encrypt the file, so that the content only reaches the disk in encrypted form. Requires store_content = false",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"age_recipients": {
+					Type:         schema.TypeList,
+					Optional:     true,
+					ExactlyOneOf: []string{"encrypt.0.age_recipients", "encrypt.0.gpg_public_keys"},
+					Elem: &schema.Schema{
+						Type:         schema.TypeString,
+						ValidateFunc: validateAgeRecipient,
+					},
+					Description: "age recipients (age1...) that can decrypt the file",
+				},
+				"gpg_public_keys": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem: &schema.Schema{
+						Type:         schema.TypeString,
+						ValidateFunc: validateGPGPublicKey,
+					},
+					Description: "ASCII-armored OpenPGP public keys that can decrypt the file",
+				},
+				"armor": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Default:     false,
+					Description: "Write the encrypted file ASCII-armored instead of binary",
+				},
+			},
+		},
+	}
+}
+
+func validateAgeRecipient(v interface{}, k string) ([]string, []error) {
+	if _, err := age.ParseX25519Recipient(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: %s", k, err)}
+	}
+	return nil, nil
+}
+
+func validateGPGPublicKey(v interface{}, k string) ([]string, []error) {
+	if _, err := openpgp.ReadArmoredKeyRing(strings.NewReader(v.(string))); err != nil {
+		return nil, []error{fmt.Errorf("%s: invalid OpenPGP public key: %s", k, err)}
+	}
+	return nil, nil
+}
+
+// encryption encrypts files to either age recipients or OpenPGP keys.
+type encryption struct {
+	ageRecipients []age.Recipient
+	gpgKeys       openpgp.EntityList
+	armor         bool
+}
+
+// encryptionFromResourceData returns nil when the encrypt block isn't set.
+func encryptionFromResourceData(d *schema.ResourceData) (*encryption, error) {
+	blocks := d.Get("encrypt").([]interface{})
+	if len(blocks) == 0 || blocks[0] == nil {
+		return nil, nil
+	}
+	block := blocks[0].(map[string]interface{})
+
+	e := &encryption{armor: block["armor"].(bool)}
+	for _, v := range block["age_recipients"].([]interface{}) {
+		recipient, err := age.ParseX25519Recipient(v.(string))
+		if err != nil {
+			return nil, err
+		}
+		e.ageRecipients = append(e.ageRecipients, recipient)
+	}
+	for _, v := range block["gpg_public_keys"].([]interface{}) {
+		keys, err := openpgp.ReadArmoredKeyRing(strings.NewReader(v.(string)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OpenPGP public key: %s", err)
+		}
+		e.gpgKeys = append(e.gpgKeys, keys...)
+	}
+
+	return e, nil
+}
+
+func (e *encryption) encrypt(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	out := io.Writer(&buf)
+
+	var armored io.WriteCloser
+	var err error
+	if e.armor {
+		if e.gpgKeys != nil {
+			armored, err = armor.Encode(&buf, "PGP MESSAGE", nil)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			armored = agearmor.NewWriter(&buf)
+		}
+		out = armored
+	}
+
+	var w io.WriteCloser
+	if e.gpgKeys != nil {
+		w, err = openpgp.Encrypt(out, e.gpgKeys, nil, nil, nil)
+	} else {
+		w, err = age.Encrypt(out, e.ageRecipients...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if armored != nil {
+		if err := armored.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
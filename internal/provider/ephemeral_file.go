@@ -0,0 +1,200 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// ephemeralFile writes content to a file in a private temporary directory
+// for the duration of a Terraform run, and removes it when the run is done.
+// The content is never stored in the state.
+type ephemeralFile struct {
+	provider *frameworkProvider
+}
+
+type ephemeralFileModel struct {
+	Content     types.String `tfsdk:"content"`
+	Parent      types.String `tfsdk:"parent"`
+	Filename    types.String `tfsdk:"filename"`
+	Permissions types.String `tfsdk:"permissions"`
+	Path        types.String `tfsdk:"path"`
+}
+
+// ephemeralFileDirKey is the private data key holding the temporary
+// directory to remove on close.
+const ephemeralFileDirKey = "dir"
+
+func newEphemeralFile() ephemeral.EphemeralResource {
+	return &ephemeralFile{}
+}
+
+var (
+	_ ephemeral.EphemeralResourceWithClose          = &ephemeralFile{}
+	_ ephemeral.EphemeralResourceWithConfigure      = &ephemeralFile{}
+	_ ephemeral.EphemeralResourceWithValidateConfig = &ephemeralFile{}
+)
+
+func (e *ephemeralFile) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_file"
+}
+
+func (e *ephemeralFile) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Write content, such as a kubeconfig or credentials, to a temporary file that exists only while Terraform runs",
+		Attributes: map[string]schema.Attribute{
+			"content": schema.StringAttribute{
+				Required:    true,
+				Sensitive:   true,
+				Description: "The content of the file",
+			},
+			"parent": schema.StringAttribute{
+				Optional:    true,
+				Description: "The directory in which the private directory holding the file is created. Defaults to the system temporary directory",
+			},
+			"filename": schema.StringAttribute{
+				Optional:    true,
+				Description: "The name of the file, for programs that expect a particular one. Defaults to 'file'",
+			},
+			"permissions": schema.StringAttribute{
+				Optional:    true,
+				Description: "File permissions in octal format. Defaults to '0600'",
+			},
+			"path": schema.StringAttribute{
+				Computed:    true,
+				Description: "The path to the file",
+			},
+		},
+	}
+}
+
+func (e *ephemeralFile) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if p, ok := req.ProviderData.(*frameworkProvider); ok {
+		e.provider = p
+	}
+}
+
+func (e *ephemeralFile) ValidateConfig(ctx context.Context, req ephemeral.ValidateConfigRequest, resp *ephemeral.ValidateConfigResponse) {
+	var model ephemeralFileModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if v := model.Filename.ValueString(); v != "" && (filepath.Base(v) != v || v == "." || v == "..") {
+		resp.Diagnostics.AddAttributeError(path.Root("filename"), "Invalid filename", fmt.Sprintf("%q must be a file name without directories", v))
+	}
+	if v := model.Permissions.ValueString(); v != "" {
+		if _, err := parsePermissions(v); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("permissions"), "Invalid permissions", err.Error())
+		}
+	}
+}
+
+func (e *ephemeralFile) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	var model ephemeralFileModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	conf := e.provider.config()
+	if conf == nil {
+		resp.Diagnostics.AddError("Provider not configured", "the filesystem provider must be configured before ephemeral resources are opened")
+		return
+	}
+	fsys := withContext(ctx, conf.scratch)
+	parent := model.Parent.ValueString()
+
+	filename := "file"
+	if v := model.Filename.ValueString(); v != "" {
+		filename = v
+	}
+
+	// Parse permissions
+	perm := os.FileMode(0600)
+	if v := model.Permissions.ValueString(); v != "" {
+		var err error
+		if perm, err = parsePermissions(v); err != nil {
+			resp.Diagnostics.AddError("Invalid permissions", err.Error())
+			return
+		}
+	}
+
+	// Make sure the parent directory exists
+	if parent != "" {
+		err := fsys.MkdirAll(parent, conf.defaults.dirPerm())
+		if err != nil {
+			resp.Diagnostics.AddError("Error creating directory", fmt.Sprintf("error creating directory %s: %s", parent, err))
+			return
+		}
+	}
+
+	// The file is written below a directory that only its owner can enter,
+	// so that nobody else can open it before its permissions are applied
+	dir, err := fsys.MkdirTemp(parent, "terraform-ephemeral-")
+	if err != nil {
+		resp.Diagnostics.AddError("Error creating temporary directory", fmt.Sprintf("error creating temporary directory in %s: %s", parent, err))
+		return
+	}
+
+	private, err := json.Marshal(dir)
+	if err == nil {
+		resp.Diagnostics.Append(resp.Private.SetKey(ctx, ephemeralFileDirKey, private)...)
+	} else {
+		resp.Diagnostics.AddError("Error recording temporary directory", err.Error())
+	}
+	if resp.Diagnostics.HasError() {
+		fsys.RemoveAll(dir)
+		return
+	}
+
+	filePath := filepath.Join(dir, filename)
+	err = fsys.WriteFile(filePath, []byte(model.Content.ValueString()), perm)
+	if err == nil {
+		// The umask may have masked the requested mode
+		err = fsys.Chmod(filePath, perm)
+	}
+	if err != nil {
+		fsys.RemoveAll(dir)
+		resp.Diagnostics.AddError("Error writing file", fmt.Sprintf("error writing file %s: %s", filePath, err))
+		return
+	}
+
+	model.Path = types.StringValue(filePath)
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &model)...)
+}
+
+func (e *ephemeralFile) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	data, diags := req.Private.GetKey(ctx, ephemeralFileDirKey)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() || data == nil {
+		return
+	}
+
+	var dir string
+	if err := json.Unmarshal(data, &dir); err != nil {
+		resp.Diagnostics.AddError("Error reading private data", err.Error())
+		return
+	}
+
+	conf := e.provider.config()
+	if conf == nil {
+		resp.Diagnostics.AddError("Provider not configured", "the filesystem provider must be configured before ephemeral resources are closed")
+		return
+	}
+
+	// Delete the directory along with the file
+	fsys := withContext(ctx, conf.scratch)
+	err := fsys.RemoveAll(dir)
+	if err != nil {
+		resp.Diagnostics.AddError("Error deleting temporary directory", fmt.Sprintf("error deleting %s: %s", dir, err))
+	}
+}
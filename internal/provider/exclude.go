@@ -0,0 +1,216 @@
+package provider
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func excludesSchema(description string) *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Schema{
+			Type:         schema.TypeString,
+			ValidateFunc: validateExcludePattern,
+		},
+		Description: description,
+	}
+}
+
+// excludeMatcher matches paths relative to the root of a walk against
+// gitignore-style patterns. A nil matcher matches nothing.
+type excludeMatcher struct {
+	patterns []excludePattern
+}
+
+type excludePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+func validateExcludePattern(v interface{}, k string) ([]string, []error) {
+	if _, err := parseExcludePattern(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: %s", k, err)}
+	}
+	return nil, nil
+}
+
+// newExcludeMatcher compiles the excludes of a resource, returning nil when
+// there are none.
+func newExcludeMatcher(excludes []interface{}) (*excludeMatcher, error) {
+	var patterns []excludePattern
+	for _, v := range excludes {
+		s, _ := v.(string)
+		p, err := parseExcludePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			patterns = append(patterns, *p)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	return &excludeMatcher{patterns: patterns}, nil
+}
+
+// parseExcludePattern parses a pattern the way .gitignore lines are, except
+// that backslashes aren't escapes. It returns nil for blank patterns and
+// comments.
+func parseExcludePattern(pattern string) (*excludePattern, error) {
+	s := strings.TrimSpace(filepath.ToSlash(pattern))
+	if s == "" || strings.HasPrefix(s, "#") {
+		return nil, nil
+	}
+
+	p := &excludePattern{}
+	if strings.HasPrefix(s, "!") {
+		p.negate, s = true, s[1:]
+	}
+	if strings.HasSuffix(s, "/") {
+		p.dirOnly, s = true, strings.TrimRight(s, "/")
+	}
+	if s == "" {
+		return nil, fmt.Errorf("invalid exclude pattern %q", pattern)
+	}
+
+	// Patterns without a slash match at any depth, the others from the root
+	var b strings.Builder
+	b.WriteString("^")
+	if !strings.Contains(s, "/") {
+		b.WriteString("(?:.*/)?")
+	}
+	s = strings.TrimPrefix(s, "/")
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case strings.HasPrefix(s[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(s[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(s[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid exclude pattern %q: unterminated [", pattern)
+			}
+			class := s[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern %q: %s", pattern, err)
+	}
+	p.re = re
+	return p, nil
+}
+
+// match reports whether rel, a slash-separated path relative to the root of
+// a walk, is excluded. As in .gitignore, the last pattern that matches wins.
+func (m *excludeMatcher) match(rel string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+
+	excluded := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(rel) {
+			excluded = !p.negate
+		}
+	}
+	return excluded
+}
+
+// walkDirExcluding is walkDir leaving out what excludes matches below root.
+// Nothing below an excluded directory is visited.
+func walkDirExcluding(fsys fileSystem, root string, excludes *excludeMatcher, fn fs.WalkDirFunc) error {
+	return walkDir(fsys, root, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && path != root && excludes.match(relSlash(root, path), entry.IsDir()) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		return fn(path, entry, err)
+	})
+}
+
+// removeAllExcluding is RemoveAll leaving in place what excludes matches
+// below path, along with the directories holding it.
+func removeAllExcluding(fsys fileSystem, path string, excludes *excludeMatcher) error {
+	if excludes == nil {
+		return fsys.RemoveAll(path)
+	}
+	_, err := removeExcluding(fsys, path, path, excludes)
+	return err
+}
+
+// removeExcluding removes path unless something below it is excluded, and
+// reports whether anything was kept.
+func removeExcluding(fsys fileSystem, root, path string, excludes *excludeMatcher) (bool, error) {
+	info, err := fsys.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if path != root && excludes.match(relSlash(root, path), info.IsDir()) {
+		return true, nil
+	}
+	if !info.IsDir() {
+		return false, fsys.Remove(path)
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		return false, err
+	}
+	kept := false
+	for _, entry := range entries {
+		k, err := removeExcluding(fsys, root, filepath.Join(path, entry.Name()), excludes)
+		if err != nil {
+			return false, err
+		}
+		kept = kept || k
+	}
+	if kept {
+		return true, nil
+	}
+	return false, fsys.Remove(path)
+}
+
+// relSlash returns path relative to root with forward slashes, as patterns
+// are written.
+func relSlash(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
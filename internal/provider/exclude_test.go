@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestExcludeMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		excludes []interface{}
+		rel      string
+		isDir    bool
+		want     bool
+	}{
+		{name: "name at the root", excludes: []interface{}{"cache"}, rel: "cache", want: true},
+		{name: "name at any depth", excludes: []interface{}{"cache"}, rel: "a/b/cache", want: true},
+		{name: "name is not a prefix", excludes: []interface{}{"cache"}, rel: "caches", want: false},
+		{name: "star within a name", excludes: []interface{}{"*.log"}, rel: "logs/app.log", want: true},
+		{name: "star doesn't cross slashes", excludes: []interface{}{"logs/*.log"}, rel: "logs/old/app.log", want: false},
+		{name: "question mark", excludes: []interface{}{"app.?"}, rel: "app.1", want: true},
+		{name: "class", excludes: []interface{}{"app.[0-9]"}, rel: "app.x", want: false},
+		{name: "negated class", excludes: []interface{}{"app.[!0-9]"}, rel: "app.x", want: true},
+		{name: "slash anchors to the root", excludes: []interface{}{"a/cache"}, rel: "b/a/cache", want: false},
+		{name: "leading slash anchors to the root", excludes: []interface{}{"/cache"}, rel: "a/cache", want: false},
+		{name: "leading slash matches at the root", excludes: []interface{}{"/cache"}, rel: "cache", want: true},
+		{name: "double star prefix", excludes: []interface{}{"**/tmp"}, rel: "a/b/tmp", want: true},
+		{name: "double star in the middle", excludes: []interface{}{"a/**/tmp"}, rel: "a/tmp", want: true},
+		{name: "double star suffix", excludes: []interface{}{"a/**"}, rel: "a/b/c", want: true},
+		{name: "directory only matches directories", excludes: []interface{}{"build/"}, rel: "build", isDir: true, want: true},
+		{name: "directory only skips files", excludes: []interface{}{"build/"}, rel: "build", want: false},
+		{name: "negation", excludes: []interface{}{"*.log", "!keep.log"}, rel: "keep.log", want: false},
+		{name: "last match wins", excludes: []interface{}{"!keep.log", "*.log"}, rel: "keep.log", want: true},
+		{name: "regexp characters are literal", excludes: []interface{}{"a+b.txt"}, rel: "aab.txt", want: false},
+		{name: "comments and blanks", excludes: []interface{}{"# cache", " "}, rel: "# cache", want: false},
+		{name: "no excludes", excludes: nil, rel: "anything", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newExcludeMatcher(tt.excludes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.match(tt.rel, tt.isDir); got != tt.want {
+				t.Errorf("match(%q, %t) = %t, want %t", tt.rel, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExcludePatternInvalid(t *testing.T) {
+	for _, pattern := range []string{"!", "/", "app.[0-9"} {
+		if _, err := parseExcludePattern(pattern); err == nil {
+			t.Errorf("parseExcludePattern(%q) succeeded", pattern)
+		}
+	}
+}
+
+func TestRemoveAllExcluding(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "root")
+	for _, name := range []string{"a/keep.txt", "a/drop.txt", "b/drop.txt", "drop.txt"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	excludes, err := newExcludeMatcher([]interface{}{"keep.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := removeAllExcluding(localFileSystem{}, root, excludes); err != nil {
+		t.Fatal(err)
+	}
+
+	var left []string
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		left = append(left, relSlash(root, path))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(left)
+	if got, want := strings.Join(left, " "), ". a a/keep.txt"; got != want {
+		t.Errorf("left %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,35 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// pathAttributes are the attributes of resources that name a path on the
+// target.
+var pathAttributes = []string{"path", "source", "destination", "target", "parent", "mountpoint", "snapshot_of", "device", "backup_path", "versions_dir"}
+
+// withExpandPaths expands the path attributes of r as they're planned when
+// the provider sets expand_paths, so that the plan and the state show the
+// paths the resource manages on the target.
+func withExpandPaths(p *schema.Provider, r *schema.Resource) *schema.Resource {
+	for _, k := range pathAttributes {
+		s, ok := r.Schema[k]
+		if !ok || s.Type != schema.TypeString || s.StateFunc != nil || !s.Required && !s.Optional {
+			continue
+		}
+		s.StateFunc = func(v interface{}) string {
+			path := v.(string)
+			conf, ok := p.Meta().(*providerConfig)
+			if !ok || conf.expand == nil {
+				return path
+			}
+			// What can't be expanded fails once it's used instead
+			expanded, err := conf.expand.expand(path)
+			if err != nil {
+				return path
+			}
+			return expanded
+		}
+	}
+	return r
+}
@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// quarantineXattr is the extended attribute that macOS adds to files that
+// apps downloaded, which Gatekeeper checks before running them.
+const quarantineXattr = "com.apple.quarantine"
+
+// fileFlagNames are the file flags that can be managed, named as chflags
+// names them. Their values are the same on macOS and FreeBSD.
+var fileFlagNames = map[string]uint32{
+	"nodump": 0x1,
+	"uchg":   0x2,
+	"uappnd": 0x4,
+	"opaque": 0x8,
+	"hidden": 0x8000,
+	"schg":   0x20000,
+	"sappnd": 0x40000,
+}
+
+// writeProtectingFlags stop a file from being written, renamed, deleted or
+// having its permissions changed, including by root.
+const writeProtectingFlags = 0x2 | 0x4 | 0x20000 | 0x40000
+
+func fileFlagNameList() []string {
+	names := make([]string, 0, len(fileFlagNames))
+	for name := range fileFlagNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fileFlagsFromSet returns the flags named in a "flags" set.
+func fileFlagsFromSet(v interface{}) uint32 {
+	var flags uint32
+	for _, name := range v.(*schema.Set).List() {
+		flags |= fileFlagNames[name.(string)]
+	}
+	return flags
+}
+
+// liftFlagsFromResourceData clears the write-protecting flags of path that
+// were applied from the state, so that the file can be changed. They are
+// applied again by setFlagsFromResourceData.
+func liftFlagsFromResourceData(fsys fileSystem, d *schema.ResourceData, path string) error {
+	old, _ := d.GetChange("flags")
+	managed := fileFlagsFromSet(old) & writeProtectingFlags
+	if managed == 0 {
+		return nil
+	}
+
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return err
+	}
+	current, ok := fileFlags(info)
+	if !ok || current&managed == 0 {
+		return nil
+	}
+	return fsys.Chflags(path, current&^managed)
+}
+
+// setFlagsFromResourceData sets the configured flags of path and clears
+// the other managed ones, leaving those that can't be managed, such as the
+// flags macOS sets on system files, as they are.
+func setFlagsFromResourceData(fsys fileSystem, d *schema.ResourceData, path string) error {
+	old, n := d.GetChange("flags")
+	flags := fileFlagsFromSet(n)
+	if flags == 0 && !d.HasChange("flags") {
+		return nil
+	}
+
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return err
+	}
+	current, ok := fileFlags(info)
+	if !ok {
+		return fsys.Chflags(path, flags)
+	}
+	if updated := current&^fileFlagsFromSet(old) | flags; updated != current {
+		return fsys.Chflags(path, updated)
+	}
+	return nil
+}
+
+// readFlagsIntoResourceData records the manageable flags of a file whose
+// flags are managed.
+func readFlagsIntoResourceData(d *schema.ResourceData, info os.FileInfo) error {
+	if d.Get("flags").(*schema.Set).Len() == 0 {
+		return nil
+	}
+	current, ok := fileFlags(info)
+	if !ok {
+		return nil
+	}
+
+	names := []interface{}{}
+	for _, name := range fileFlagNameList() {
+		if current&fileFlagNames[name] != 0 {
+			names = append(names, name)
+		}
+	}
+	return d.Set("flags", names)
+}
+
+// customizeDiffQuarantine plans the removal of a quarantine attribute that
+// reappeared, such as when an app wrote the file again.
+func customizeDiffQuarantine(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" || !d.Get("strip_quarantine").(bool) || !d.Get("quarantined").(bool) {
+		return nil
+	}
+	return d.SetNew("quarantined", false)
+}
+
+// stripQuarantineFromResourceData removes the quarantine attribute of path
+// when strip_quarantine is set.
+func stripQuarantineFromResourceData(fsys fileSystem, d *schema.ResourceData, path string) error {
+	if !d.Get("strip_quarantine").(bool) {
+		return nil
+	}
+	err := fsys.RemoveXattr(path, quarantineXattr)
+	if err != nil {
+		return fmt.Errorf("error removing %s of %s: %s", quarantineXattr, path, err)
+	}
+	return nil
+}
+
+// stripQuarantineTree removes the quarantine attribute of path and, when it
+// is a directory, of everything below it. Symlinks aren't followed.
+func stripQuarantineTree(fsys fileSystem, path string) error {
+	info, err := fsys.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return nil
+	}
+	if err := fsys.RemoveXattr(path, quarantineXattr); err != nil {
+		return fmt.Errorf("error removing %s of %s: %s", quarantineXattr, path, err)
+	}
+	if !info.IsDir() {
+		return nil
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := stripQuarantineTree(fsys, filepath.Join(path, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+//go:build darwin || freebsd
+
+package provider
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// localFileFlags returns the flags of a file.
+func localFileFlags(info os.FileInfo) (uint32, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return stat.Flags, true
+}
+
+func chflags(path string, flags uint32) error {
+	if err := unix.Chflags(path, int(flags)); err != nil {
+		return &os.PathError{Op: "chflags", Path: path, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !darwin && !freebsd
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func localFileFlags(info os.FileInfo) (uint32, bool) {
+	return 0, false
+}
+
+func chflags(path string, flags uint32) error {
+	return fmt.Errorf("file flags are not supported on %s", runtime.GOOS)
+}
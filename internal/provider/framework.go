@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	fwprovider "github.com/hashicorp/terraform-plugin-framework/provider"
+	fwschema "github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// frameworkProvider serves what the SDK can't express, such as provider
+// functions, plan modifiers and write-only attributes. It is muxed with sdk,
+// the provider returned by New, which owns the resources and data sources
+// that haven't moved over yet.
+type frameworkProvider struct {
+	sdk *schema.Provider
+}
+
+// NewFramework returns the terraform-plugin-framework half of the provider,
+// sharing the configuration of sdk.
+func NewFramework(sdk *schema.Provider) fwprovider.Provider {
+	return &frameworkProvider{sdk: sdk}
+}
+
+var (
+	_ fwprovider.ProviderWithFunctions          = &frameworkProvider{}
+	_ fwprovider.ProviderWithEphemeralResources = &frameworkProvider{}
+)
+
+func (p *frameworkProvider) Metadata(ctx context.Context, req fwprovider.MetadataRequest, resp *fwprovider.MetadataResponse) {
+	resp.TypeName = "filesystem"
+}
+
+// Schema is derived from the SDK provider's, as muxed providers must have
+// identical provider schemas.
+func (p *frameworkProvider) Schema(ctx context.Context, req fwprovider.SchemaRequest, resp *fwprovider.SchemaResponse) {
+	attrs, blocks := frameworkAttributes(p.sdk.Schema)
+	resp.Schema = fwschema.Schema{Attributes: attrs, Blocks: blocks}
+}
+
+// Configure leaves validating and applying the configuration to the SDK
+// provider, which is configured in the same ConfigureProvider call. Resources
+// and data sources get the provider itself, and use config once they run.
+func (p *frameworkProvider) Configure(ctx context.Context, req fwprovider.ConfigureRequest, resp *fwprovider.ConfigureResponse) {
+	resp.ResourceData = p
+	resp.DataSourceData = p
+	resp.EphemeralResourceData = p
+}
+
+// config returns the configuration shared with the SDK provider.
+func (p *frameworkProvider) config() *providerConfig {
+	conf, _ := p.sdk.Meta().(*providerConfig)
+	return conf
+}
+
+func (p *frameworkProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		newFiles,
+	}
+}
+
+func (p *frameworkProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return []func() datasource.DataSource{
+		newStructuredFile,
+	}
+}
+
+func (p *frameworkProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
+	return []func() ephemeral.EphemeralResource{
+		newEphemeralFile,
+	}
+}
+
+func (p *frameworkProvider) Functions(ctx context.Context) []func() function.Function {
+	return []func() function.Function{
+		newJoinPathFunction,
+		newExpandHomeFunction,
+		newParseModeFunction,
+		newFormatModeFunction,
+		newSanitizeFilenameFunction,
+	}
+}
+
+// frameworkAttributes converts SDK schemas to framework provider schema
+// attributes and blocks. Only the types the provider schema uses are
+// supported.
+func frameworkAttributes(s map[string]*schema.Schema) (map[string]fwschema.Attribute, map[string]fwschema.Block) {
+	attrs := map[string]fwschema.Attribute{}
+	blocks := map[string]fwschema.Block{}
+	for k, v := range s {
+		switch v.Type {
+		case schema.TypeString:
+			attrs[k] = fwschema.StringAttribute{Required: v.Required, Optional: v.Optional, Sensitive: v.Sensitive, Description: v.Description}
+		case schema.TypeInt:
+			attrs[k] = fwschema.Int64Attribute{Required: v.Required, Optional: v.Optional, Sensitive: v.Sensitive, Description: v.Description}
+		case schema.TypeBool:
+			attrs[k] = fwschema.BoolAttribute{Required: v.Required, Optional: v.Optional, Sensitive: v.Sensitive, Description: v.Description}
+		case schema.TypeList:
+			// Lists of values are attributes, and lists of objects blocks
+			if elem, ok := v.Elem.(*schema.Schema); ok {
+				attrs[k] = fwschema.ListAttribute{ElementType: frameworkElementType(k, elem), Required: v.Required, Optional: v.Optional, Sensitive: v.Sensitive, Description: v.Description}
+				continue
+			}
+			a, b := frameworkAttributes(v.Elem.(*schema.Resource).Schema)
+			blocks[k] = fwschema.ListNestedBlock{
+				Description:  v.Description,
+				NestedObject: fwschema.NestedBlockObject{Attributes: a, Blocks: b},
+			}
+		default:
+			panic(fmt.Sprintf("provider attribute %s has unsupported type %s", k, v.Type))
+		}
+	}
+	return attrs, blocks
+}
+
+// frameworkElementType returns the framework type of the elements of the
+// list attribute k.
+func frameworkElementType(k string, elem *schema.Schema) attr.Type {
+	switch elem.Type {
+	case schema.TypeString:
+		return types.StringType
+	case schema.TypeInt:
+		return types.Int64Type
+	case schema.TypeBool:
+		return types.BoolType
+	}
+	panic(fmt.Sprintf("provider attribute %s has unsupported element type %s", k, elem.Type))
+}
@@ -0,0 +1,289 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileSystem is the host that resources and data sources operate on: the
+// machine running Terraform, or a remote machine reached over SFTP. The
+// methods mirror their counterparts in the os package.
+type fileSystem interface {
+	Stat(name string) (os.FileInfo, error)
+	Lstat(name string) (os.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	Open(name string) (io.ReadCloser, error)
+	Create(name string, perm os.FileMode) (io.WriteCloser, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Mkdir(name string, perm os.FileMode) error
+	MkdirAll(path string, perm os.FileMode) error
+	MkdirTemp(dir, pattern string) (string, error)
+	Remove(name string) error
+	RemoveAll(path string) error
+	Rename(oldpath, newpath string) error
+	Chmod(name string, mode os.FileMode) error
+	Lchown(name string, uid, gid int) error
+	Chtimes(name string, atime, mtime time.Time) error
+	Readlink(name string) (string, error)
+	Symlink(oldname, newname string) error
+
+	// Abs returns the absolute form of name on the target. Relative names
+	// are only made absolute where the working directory they resolve
+	// against is known, and are cleaned otherwise
+	Abs(name string) (string, error)
+
+	// Resolve returns the path that name is on the target once the layers
+	// translating and confining paths have checked it, following symlinks.
+	// Commands given to Run and Probe must only be built from resolved
+	// paths, as the layers can't tell the paths in a command apart
+	Resolve(name string) (string, error)
+
+	// Junction creates an NTFS junction point at newname, the directory link
+	// that Windows resolves without the privilege symlinks need
+	Junction(oldname, newname string) error
+
+	// Sync flushes a file or directory to stable storage
+	Sync(name string) error
+
+	// Lock takes an exclusive advisory lock on an existing file without
+	// waiting for it, failing with errLocked while another process holds
+	// it. The returned function releases the lock
+	Lock(name string) (func() error, error)
+
+	// Run executes a shell command on the target host and returns its
+	// output. The output of a failing command is part of the error. env
+	// holds NAME=value pairs to add to the environment of the command,
+	// which is stopped once ctx is done
+	Run(ctx context.Context, command string, env ...string) (string, error)
+
+	// Probe runs a shell command that only inspects the target, such as
+	// when a resource reads what it manages. Unlike Run, read_only allows it
+	Probe(ctx context.Context, command string) (string, error)
+
+	// Quote quotes arg as a single word for the shell that runs the
+	// commands of Run and Probe
+	Quote(arg string) string
+
+	// User and group names are resolved against the target host's account
+	// database, not the machine running Terraform
+	LookupUID(owner string) (int, error)
+	LookupGID(group string) (int, error)
+	UserName(uid int) string
+	GroupName(gid int) string
+
+	// Access reports what the user the provider acts as on the target host
+	// may do with name, following symlinks
+	Access(name string) (*fileAccess, error)
+
+	DiskUsage(path string) (*filesystemUsage, error)
+	Mounts() ([]mountInfo, error)
+	Mknod(path, nodeType string, perm os.FileMode, major, minor uint32) error
+
+	// Chflags replaces the file flags of macOS and FreeBSD, such as hidden
+	// and uchg. They are read from the Sys() of a FileInfo
+	Chflags(name string, flags uint32) error
+
+	// Extended attributes. GetXattr returns nil for an attribute that isn't
+	// set, and RemoveXattr does nothing then
+	GetXattr(path, name string) ([]byte, error)
+	SetXattr(path, name string, value []byte) error
+	RemoveXattr(path, name string) error
+
+	// Windows security descriptors. Accounts resolve to SID strings
+	GetACL(path string) (*fileACL, error)
+	SetACL(path string, acl *fileACL) error
+	LookupSID(account string) (string, error)
+}
+
+// commandError reports a command that failed along with what it printed.
+func commandError(command string, err error, output string) error {
+	if output = strings.TrimSpace(output); output != "" {
+		return fmt.Errorf("%s: %s: %s", command, err, output)
+	}
+	return fmt.Errorf("%s: %s", command, err)
+}
+
+// commandLine joins args into a command line for the Run and Probe of fsys,
+// quoting each of them as a single word.
+func commandLine(fsys fileSystem, args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = fsys.Quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellCommand joins args into a command line for sh, as the remote targets
+// run their commands with.
+func shellCommand(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes s as a single word for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// walkDir is filepath.WalkDir for an arbitrary fileSystem.
+func walkDir(fsys fileSystem, root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDirEntry(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkDirEntry(fsys fileSystem, path string, entry fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, entry, nil); err != nil || !entry.IsDir() {
+		if err == filepath.SkipDir && entry.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		// Give fn a chance to skip the unreadable directory
+		err = fn(path, entry, err)
+		if err != nil {
+			if err == filepath.SkipDir && entry.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, child := range entries {
+		if err := walkDirEntry(fsys, filepath.Join(path, child.Name()), child, fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// mkdirTemp is os.MkdirTemp for an arbitrary fileSystem.
+func mkdirTemp(fsys fileSystem, dir, pattern string) (string, error) {
+	prefix, suffix := pattern, ""
+	if i := strings.LastIndex(pattern, "*"); i >= 0 {
+		prefix, suffix = pattern[:i], pattern[i+1:]
+	}
+
+	for try := 0; try < 100; try++ {
+		random := make([]byte, 6)
+		if _, err := rand.Read(random); err != nil {
+			return "", err
+		}
+
+		name := filepath.Join(dir, prefix+hex.EncodeToString(random)+suffix)
+		if err := fsys.Mkdir(name, 0700); err == nil {
+			return name, nil
+		} else if _, serr := fsys.Lstat(name); serr != nil {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("error creating temporary directory in %s: too many collisions", dir)
+}
+
+// tempName returns a hidden, unused name next to name for writing a new
+// version of it.
+func tempName(name string) (string, error) {
+	// A stream's temporary file is named after the file holding it
+	name, _ = splitStream(name)
+	random := make([]byte, 6)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(name), "."+filepath.Base(name)+".tmp"+hex.EncodeToString(random)), nil
+}
+
+// isTempName reports whether name was returned by tempName.
+func isTempName(name string) bool {
+	base := filepath.Base(name)
+	i := strings.LastIndex(base, ".tmp")
+	if !strings.HasPrefix(base, ".") || i < 0 {
+		return false
+	}
+	random := base[i+len(".tmp"):]
+	_, err := hex.DecodeString(random)
+	return len(random) == 12 && err == nil
+}
+
+// writeFileAtomic replaces name so that readers see either the old or the
+// new content, never a partial write: data goes to a temporary file in the
+// same directory, which is flushed and renamed over name. With syncDir, the
+// directory is flushed too so that the rename itself survives a crash. A
+// non-nil check is called with the temporary file before the rename, and
+// an error from it leaves name as it was.
+func writeFileAtomic(fsys fileSystem, name string, data []byte, perm os.FileMode, syncDir bool, check func(tmp string) error) error {
+	// Replace the file a symlink points to rather than the symlink
+	name, err := resolveSymlinks(fsys, name, true)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(name)
+	tmp, err := tempName(name)
+	if err != nil {
+		return err
+	}
+
+	w, err := fsys.Create(tmp, perm)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		// Keep the mode and owner of the file being replaced, like an
+		// in-place write would. The owner only changes with enough privileges
+		if info, serr := fsys.Stat(name); serr == nil {
+			err = fsys.Chmod(tmp, permissionBits(info.Mode()))
+			if uid, gid, ok := fileOwner(info); ok {
+				fsys.Lchown(tmp, uid, gid)
+			}
+		}
+	}
+	if err == nil {
+		err = fsys.Sync(tmp)
+	}
+	if err == nil && check != nil {
+		err = check(tmp)
+	}
+	if err == nil {
+		err = fsys.Rename(tmp, name)
+	}
+	if err != nil {
+		fsys.Remove(tmp)
+		return err
+	}
+
+	if syncDir {
+		return fsys.Sync(dir)
+	}
+	return nil
+}
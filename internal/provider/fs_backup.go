@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupTimeFormat sorts lexically in chronological order.
+const backupTimeFormat = "20060102T150405.000000000Z"
+
+// backupFileSystem copies files, symlinks and directory trees aside before
+// they are overwritten or removed, keeping at most retention backups of
+// each path.
+type backupFileSystem struct {
+	fileSystem
+
+	// dir mirrors the original paths below it. When empty, backups are
+	// written next to the original.
+	dir       string
+	suffix    string
+	retention int
+}
+
+func (b *backupFileSystem) bindContext(ctx context.Context) fileSystem {
+	c := *b
+	c.fileSystem = bindContext(ctx, b.fileSystem)
+	return &c
+}
+
+// timestampedName returns the name of a copy of path taken at t, which is
+// its base name followed by the time and suffix.
+func timestampedName(path string, t time.Time, suffix string) string {
+	return fmt.Sprintf("%s.%s%s", filepath.Base(path), t.UTC().Format(backupTimeFormat), suffix)
+}
+
+// pruneTimestamped removes the oldest copies of path in dir, as named by
+// timestampedName, beyond the keep newest ones.
+func pruneTimestamped(fsys fileSystem, dir, path, suffix string, keep int) error {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error listing copies in %s: %s", dir, err)
+	}
+
+	prefix := filepath.Base(path) + "."
+	var copies []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix)
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		copies = append(copies, name)
+	}
+
+	sort.Strings(copies)
+	for len(copies) > keep {
+		old := filepath.Join(dir, copies[0])
+		if err := fsys.RemoveAll(old); err != nil {
+			return fmt.Errorf("error removing old copy %s: %s", old, err)
+		}
+		copies = copies[1:]
+	}
+
+	return nil
+}
+
+// backupPath returns where a backup of path taken at t is written.
+func (b *backupFileSystem) backupPath(path string, t time.Time) string {
+	name := timestampedName(path, t, b.suffix)
+	if b.dir == "" {
+		return filepath.Join(filepath.Dir(path), name)
+	}
+
+	abs := filepath.Dir(path)
+	volume := filepath.VolumeName(abs)
+	return filepath.Join(b.dir, strings.TrimSuffix(volume, ":"), abs[len(volume):], name)
+}
+
+func (b *backupFileSystem) backup(path string) error {
+	// Temporary files have random names, so their backups would never be
+	// pruned, and they hold nothing that wasn't written elsewhere
+	if isTempName(path) {
+		return nil
+	}
+
+	info, err := b.fileSystem.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	// Device nodes, FIFOs and sockets hold no content worth keeping
+	if !info.Mode().IsRegular() && !info.IsDir() && info.Mode()&os.ModeSymlink == 0 {
+		return nil
+	}
+
+	dst := b.backupPath(path, time.Now())
+	err = b.fileSystem.MkdirAll(filepath.Dir(dst), 0700)
+	if err != nil {
+		return fmt.Errorf("error creating backup directory %s: %s", filepath.Dir(dst), err)
+	}
+
+	err = copyPath(b.fileSystem, path, dst, copyOptions{mode: true, timestamps: true})
+	if err != nil {
+		return fmt.Errorf("error backing up %s to %s: %s", path, dst, err)
+	}
+
+	return b.prune(path)
+}
+
+// prune removes the oldest backups of path beyond the retention count.
+func (b *backupFileSystem) prune(path string) error {
+	if b.retention <= 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(b.backupPath(path, time.Time{}))
+	return pruneTimestamped(b.fileSystem, dir, path, b.suffix, b.retention)
+}
+
+func (b *backupFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if err := b.backup(name); err != nil {
+		return err
+	}
+	return b.fileSystem.WriteFile(name, data, perm)
+}
+
+func (b *backupFileSystem) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	if err := b.backup(name); err != nil {
+		return nil, err
+	}
+	return b.fileSystem.Create(name, perm)
+}
+
+func (b *backupFileSystem) Remove(name string) error {
+	if err := b.backup(name); err != nil {
+		return err
+	}
+	return b.fileSystem.Remove(name)
+}
+
+func (b *backupFileSystem) RemoveAll(path string) error {
+	if err := b.backup(path); err != nil {
+		return err
+	}
+	return b.fileSystem.RemoveAll(path)
+}
+
+func (b *backupFileSystem) Rename(oldpath, newpath string) error {
+	if err := b.backup(newpath); err != nil {
+		return err
+	}
+	return b.fileSystem.Rename(oldpath, newpath)
+}
@@ -0,0 +1,268 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type becomeConfig struct {
+	method string
+	user   string
+}
+
+// becomeFileSystem performs every operation as a command run through sudo
+// or doas, on the machine running Terraform or over the SSH connection of
+// the ssh block, so that it acts as another user than the one the provider
+// runs or logs in as. File content goes through the standard input and
+// output of the commands. Looking up accounts and listing mounts don't need
+// privileges and go to base, the target as it is without become.
+type becomeFileSystem struct {
+	shellFileSystem
+
+	base   fileSystem
+	conn   *ssh.Client
+	prefix []string
+}
+
+func newBecomeFileSystem(base fileSystem, conn *ssh.Client, c becomeConfig) *becomeFileSystem {
+	b := &becomeFileSystem{
+		base: base,
+		conn: conn,
+		// -n fails instead of prompting for a password nobody could enter,
+		// and env sets what sudo would otherwise reset
+		prefix: []string{c.method, "-n", "-u", c.user, "--", "env", "TZ=UTC", "LC_ALL=C"},
+	}
+	b.shellFileSystem.exec = b.exec
+	b.shellFileSystem.ctx = context.Background()
+	return b
+}
+
+// withContext returns b with its commands stopped once ctx is done.
+func (b *becomeFileSystem) withContext(ctx context.Context) *becomeFileSystem {
+	c := *b
+	c.shellFileSystem.ctx = ctx
+	c.shellFileSystem.exec = c.exec
+	return &c
+}
+
+// command runs args as the user to become, connecting its standard streams
+// to stdin, stdout and stderr, and returns its exit code. The command is
+// stopped once ctx is done.
+func (b *becomeFileSystem) command(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, args ...string) (int, error) {
+	args = append(append([]string(nil), b.prefix...), args...)
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if b.conn != nil {
+		session, err := b.conn.NewSession()
+		if err != nil {
+			return 0, err
+		}
+		defer session.Close()
+		session.Stdin, session.Stdout, session.Stderr = stdin, stdout, stderr
+
+		// Closing the session hangs up the command on the server
+		stop := context.AfterFunc(ctx, func() { session.Close() })
+		defer stop()
+
+		err = session.Run(shellCommand(args...))
+		if cerr := ctx.Err(); cerr != nil {
+			return 0, cerr
+		}
+		var exit *ssh.ExitError
+		if errors.As(err, &exit) {
+			return exit.ExitStatus(), nil
+		}
+		return 0, err
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, stderr
+	// sudo and doas pass SIGTERM on to the command, where the SIGKILL
+	// exec.CommandContext sends by default would only kill them
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = 10 * time.Second
+	err := cmd.Run()
+	if cerr := ctx.Err(); cerr != nil {
+		return 0, cerr
+	}
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return exit.ExitCode(), nil
+	}
+	return 0, err
+}
+
+func (b *becomeFileSystem) exec(ctx context.Context, args ...string) (string, string, int, error) {
+	var stdout, stderr bytes.Buffer
+	code, err := b.command(ctx, nil, &stdout, &stderr, args...)
+	return stdout.String(), stderr.String(), code, err
+}
+
+func (b *becomeFileSystem) ReadFile(name string) ([]byte, error) {
+	r, err := b.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Open streams the file from cat, after checking that it can be read, as
+// what cat fails on would only be reported once the content is read.
+func (b *becomeFileSystem) Open(name string) (io.ReadCloser, error) {
+	info, err := b.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: name, Err: fmt.Errorf("is a directory")}
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		var stderr bytes.Buffer
+		code, err := b.command(b.ctx, nil, pw, &stderr, "cat", "--", name)
+		if err == nil && code != 0 {
+			err = exitError("open", name, "cat", code, stderr.String())
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+// WriteFile keeps the mode and ownership of an existing file, like
+// os.WriteFile does.
+func (b *becomeFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	w, err := b.Create(name, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// createScript writes its standard input to "$1", creating it with the
+// permissions "$2" when it doesn't exist yet.
+const createScript = `if [ ! -e "$1" ]; then (umask 077 && : > "$1") && chmod "$2" -- "$1" || exit 1; fi
+exec cat > "$1"`
+
+// becomeFile is a file written with Create, whose content is streamed to
+// the command writing it until it's closed.
+type becomeFile struct {
+	*io.PipeWriter
+	done chan error
+}
+
+func (w *becomeFile) Close() error {
+	w.PipeWriter.Close()
+	return <-w.done
+}
+
+func (b *becomeFileSystem) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	pr, pw := io.Pipe()
+	w := &becomeFile{PipeWriter: pw, done: make(chan error, 1)}
+	go func() {
+		var stderr bytes.Buffer
+		code, err := b.command(b.ctx, pr, io.Discard, &stderr, "sh", "-c", createScript, "sh", name, formatPermissions(perm))
+		if err == nil && code != 0 {
+			err = exitError("create", name, "sh", code, stderr.String())
+		}
+		// Writing fails from now on rather than waiting for a reader
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w, nil
+}
+
+func (b *becomeFileSystem) MkdirTemp(dir, pattern string) (string, error) {
+	if dir == "" {
+		dir = "/tmp"
+	}
+	return mkdirTemp(b, dir, pattern)
+}
+
+func (b *becomeFileSystem) Junction(oldname, newname string) error {
+	return fmt.Errorf("junctions cannot be created with become")
+}
+
+func (b *becomeFileSystem) Lock(name string) (func() error, error) {
+	return nil, fmt.Errorf("files cannot be locked with become")
+}
+
+func (b *becomeFileSystem) LookupUID(owner string) (int, error) { return b.base.LookupUID(owner) }
+func (b *becomeFileSystem) LookupGID(group string) (int, error) { return b.base.LookupGID(group) }
+func (b *becomeFileSystem) UserName(uid int) string             { return b.base.UserName(uid) }
+func (b *becomeFileSystem) GroupName(gid int) string            { return b.base.GroupName(gid) }
+
+func (b *becomeFileSystem) Mounts() ([]mountInfo, error) { return b.base.Mounts() }
+
+func (b *becomeFileSystem) Abs(name string) (string, error) { return b.base.Abs(name) }
+
+func (b *becomeFileSystem) Resolve(name string) (string, error) {
+	return resolveSymlinks(b, filepath.Clean(name), true)
+}
+
+func (b *becomeFileSystem) Chflags(name string, flags uint32) error {
+	return fmt.Errorf("file flags cannot be managed with become")
+}
+
+// Extended attributes are managed with getfattr and setfattr of the attr
+// package, which the target needs for them.
+func (b *becomeFileSystem) GetXattr(path, name string) ([]byte, error) {
+	stdout, stderr, code, err := b.exec(b.ctx, "getfattr", "--only-values", "-n", name, "--", path)
+	if err != nil {
+		return nil, err
+	}
+	if code != 0 {
+		if strings.Contains(stderr, "No such attribute") {
+			return nil, nil
+		}
+		return nil, exitError("getxattr", path, "getfattr", code, stderr)
+	}
+	return []byte(stdout), nil
+}
+
+func (b *becomeFileSystem) SetXattr(path, name string, value []byte) error {
+	_, err := b.run("setxattr", path, "setfattr", "-n", name, "-v", "0x"+hex.EncodeToString(value), "--", path)
+	return err
+}
+
+func (b *becomeFileSystem) RemoveXattr(path, name string) error {
+	_, stderr, code, err := b.exec(b.ctx, "setfattr", "-x", name, "--", path)
+	if err != nil {
+		return err
+	}
+	if code != 0 && !strings.Contains(stderr, "No such attribute") {
+		return exitError("removexattr", path, "setfattr", code, stderr)
+	}
+	return nil
+}
+
+func (b *becomeFileSystem) GetACL(path string) (*fileACL, error) {
+	return nil, fmt.Errorf("ACLs cannot be managed with become")
+}
+
+func (b *becomeFileSystem) SetACL(path string, acl *fileACL) error {
+	return fmt.Errorf("ACLs cannot be managed with become")
+}
+
+func (b *becomeFileSystem) LookupSID(account string) (string, error) {
+	return "", fmt.Errorf("ACLs cannot be managed with become")
+}
@@ -0,0 +1,285 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// contextFileSystem bounds every call to fs by ctx, so that a resource's
+// timeouts apply even when the target hangs, as with a dead NFS mount or
+// SSH connection. A read that doesn't return in time is abandoned rather
+// than interrupted, and what it opens is closed once it returns. Changes
+// aren't started once ctx is done, but are waited for once they are, as
+// abandoning them would report them as failed while they're still being
+// made. Commands are stopped by the backends running them.
+type contextFileSystem struct {
+	fileSystem
+	ctx context.Context
+}
+
+// withContext returns fsys bounded by ctx.
+func withContext(ctx context.Context, fsys fileSystem) fileSystem {
+	if ctx.Done() == nil {
+		return fsys
+	}
+	return contextFileSystem{fileSystem: bindContext(ctx, fsys), ctx: ctx}
+}
+
+// contextBinder is implemented by the layers that act on the context their
+// calls are bounded by themselves, such as retries waiting between attempts,
+// and by those wrapping such layers.
+type contextBinder interface {
+	bindContext(ctx context.Context) fileSystem
+}
+
+// bindContext returns fsys with ctx given to the layers that take it.
+func bindContext(ctx context.Context, fsys fileSystem) fileSystem {
+	if b, ok := fsys.(contextBinder); ok {
+		return b.bindContext(ctx)
+	}
+	return fsys
+}
+
+// await runs fn, giving up once ctx is done.
+func await[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	return awaitRelease(ctx, fn, nil)
+}
+
+// awaitRelease is await for calls whose result has to be released, such as
+// open files. A result that comes back after the call was abandoned is given
+// to release, when it isn't nil.
+func awaitRelease[T any](ctx context.Context, fn func() (T, error), release func(T)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := fn()
+		done <- result{v, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+		if release != nil {
+			go func() {
+				if r := <-done; r.err == nil {
+					release(r.v)
+				}
+			}()
+		}
+		return zero, ctx.Err()
+	}
+}
+
+// do makes a change unless ctx is done already.
+func (c contextFileSystem) do(fn func() error) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	return fn()
+}
+
+func (c contextFileSystem) Stat(name string) (os.FileInfo, error) {
+	return await(c.ctx, func() (os.FileInfo, error) { return c.fileSystem.Stat(name) })
+}
+
+func (c contextFileSystem) Lstat(name string) (os.FileInfo, error) {
+	return await(c.ctx, func() (os.FileInfo, error) { return c.fileSystem.Lstat(name) })
+}
+
+func (c contextFileSystem) ReadFile(name string) ([]byte, error) {
+	return await(c.ctx, func() ([]byte, error) { return c.fileSystem.ReadFile(name) })
+}
+
+func (c contextFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return c.do(func() error { return c.fileSystem.WriteFile(name, data, perm) })
+}
+
+func (c contextFileSystem) Open(name string) (io.ReadCloser, error) {
+	r, err := awaitRelease(c.ctx, func() (io.ReadCloser, error) { return c.fileSystem.Open(name) }, func(r io.ReadCloser) { r.Close() })
+	if err != nil {
+		return nil, err
+	}
+	return contextReadCloser{r, c.ctx}, nil
+}
+
+func (c contextFileSystem) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	w, err := c.fileSystem.Create(name, perm)
+	if err != nil {
+		return nil, err
+	}
+	return contextWriteCloser{w, c.ctx}, nil
+}
+
+func (c contextFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return await(c.ctx, func() ([]fs.DirEntry, error) { return c.fileSystem.ReadDir(name) })
+}
+
+func (c contextFileSystem) Mkdir(name string, perm os.FileMode) error {
+	return c.do(func() error { return c.fileSystem.Mkdir(name, perm) })
+}
+
+func (c contextFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	return c.do(func() error { return c.fileSystem.MkdirAll(path, perm) })
+}
+
+func (c contextFileSystem) MkdirTemp(dir, pattern string) (string, error) {
+	if err := c.ctx.Err(); err != nil {
+		return "", err
+	}
+	return c.fileSystem.MkdirTemp(dir, pattern)
+}
+
+func (c contextFileSystem) Remove(name string) error {
+	return c.do(func() error { return c.fileSystem.Remove(name) })
+}
+
+func (c contextFileSystem) RemoveAll(path string) error {
+	return c.do(func() error { return c.fileSystem.RemoveAll(path) })
+}
+
+func (c contextFileSystem) Rename(oldpath, newpath string) error {
+	return c.do(func() error { return c.fileSystem.Rename(oldpath, newpath) })
+}
+
+func (c contextFileSystem) Chmod(name string, mode os.FileMode) error {
+	return c.do(func() error { return c.fileSystem.Chmod(name, mode) })
+}
+
+func (c contextFileSystem) Lchown(name string, uid, gid int) error {
+	return c.do(func() error { return c.fileSystem.Lchown(name, uid, gid) })
+}
+
+func (c contextFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	return c.do(func() error { return c.fileSystem.Chtimes(name, atime, mtime) })
+}
+
+func (c contextFileSystem) Readlink(name string) (string, error) {
+	return await(c.ctx, func() (string, error) { return c.fileSystem.Readlink(name) })
+}
+
+func (c contextFileSystem) Resolve(name string) (string, error) {
+	return await(c.ctx, func() (string, error) { return c.fileSystem.Resolve(name) })
+}
+
+func (c contextFileSystem) Symlink(oldname, newname string) error {
+	return c.do(func() error { return c.fileSystem.Symlink(oldname, newname) })
+}
+
+func (c contextFileSystem) Junction(oldname, newname string) error {
+	return c.do(func() error { return c.fileSystem.Junction(oldname, newname) })
+}
+
+func (c contextFileSystem) Sync(name string) error {
+	return c.do(func() error { return c.fileSystem.Sync(name) })
+}
+
+func (c contextFileSystem) Lock(name string) (func() error, error) {
+	return awaitRelease(c.ctx, func() (func() error, error) { return c.fileSystem.Lock(name) }, func(unlock func() error) { unlock() })
+}
+
+func (c contextFileSystem) Run(ctx context.Context, command string, env ...string) (string, error) {
+	return c.fileSystem.Run(ctx, command, env...)
+}
+
+func (c contextFileSystem) Probe(ctx context.Context, command string) (string, error) {
+	return c.fileSystem.Probe(ctx, command)
+}
+
+func (c contextFileSystem) LookupUID(owner string) (int, error) {
+	return await(c.ctx, func() (int, error) { return c.fileSystem.LookupUID(owner) })
+}
+
+func (c contextFileSystem) LookupGID(group string) (int, error) {
+	return await(c.ctx, func() (int, error) { return c.fileSystem.LookupGID(group) })
+}
+
+func (c contextFileSystem) Access(name string) (*fileAccess, error) {
+	return await(c.ctx, func() (*fileAccess, error) { return c.fileSystem.Access(name) })
+}
+
+func (c contextFileSystem) DiskUsage(path string) (*filesystemUsage, error) {
+	return await(c.ctx, func() (*filesystemUsage, error) { return c.fileSystem.DiskUsage(path) })
+}
+
+func (c contextFileSystem) Mounts() ([]mountInfo, error) {
+	return await(c.ctx, c.fileSystem.Mounts)
+}
+
+func (c contextFileSystem) Mknod(path, nodeType string, perm os.FileMode, major, minor uint32) error {
+	return c.do(func() error { return c.fileSystem.Mknod(path, nodeType, perm, major, minor) })
+}
+
+func (c contextFileSystem) Chflags(name string, flags uint32) error {
+	return c.do(func() error { return c.fileSystem.Chflags(name, flags) })
+}
+
+func (c contextFileSystem) GetXattr(path, name string) ([]byte, error) {
+	return await(c.ctx, func() ([]byte, error) { return c.fileSystem.GetXattr(path, name) })
+}
+
+func (c contextFileSystem) SetXattr(path, name string, value []byte) error {
+	return c.do(func() error { return c.fileSystem.SetXattr(path, name, value) })
+}
+
+func (c contextFileSystem) RemoveXattr(path, name string) error {
+	return c.do(func() error { return c.fileSystem.RemoveXattr(path, name) })
+}
+
+func (c contextFileSystem) GetACL(path string) (*fileACL, error) {
+	return await(c.ctx, func() (*fileACL, error) { return c.fileSystem.GetACL(path) })
+}
+
+func (c contextFileSystem) SetACL(path string, acl *fileACL) error {
+	return c.do(func() error { return c.fileSystem.SetACL(path, acl) })
+}
+
+func (c contextFileSystem) LookupSID(account string) (string, error) {
+	return await(c.ctx, func() (string, error) { return c.fileSystem.LookupSID(account) })
+}
+
+// contextReadCloser and contextWriteCloser bound each call on an open file,
+// so that long copies and checksums stop when their resource times out.
+type contextReadCloser struct {
+	r   io.ReadCloser
+	ctx context.Context
+}
+
+// Read reads into a buffer of its own, which an abandoned read may still
+// fill after p is the caller's again.
+func (c contextReadCloser) Read(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	n, err := await(c.ctx, func() (int, error) { return c.r.Read(buf) })
+	copy(p, buf[:n])
+	return n, err
+}
+
+func (c contextReadCloser) Close() error { return c.r.Close() }
+
+type contextWriteCloser struct {
+	w   io.WriteCloser
+	ctx context.Context
+}
+
+func (c contextWriteCloser) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.w.Write(p)
+}
+
+func (c contextWriteCloser) Close() error { return c.w.Close() }
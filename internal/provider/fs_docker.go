@@ -0,0 +1,546 @@
+package provider
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type dockerConfig struct {
+	host        string
+	container   string
+	user        string
+	tlsVerify   bool
+	certPath    string
+	insecureTCP bool
+}
+
+// dockerAPIVersion is the version of the Engine API requests are made in,
+// that of Docker 19.03, so that newer daemons keep answering them the same.
+const dockerAPIVersion = "1.40"
+
+// dockerFileSystem operates inside a running container through the Docker
+// Engine API. File content is transferred with the archive endpoints and
+// everything else runs as a command in the container.
+type dockerFileSystem struct {
+	shellFileSystem
+
+	client    *http.Client
+	endpoint  string
+	container string
+	user      string
+
+	accounts accountFiles
+}
+
+// dockerStatFormat is the stat(1) format parsed by parseDockerStat.
+const dockerStatFormat = "%f %s %Y %X %u %g %i %n"
+
+func newDockerFileSystem(c dockerConfig) (*dockerFileSystem, error) {
+	u, err := url.Parse(c.host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %s", c.host, err)
+	}
+
+	f := &dockerFileSystem{container: c.container, user: c.user}
+	f.shellFileSystem.exec = f.exec
+	f.shellFileSystem.ctx = context.Background()
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		f.endpoint = "http://docker"
+		f.client = &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		}}
+	case "tcp", "http":
+		switch {
+		case c.tlsVerify:
+			tlsConfig, err := dockerTLSConfig(c.certPath)
+			if err != nil {
+				return nil, err
+			}
+			f.endpoint = "https://" + u.Host
+			f.client = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		case c.insecureTCP:
+			f.endpoint = "http://" + u.Host
+			f.client = &http.Client{}
+		default:
+			// Anyone reaching a daemon without TLS can run anything on it
+			return nil, fmt.Errorf("docker host %q is not protected by TLS: set docker_tls_verify, or docker_insecure_tcp to connect without it", c.host)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported docker host %q: only unix:// and tcp:// are supported", c.host)
+	}
+
+	// Make sure the container is there and running
+	var inspect struct {
+		State struct {
+			Running bool
+		}
+	}
+	resp, err := f.request(context.Background(), http.MethodGet, "/containers/"+url.PathEscape(c.container)+"/json", nil, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting container %s: %s", c.container, err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return nil, fmt.Errorf("error inspecting container %s: %s", c.container, err)
+	}
+	if !inspect.State.Running {
+		return nil, fmt.Errorf("container %s is not running", c.container)
+	}
+
+	return f, nil
+}
+
+// dockerTLSConfig verifies the daemon against ca.pem in certPath and
+// authenticates with the client certificate in cert.pem and key.pem, the
+// files the Docker CLI uses with DOCKER_TLS_VERIFY.
+func dockerTLSConfig(certPath string) (*tls.Config, error) {
+	if certPath == "" {
+		certPath = "~/.docker"
+	}
+	certPath, err := expandHome(certPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := os.ReadFile(filepath.Join(certPath, "ca.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading the docker CA certificate: %s", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in %s", filepath.Join(certPath, "ca.pem"))
+	}
+	cert, err := tls.LoadX509KeyPair(filepath.Join(certPath, "cert.pem"), filepath.Join(certPath, "key.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading the docker client certificate: %s", err)
+	}
+
+	return &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// request calls the Docker Engine API, turning error responses into errors.
+func (f *dockerFileSystem) request(ctx context.Context, method, endpoint string, query url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	u := f.endpoint + "/v" + dockerAPIVersion + endpoint
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		if resp.StatusCode == http.StatusNotFound && strings.Contains(endpoint, "/archive") {
+			return nil, os.ErrNotExist
+		}
+		return nil, fmt.Errorf("docker API %s %s: %s (%s)", method, endpoint, apiErr.Message, resp.Status)
+	}
+	return resp, nil
+}
+
+func (f *dockerFileSystem) postJSON(ctx context.Context, endpoint string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	resp, err := f.request(ctx, http.MethodPost, endpoint, nil, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// exec executes a command in the container and returns its output and exit
+// code. The Engine API has no way to stop a command, so one that is still
+// running once ctx is done is left to finish in the container.
+func (f *dockerFileSystem) exec(ctx context.Context, args ...string) (string, string, int, error) {
+	var created struct {
+		ID string `json:"Id"`
+	}
+	err := f.postJSON(ctx, "/containers/"+url.PathEscape(f.container)+"/exec", map[string]interface{}{
+		"Cmd":          args,
+		"User":         f.user,
+		"Env":          []string{"TZ=UTC", "LC_ALL=C"},
+		"AttachStdout": true,
+		"AttachStderr": true,
+	}, &created)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	body, err := json.Marshal(map[string]bool{"Detach": false, "Tty": false})
+	if err != nil {
+		return "", "", 0, err
+	}
+	resp, err := f.request(ctx, http.MethodPost, "/exec/"+created.ID+"/start", nil, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", "", 0, err
+	}
+	var out, errOut bytes.Buffer
+	err = demuxDockerStream(resp.Body, &out, &errOut)
+	resp.Body.Close()
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	var inspect struct {
+		ExitCode int
+	}
+	resp, err = f.request(ctx, http.MethodGet, "/exec/"+created.ID+"/json", nil, "", nil)
+	if err != nil {
+		return "", "", 0, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return "", "", 0, err
+	}
+
+	return out.String(), errOut.String(), inspect.ExitCode, nil
+}
+
+// demuxDockerStream splits the multiplexed stdout/stderr stream returned by
+// the exec and attach endpoints when no TTY is allocated.
+func demuxDockerStream(r io.Reader, stdout, stderr io.Writer) error {
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		w := stdout
+		if header[0] == 2 {
+			w = stderr
+		}
+		if _, err := io.CopyN(w, r, int64(binary.BigEndian.Uint32(header[4:]))); err != nil {
+			return err
+		}
+	}
+}
+
+// dockerFileStat is the Sys() value of the FileInfo returned by
+// dockerFileSystem.
+type dockerFileStat struct {
+	uid   int
+	gid   int
+	inode uint64
+	atime time.Time
+}
+
+type dockerFileInfo struct {
+	name  string
+	size  int64
+	mode  os.FileMode
+	mtime time.Time
+	sys   *dockerFileStat
+}
+
+func (i *dockerFileInfo) Name() string       { return i.name }
+func (i *dockerFileInfo) Size() int64        { return i.size }
+func (i *dockerFileInfo) Mode() os.FileMode  { return i.mode }
+func (i *dockerFileInfo) ModTime() time.Time { return i.mtime }
+func (i *dockerFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *dockerFileInfo) Sys() interface{}   { return i.sys }
+
+// parseDockerStat parses a line of stat(1) output in dockerStatFormat.
+func parseDockerStat(line string) (*dockerFileInfo, error) {
+	fields := strings.SplitN(line, " ", 8)
+	if len(fields) != 8 {
+		return nil, fmt.Errorf("unexpected stat output %q", line)
+	}
+
+	var nums [7]uint64
+	for i, base := range []int{16, 10, 10, 10, 10, 10, 10} {
+		n, err := strconv.ParseUint(fields[i], base, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected stat output %q", line)
+		}
+		nums[i] = n
+	}
+
+	return &dockerFileInfo{
+		name:  path.Base(fields[7]),
+		size:  int64(nums[1]),
+		mode:  fileModeFromUnix(uint32(nums[0])),
+		mtime: time.Unix(int64(nums[2]), 0),
+		sys: &dockerFileStat{
+			uid:   int(nums[4]),
+			gid:   int(nums[5]),
+			inode: nums[6],
+			atime: time.Unix(int64(nums[3]), 0),
+		},
+	}, nil
+}
+
+// fileModeFromUnix converts a st_mode value to an os.FileMode.
+func fileModeFromUnix(m uint32) os.FileMode {
+	mode := os.FileMode(m & 0777)
+	switch m & 0170000 {
+	case 0140000:
+		mode |= os.ModeSocket
+	case 0120000:
+		mode |= os.ModeSymlink
+	case 0060000:
+		mode |= os.ModeDevice
+	case 0040000:
+		mode |= os.ModeDir
+	case 0020000:
+		mode |= os.ModeDevice | os.ModeCharDevice
+	case 0010000:
+		mode |= os.ModeNamedPipe
+	}
+	if m&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if m&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if m&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+func (f *dockerFileSystem) ReadFile(name string) ([]byte, error) {
+	r, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Open follows symlinks itself, as the archive endpoint returns the link
+// rather than its target.
+func (f *dockerFileSystem) Open(name string) (io.ReadCloser, error) {
+	target := name
+	for links := 0; links < 40; links++ {
+		resp, err := f.request(f.ctx, http.MethodGet, "/containers/"+url.PathEscape(f.container)+"/archive", url.Values{"path": {target}}, "", nil)
+		if err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		tr := tar.NewReader(resp.Body)
+		header, err := tr.Next()
+		if err != nil {
+			resp.Body.Close()
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		switch header.Typeflag {
+		case tar.TypeReg:
+			return struct {
+				io.Reader
+				io.Closer
+			}{tr, resp.Body}, nil
+		case tar.TypeSymlink:
+			resp.Body.Close()
+			if path.IsAbs(header.Linkname) {
+				target = header.Linkname
+			} else {
+				target = path.Join(path.Dir(target), header.Linkname)
+			}
+		case tar.TypeDir:
+			resp.Body.Close()
+			return nil, &os.PathError{Op: "open", Path: name, Err: fmt.Errorf("is a directory")}
+		default:
+			resp.Body.Close()
+			return nil, &os.PathError{Op: "open", Path: name, Err: fmt.Errorf("not a regular file")}
+		}
+	}
+	return nil, &os.PathError{Op: "open", Path: name, Err: fmt.Errorf("too many levels of symbolic links")}
+}
+
+// WriteFile keeps the mode and ownership of an existing file, like
+// os.WriteFile does.
+func (f *dockerFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return f.upload(name, bytes.NewReader(data), int64(len(data)), perm)
+}
+
+// upload streams size bytes of content to name as a single-file archive,
+// keeping the mode and ownership of an existing file.
+func (f *dockerFileSystem) upload(name string, content io.Reader, size int64, perm os.FileMode) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path.Base(name),
+		Size:     size,
+		Mode:     int64(unixPermissions(perm)),
+		ModTime:  time.Now(),
+	}
+
+	if info, err := f.Stat(name); err == nil {
+		if info.IsDir() {
+			return &os.PathError{Op: "write", Path: name, Err: fmt.Errorf("is a directory")}
+		}
+		st := info.Sys().(*dockerFileStat)
+		header.Mode = int64(unixPermissions(info.Mode()))
+		header.Uid, header.Gid = st.uid, st.gid
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// The archive is written while it's being sent
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(header)
+		if err == nil {
+			_, err = streamCopy(tw, content, "uploading", name, size)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	resp, err := f.request(f.ctx, http.MethodPut, "/containers/"+url.PathEscape(f.container)+"/archive", url.Values{"path": {path.Dir(name)}}, "application/x-tar", pr)
+	pr.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		return &os.PathError{Op: "write", Path: name, Err: err}
+	}
+	return resp.Body.Close()
+}
+
+// dockerFile collects a file written with Create in a local temporary file
+// until it's closed, as an archive needs the size of the file up front.
+type dockerFile struct {
+	*os.File
+	fsys *dockerFileSystem
+	name string
+	perm os.FileMode
+}
+
+func (w *dockerFile) Close() error {
+	defer os.Remove(w.File.Name())
+	defer w.File.Close()
+
+	size, err := w.File.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := w.File.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return w.fsys.upload(w.name, w.File, size, w.perm)
+}
+
+func (f *dockerFileSystem) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	spool, err := os.CreateTemp("", "terraform-provider-filesystem-*")
+	if err != nil {
+		return nil, err
+	}
+	return &dockerFile{File: spool, fsys: f, name: name, perm: perm}, nil
+}
+
+func (f *dockerFileSystem) MkdirTemp(dir, pattern string) (string, error) {
+	if dir == "" {
+		dir = "/tmp"
+	}
+	return mkdirTemp(f, dir, pattern)
+}
+
+func (f *dockerFileSystem) Resolve(name string) (string, error) {
+	return resolveSymlinks(f, filepath.Clean(name), true)
+}
+
+func (f *dockerFileSystem) Junction(oldname, newname string) error {
+	return fmt.Errorf("junctions cannot be created in containers")
+}
+
+func (f *dockerFileSystem) LookupUID(owner string) (int, error) {
+	return f.accounts.lookupUID(f, owner)
+}
+
+func (f *dockerFileSystem) LookupGID(group string) (int, error) {
+	return f.accounts.lookupGID(f, group)
+}
+
+func (f *dockerFileSystem) UserName(uid int) string  { return f.accounts.userName(f, uid) }
+func (f *dockerFileSystem) GroupName(gid int) string { return f.accounts.groupName(f, gid) }
+
+func (f *dockerFileSystem) Mounts() ([]mountInfo, error) {
+	content, err := f.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return nil, fmt.Errorf("error reading /proc/self/mountinfo in container: %s", err)
+	}
+	return parseMountInfo(string(content))
+}
+
+func (f *dockerFileSystem) Lock(name string) (func() error, error) {
+	return nil, fmt.Errorf("files cannot be locked in containers")
+}
+
+func (f *dockerFileSystem) Chflags(name string, flags uint32) error {
+	return fmt.Errorf("file flags cannot be managed in containers")
+}
+
+func (f *dockerFileSystem) GetXattr(path, name string) ([]byte, error) {
+	return nil, fmt.Errorf("extended attributes cannot be managed in containers")
+}
+
+func (f *dockerFileSystem) SetXattr(path, name string, value []byte) error {
+	return fmt.Errorf("extended attributes cannot be managed in containers")
+}
+
+func (f *dockerFileSystem) RemoveXattr(path, name string) error {
+	return fmt.Errorf("extended attributes cannot be managed in containers")
+}
+
+func (f *dockerFileSystem) GetACL(path string) (*fileACL, error) {
+	return nil, fmt.Errorf("ACLs cannot be managed in containers")
+}
+
+func (f *dockerFileSystem) SetACL(path string, acl *fileACL) error {
+	return fmt.Errorf("ACLs cannot be managed in containers")
+}
+
+func (f *dockerFileSystem) LookupSID(account string) (string, error) {
+	return "", fmt.Errorf("ACLs cannot be managed in containers")
+}
@@ -0,0 +1,417 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// expandFileSystem expands a leading "~" or "~user" and environment
+// variables in every path before passing it on to fs, using the home
+// directories and the environment of the target.
+type expandFileSystem struct {
+	fileSystem
+	paths *pathExpander
+}
+
+func (e expandFileSystem) bindContext(ctx context.Context) fileSystem {
+	e.fileSystem = bindContext(ctx, e.fileSystem)
+	return e
+}
+
+// envReference matches $VAR, ${VAR} and %VAR%.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)|%([A-Za-z_][A-Za-z0-9_]*)%`)
+
+// pathExpander expands paths with the home directories and the environment
+// of a target.
+type pathExpander struct {
+	lookupEnv func(name string) (string, bool, error)
+	homeDir   func(name string) (string, error)
+}
+
+// localPathExpander expands paths for the machine running Terraform.
+func localPathExpander() *pathExpander {
+	return &pathExpander{
+		lookupEnv: func(name string) (string, bool, error) {
+			value, ok := os.LookupEnv(name)
+			return value, ok, nil
+		},
+		homeDir: localHomeDir,
+	}
+}
+
+// targetPathExpander expands paths for a remote target, looking them up with
+// commands run on fsys: the environment is that of the commands, read once,
+// and home directories come from the account database. What is read is
+// kept for every later expansion, so it isn't read with the context of the
+// resource that happens to expand a path first.
+func targetPathExpander(fsys fileSystem) *pathExpander {
+	var (
+		once   sync.Once
+		env    map[string]string
+		envErr error
+		mu     sync.Mutex
+		homes  = map[string]string{}
+	)
+	x := &pathExpander{}
+	x.lookupEnv = func(name string) (string, bool, error) {
+		once.Do(func() {
+			var out string
+			out, envErr = fsys.Probe(context.Background(), "env")
+			env = map[string]string{}
+			for _, line := range strings.Split(out, "\n") {
+				if k, v, ok := strings.Cut(line, "="); ok {
+					env[k] = v
+				}
+			}
+		})
+		if envErr != nil {
+			return "", false, fmt.Errorf("error reading the environment of the target: %s", envErr)
+		}
+		value, ok := env[name]
+		return value, ok, nil
+	}
+	x.homeDir = func(name string) (string, error) {
+		if name == "" {
+			home, ok, err := x.lookupEnv("HOME")
+			if err == nil && !ok {
+				err = fmt.Errorf("HOME is not set on the target")
+			}
+			return home, err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if home, ok := homes[name]; ok {
+			return home, nil
+		}
+		out, err := fsys.Probe(context.Background(), shellCommand("getent", "passwd", name))
+		fields := strings.Split(strings.TrimSpace(out), ":")
+		if err != nil || len(fields) < 6 {
+			return "", fmt.Errorf("error looking up user %s", name)
+		}
+		homes[name] = fields[5]
+		return fields[5], nil
+	}
+	return x
+}
+
+// expand expands the home directory and environment variables in path.
+// Unset variables are an error, except in %VAR% form, which is kept as it
+// is the way cmd.exe does.
+func (x *pathExpander) expand(path string) (string, error) {
+	var err error
+	path = envReference.ReplaceAllStringFunc(path, func(ref string) string {
+		m := envReference.FindStringSubmatch(ref)
+		name := m[1] + m[2] + m[3]
+		value, ok, lerr := x.lookupEnv(name)
+		if lerr != nil && err == nil {
+			err = lerr
+		}
+		if !ok {
+			if m[3] == "" && err == nil {
+				err = fmt.Errorf("environment variable %s is not set", name)
+			}
+			return ref
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return replaceHome(path, x.homeDir)
+}
+
+func (e expandFileSystem) expandPath(name string) (string, error) {
+	return e.paths.expand(name)
+}
+
+func expandError(op, path string, err error) error {
+	return &os.PathError{Op: op, Path: path, Err: err}
+}
+
+func (e expandFileSystem) Stat(name string) (os.FileInfo, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return nil, expandError("stat", name, err)
+	}
+	return e.fileSystem.Stat(path)
+}
+
+func (e expandFileSystem) Lstat(name string) (os.FileInfo, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return nil, expandError("lstat", name, err)
+	}
+	return e.fileSystem.Lstat(path)
+}
+
+func (e expandFileSystem) ReadFile(name string) ([]byte, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return nil, expandError("read", name, err)
+	}
+	return e.fileSystem.ReadFile(path)
+}
+
+func (e expandFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("write", name, err)
+	}
+	return e.fileSystem.WriteFile(path, data, perm)
+}
+
+func (e expandFileSystem) Open(name string) (io.ReadCloser, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return nil, expandError("open", name, err)
+	}
+	return e.fileSystem.Open(path)
+}
+
+func (e expandFileSystem) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return nil, expandError("create", name, err)
+	}
+	return e.fileSystem.Create(path, perm)
+}
+
+func (e expandFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return nil, expandError("readdir", name, err)
+	}
+	return e.fileSystem.ReadDir(path)
+}
+
+func (e expandFileSystem) Mkdir(name string, perm os.FileMode) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("mkdir", name, err)
+	}
+	return e.fileSystem.Mkdir(path, perm)
+}
+
+func (e expandFileSystem) MkdirAll(name string, perm os.FileMode) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("mkdir", name, err)
+	}
+	return e.fileSystem.MkdirAll(path, perm)
+}
+
+func (e expandFileSystem) MkdirTemp(dir, pattern string) (string, error) {
+	path, err := e.expandPath(dir)
+	if err != nil {
+		return "", expandError("mkdirtemp", dir, err)
+	}
+	return e.fileSystem.MkdirTemp(path, pattern)
+}
+
+func (e expandFileSystem) Remove(name string) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("remove", name, err)
+	}
+	return e.fileSystem.Remove(path)
+}
+
+func (e expandFileSystem) RemoveAll(name string) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("remove", name, err)
+	}
+	return e.fileSystem.RemoveAll(path)
+}
+
+func (e expandFileSystem) Rename(oldpath, newpath string) error {
+	from, err := e.expandPath(oldpath)
+	if err != nil {
+		return expandError("rename", oldpath, err)
+	}
+	to, err := e.expandPath(newpath)
+	if err != nil {
+		return expandError("rename", newpath, err)
+	}
+	return e.fileSystem.Rename(from, to)
+}
+
+func (e expandFileSystem) Chmod(name string, mode os.FileMode) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("chmod", name, err)
+	}
+	return e.fileSystem.Chmod(path, mode)
+}
+
+func (e expandFileSystem) Lchown(name string, uid, gid int) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("lchown", name, err)
+	}
+	return e.fileSystem.Lchown(path, uid, gid)
+}
+
+func (e expandFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("chtimes", name, err)
+	}
+	return e.fileSystem.Chtimes(path, atime, mtime)
+}
+
+func (e expandFileSystem) Readlink(name string) (string, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return "", expandError("readlink", name, err)
+	}
+	return e.fileSystem.Readlink(path)
+}
+
+// Symlink expands the target as well as the link, as targets are configured
+// like any other path.
+func (e expandFileSystem) Symlink(oldname, newname string) error {
+	target, err := e.expandPath(oldname)
+	if err != nil {
+		return expandError("symlink", oldname, err)
+	}
+	path, err := e.expandPath(newname)
+	if err != nil {
+		return expandError("symlink", newname, err)
+	}
+	return e.fileSystem.Symlink(target, path)
+}
+
+func (e expandFileSystem) Abs(name string) (string, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return "", expandError("abs", name, err)
+	}
+	return e.fileSystem.Abs(path)
+}
+
+func (e expandFileSystem) Junction(oldname, newname string) error {
+	target, err := e.expandPath(oldname)
+	if err != nil {
+		return expandError("junction", oldname, err)
+	}
+	path, err := e.expandPath(newname)
+	if err != nil {
+		return expandError("junction", newname, err)
+	}
+	return e.fileSystem.Junction(target, path)
+}
+
+func (e expandFileSystem) Sync(name string) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("sync", name, err)
+	}
+	return e.fileSystem.Sync(path)
+}
+
+func (e expandFileSystem) Lock(name string) (func() error, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return nil, expandError("lock", name, err)
+	}
+	return e.fileSystem.Lock(path)
+}
+
+func (e expandFileSystem) Resolve(name string) (string, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return "", expandError("resolve", name, err)
+	}
+	return e.fileSystem.Resolve(path)
+}
+
+// Run can't tell the paths in a command apart from the rest of it, so
+// commands are only ever built from paths given by Resolve.
+func (e expandFileSystem) Run(ctx context.Context, command string, env ...string) (string, error) {
+	return e.fileSystem.Run(ctx, command, env...)
+}
+
+func (e expandFileSystem) Probe(ctx context.Context, command string) (string, error) {
+	return e.fileSystem.Probe(ctx, command)
+}
+
+func (e expandFileSystem) Access(name string) (*fileAccess, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return nil, expandError("access", name, err)
+	}
+	return e.fileSystem.Access(path)
+}
+
+func (e expandFileSystem) DiskUsage(name string) (*filesystemUsage, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return nil, expandError("statfs", name, err)
+	}
+	return e.fileSystem.DiskUsage(path)
+}
+
+func (e expandFileSystem) Mknod(name, nodeType string, perm os.FileMode, major, minor uint32) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("mknod", name, err)
+	}
+	return e.fileSystem.Mknod(path, nodeType, perm, major, minor)
+}
+
+func (e expandFileSystem) Chflags(name string, flags uint32) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("chflags", name, err)
+	}
+	return e.fileSystem.Chflags(path, flags)
+}
+
+func (e expandFileSystem) GetXattr(name, attr string) ([]byte, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return nil, expandError("getxattr", name, err)
+	}
+	return e.fileSystem.GetXattr(path, attr)
+}
+
+func (e expandFileSystem) SetXattr(name, attr string, value []byte) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("setxattr", name, err)
+	}
+	return e.fileSystem.SetXattr(path, attr, value)
+}
+
+func (e expandFileSystem) RemoveXattr(name, attr string) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("removexattr", name, err)
+	}
+	return e.fileSystem.RemoveXattr(path, attr)
+}
+
+func (e expandFileSystem) GetACL(name string) (*fileACL, error) {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return nil, expandError("getacl", name, err)
+	}
+	return e.fileSystem.GetACL(path)
+}
+
+func (e expandFileSystem) SetACL(name string, acl *fileACL) error {
+	path, err := e.expandPath(name)
+	if err != nil {
+		return expandError("setacl", name, err)
+	}
+	return e.fileSystem.SetACL(path, acl)
+}
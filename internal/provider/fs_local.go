@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// localFileSystem operates on the machine running Terraform. Every path goes
+// through localPath, which takes care of long and UNC paths on Windows.
+type localFileSystem struct{}
+
+func (localFileSystem) Stat(name string) (os.FileInfo, error) { return os.Stat(localPath(name)) }
+
+func (localFileSystem) Lstat(name string) (os.FileInfo, error) { return os.Lstat(localPath(name)) }
+
+func (localFileSystem) ReadFile(name string) ([]byte, error) { return os.ReadFile(localPath(name)) }
+
+func (localFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(localPath(name))
+}
+
+func (localFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(localPath(name), data, perm)
+}
+
+func (localFileSystem) Open(name string) (io.ReadCloser, error) { return os.Open(localPath(name)) }
+
+func (localFileSystem) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(localPath(name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+}
+
+func (localFileSystem) Mkdir(name string, perm os.FileMode) error {
+	return os.Mkdir(localPath(name), perm)
+}
+
+func (localFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(localPath(path), perm)
+}
+
+func (localFileSystem) MkdirTemp(dir, pattern string) (string, error) {
+	return os.MkdirTemp(localPath(dir), pattern)
+}
+
+func (localFileSystem) Remove(name string) error { return os.Remove(localPath(name)) }
+
+func (localFileSystem) RemoveAll(path string) error { return os.RemoveAll(localPath(path)) }
+
+func (localFileSystem) Rename(oldpath, newpath string) error {
+	return os.Rename(localPath(oldpath), localPath(newpath))
+}
+
+func (localFileSystem) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(localPath(name), mode)
+}
+
+func (localFileSystem) Lchown(name string, uid, gid int) error {
+	return os.Lchown(localPath(name), uid, gid)
+}
+
+func (localFileSystem) Readlink(name string) (string, error) { return os.Readlink(localPath(name)) }
+
+func (localFileSystem) Symlink(oldname, newname string) error {
+	return os.Symlink(oldname, localPath(newname))
+}
+
+func (localFileSystem) Abs(name string) (string, error) { return filepath.Abs(name) }
+
+func (l localFileSystem) Resolve(name string) (string, error) {
+	return resolveSymlinks(l, filepath.Clean(name), true)
+}
+
+func (localFileSystem) Junction(oldname, newname string) error {
+	return createJunction(oldname, localPath(newname))
+}
+
+func (localFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(localPath(name), atime, mtime)
+}
+
+func (localFileSystem) Sync(name string) error {
+	f, err := os.Open(localPath(name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Windows can't flush directory handles, and NTFS journals renames anyway
+	if runtime.GOOS == "windows" {
+		if info, err := f.Stat(); err == nil && info.IsDir() {
+			return nil
+		}
+	}
+	return f.Sync()
+}
+
+func (localFileSystem) Lock(name string) (func() error, error) { return lockLocalFile(localPath(name)) }
+
+// Run uses sh, or cmd on Windows.
+func (localFileSystem) Run(ctx context.Context, command string, env ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	stopProcessGroup(cmd)
+	// Processes that keep the output open don't hold up a stopped command
+	// for longer than that
+	cmd.WaitDelay = 10 * time.Second
+
+	out, err := cmd.CombinedOutput()
+	if cerr := ctx.Err(); cerr != nil {
+		return "", commandError(command, cerr, string(out))
+	}
+	if err != nil {
+		return "", commandError(command, err, string(out))
+	}
+	return string(out), nil
+}
+
+func (l localFileSystem) Probe(ctx context.Context, command string) (string, error) {
+	return l.Run(ctx, command)
+}
+
+func (localFileSystem) Quote(arg string) string { return localQuote(arg) }
+
+func (localFileSystem) LookupUID(owner string) (int, error) { return lookupUID(owner) }
+
+func (localFileSystem) LookupGID(group string) (int, error) { return lookupGID(group) }
+
+func (localFileSystem) UserName(uid int) string { return userName(uid) }
+
+func (localFileSystem) GroupName(gid int) string { return groupName(gid) }
+
+func (localFileSystem) Access(name string) (*fileAccess, error) { return localAccess(localPath(name)) }
+
+func (localFileSystem) DiskUsage(path string) (*filesystemUsage, error) {
+	return diskUsage(localPath(path))
+}
+
+func (localFileSystem) Mounts() ([]mountInfo, error) { return listMounts() }
+
+func (localFileSystem) Mknod(path, nodeType string, perm os.FileMode, major, minor uint32) error {
+	return mknod(localPath(path), nodeType, perm, major, minor)
+}
+
+func (localFileSystem) Chflags(name string, flags uint32) error {
+	return chflags(localPath(name), flags)
+}
+
+func (localFileSystem) GetXattr(path, name string) ([]byte, error) {
+	return getXattr(localPath(path), name)
+}
+
+func (localFileSystem) SetXattr(path, name string, value []byte) error {
+	return setXattr(localPath(path), name, value)
+}
+
+func (localFileSystem) RemoveXattr(path, name string) error {
+	return removeXattr(localPath(path), name)
+}
+
+func (localFileSystem) GetACL(path string) (*fileACL, error) { return getACL(localPath(path)) }
+
+func (localFileSystem) SetACL(path string, acl *fileACL) error { return setACL(localPath(path), acl) }
+
+func (localFileSystem) LookupSID(account string) (string, error) { return lookupSID(account) }
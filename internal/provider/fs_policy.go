@@ -0,0 +1,301 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// policyFileSystem refuses paths that the path_policy of the provider
+// doesn't allow, before they reach fs.
+type policyFileSystem struct {
+	fileSystem
+	requireAbsolute bool
+	denyTraversal   bool
+	deny            *excludeMatcher
+}
+
+func (p policyFileSystem) bindContext(ctx context.Context) fileSystem {
+	p.fileSystem = bindContext(ctx, p.fileSystem)
+	return p
+}
+
+// check returns an error when the policy doesn't allow name. Denied
+// patterns are matched against the path as it is given and as it resolves,
+// following the last element only when follow is set, so that neither ".."
+// nor symlinks get around them. Like in .gitignore, a denied directory
+// denies everything below it.
+func (p policyFileSystem) check(name string, follow bool) error {
+	if p.requireAbsolute && !filepath.IsAbs(name) {
+		return &os.PathError{Op: "check", Path: name, Err: errors.New("path_policy requires absolute paths")}
+	}
+	if p.denyTraversal {
+		for _, elem := range strings.Split(filepath.ToSlash(name), "/") {
+			if elem == ".." {
+				return &os.PathError{Op: "check", Path: name, Err: errors.New("path_policy denies '..' in paths")}
+			}
+		}
+	}
+	if p.deny == nil {
+		return nil
+	}
+
+	paths := []string{filepath.Clean(name)}
+	if resolved, err := resolveSymlinks(p.fileSystem, name, follow); err == nil && resolved != paths[0] {
+		paths = append(paths, resolved)
+	}
+	for _, path := range paths {
+		for dir := path; ; dir = filepath.Dir(dir) {
+			if p.deny.match(strings.TrimPrefix(filepath.ToSlash(dir), "/"), true) {
+				return &os.PathError{Op: "check", Path: name, Err: errors.New("path_policy denies " + dir)}
+			}
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (p policyFileSystem) Stat(name string) (os.FileInfo, error) {
+	if err := p.check(name, true); err != nil {
+		return nil, err
+	}
+	return p.fileSystem.Stat(name)
+}
+
+func (p policyFileSystem) Lstat(name string) (os.FileInfo, error) {
+	if err := p.check(name, false); err != nil {
+		return nil, err
+	}
+	return p.fileSystem.Lstat(name)
+}
+
+func (p policyFileSystem) ReadFile(name string) ([]byte, error) {
+	if err := p.check(name, true); err != nil {
+		return nil, err
+	}
+	return p.fileSystem.ReadFile(name)
+}
+
+func (p policyFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if err := p.check(name, true); err != nil {
+		return err
+	}
+	return p.fileSystem.WriteFile(name, data, perm)
+}
+
+func (p policyFileSystem) Open(name string) (io.ReadCloser, error) {
+	if err := p.check(name, true); err != nil {
+		return nil, err
+	}
+	return p.fileSystem.Open(name)
+}
+
+func (p policyFileSystem) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	if err := p.check(name, true); err != nil {
+		return nil, err
+	}
+	return p.fileSystem.Create(name, perm)
+}
+
+func (p policyFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := p.check(name, true); err != nil {
+		return nil, err
+	}
+	return p.fileSystem.ReadDir(name)
+}
+
+func (p policyFileSystem) Mkdir(name string, perm os.FileMode) error {
+	if err := p.check(name, true); err != nil {
+		return err
+	}
+	return p.fileSystem.Mkdir(name, perm)
+}
+
+func (p policyFileSystem) MkdirAll(name string, perm os.FileMode) error {
+	if err := p.check(name, true); err != nil {
+		return err
+	}
+	return p.fileSystem.MkdirAll(name, perm)
+}
+
+// MkdirTemp leaves an empty dir, the system temporary directory, unchecked.
+func (p policyFileSystem) MkdirTemp(dir, pattern string) (string, error) {
+	if err := p.check(dir, true); dir != "" && err != nil {
+		return "", err
+	}
+	return p.fileSystem.MkdirTemp(dir, pattern)
+}
+
+func (p policyFileSystem) Remove(name string) error {
+	if err := p.check(name, false); err != nil {
+		return err
+	}
+	return p.fileSystem.Remove(name)
+}
+
+func (p policyFileSystem) RemoveAll(name string) error {
+	if err := p.check(name, false); err != nil {
+		return err
+	}
+	return p.fileSystem.RemoveAll(name)
+}
+
+func (p policyFileSystem) Rename(oldpath, newpath string) error {
+	if err := p.check(oldpath, false); err != nil {
+		return err
+	}
+	if err := p.check(newpath, false); err != nil {
+		return err
+	}
+	return p.fileSystem.Rename(oldpath, newpath)
+}
+
+func (p policyFileSystem) Chmod(name string, mode os.FileMode) error {
+	if err := p.check(name, true); err != nil {
+		return err
+	}
+	return p.fileSystem.Chmod(name, mode)
+}
+
+func (p policyFileSystem) Lchown(name string, uid, gid int) error {
+	if err := p.check(name, false); err != nil {
+		return err
+	}
+	return p.fileSystem.Lchown(name, uid, gid)
+}
+
+func (p policyFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	if err := p.check(name, true); err != nil {
+		return err
+	}
+	return p.fileSystem.Chtimes(name, atime, mtime)
+}
+
+func (p policyFileSystem) Readlink(name string) (string, error) {
+	if err := p.check(name, false); err != nil {
+		return "", err
+	}
+	return p.fileSystem.Readlink(name)
+}
+
+// Symlink only checks where the link is created. What it points to is
+// checked whenever something is accessed through it.
+func (p policyFileSystem) Symlink(oldname, newname string) error {
+	if err := p.check(newname, false); err != nil {
+		return err
+	}
+	return p.fileSystem.Symlink(oldname, newname)
+}
+
+// Junction checks the target as well as the link, as junctions aren't
+// followed when paths are resolved.
+func (p policyFileSystem) Junction(oldname, newname string) error {
+	if err := p.check(oldname, true); err != nil {
+		return err
+	}
+	if err := p.check(newname, false); err != nil {
+		return err
+	}
+	return p.fileSystem.Junction(oldname, newname)
+}
+
+func (p policyFileSystem) Sync(name string) error {
+	if err := p.check(name, true); err != nil {
+		return err
+	}
+	return p.fileSystem.Sync(name)
+}
+
+func (p policyFileSystem) Lock(name string) (func() error, error) {
+	if err := p.check(name, true); err != nil {
+		return nil, err
+	}
+	return p.fileSystem.Lock(name)
+}
+
+func (p policyFileSystem) Resolve(name string) (string, error) {
+	if err := p.check(name, true); err != nil {
+		return "", err
+	}
+	return p.fileSystem.Resolve(name)
+}
+
+// Run can't tell the paths in a command apart from the rest of it, so
+// commands are only ever built from paths given by Resolve.
+func (p policyFileSystem) Run(ctx context.Context, command string, env ...string) (string, error) {
+	return p.fileSystem.Run(ctx, command, env...)
+}
+
+func (p policyFileSystem) Probe(ctx context.Context, command string) (string, error) {
+	return p.fileSystem.Probe(ctx, command)
+}
+
+func (p policyFileSystem) Access(name string) (*fileAccess, error) {
+	if err := p.check(name, true); err != nil {
+		return nil, err
+	}
+	return p.fileSystem.Access(name)
+}
+
+func (p policyFileSystem) DiskUsage(name string) (*filesystemUsage, error) {
+	if err := p.check(name, true); err != nil {
+		return nil, err
+	}
+	return p.fileSystem.DiskUsage(name)
+}
+
+func (p policyFileSystem) Mknod(name, nodeType string, perm os.FileMode, major, minor uint32) error {
+	if err := p.check(name, false); err != nil {
+		return err
+	}
+	return p.fileSystem.Mknod(name, nodeType, perm, major, minor)
+}
+
+func (p policyFileSystem) Chflags(name string, flags uint32) error {
+	if err := p.check(name, true); err != nil {
+		return err
+	}
+	return p.fileSystem.Chflags(name, flags)
+}
+
+func (p policyFileSystem) GetXattr(name, attr string) ([]byte, error) {
+	if err := p.check(name, true); err != nil {
+		return nil, err
+	}
+	return p.fileSystem.GetXattr(name, attr)
+}
+
+func (p policyFileSystem) SetXattr(name, attr string, value []byte) error {
+	if err := p.check(name, true); err != nil {
+		return err
+	}
+	return p.fileSystem.SetXattr(name, attr, value)
+}
+
+func (p policyFileSystem) RemoveXattr(name, attr string) error {
+	if err := p.check(name, true); err != nil {
+		return err
+	}
+	return p.fileSystem.RemoveXattr(name, attr)
+}
+
+func (p policyFileSystem) GetACL(name string) (*fileACL, error) {
+	if err := p.check(name, true); err != nil {
+		return nil, err
+	}
+	return p.fileSystem.GetACL(name)
+}
+
+func (p policyFileSystem) SetACL(name string, acl *fileACL) error {
+	if err := p.check(name, true); err != nil {
+		return err
+	}
+	return p.fileSystem.SetACL(name, acl)
+}
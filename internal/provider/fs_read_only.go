@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// errReadOnly is returned for every modification made while the provider is
+// configured with read_only = true.
+var errReadOnly = errors.New("the provider is configured with read_only = true")
+
+// readOnlyFileSystem passes reads through to fs and refuses everything that
+// would change the target host, so that plans work but applies fail.
+type readOnlyFileSystem struct {
+	fileSystem
+}
+
+func (r readOnlyFileSystem) bindContext(ctx context.Context) fileSystem {
+	return readOnlyFileSystem{bindContext(ctx, r.fileSystem)}
+}
+
+func readOnlyError(op, path string) error {
+	return &os.PathError{Op: op, Path: path, Err: errReadOnly}
+}
+
+func (readOnlyFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return readOnlyError("write", name)
+}
+
+func (readOnlyFileSystem) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	return nil, readOnlyError("create", name)
+}
+
+func (readOnlyFileSystem) Mkdir(name string, perm os.FileMode) error {
+	return readOnlyError("mkdir", name)
+}
+
+func (readOnlyFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	return readOnlyError("mkdir", path)
+}
+
+func (readOnlyFileSystem) MkdirTemp(dir, pattern string) (string, error) {
+	return "", readOnlyError("mkdirtemp", dir)
+}
+
+func (readOnlyFileSystem) Remove(name string) error { return readOnlyError("remove", name) }
+
+func (readOnlyFileSystem) RemoveAll(path string) error { return readOnlyError("remove", path) }
+
+func (readOnlyFileSystem) Rename(oldpath, newpath string) error {
+	return readOnlyError("rename", oldpath)
+}
+
+func (readOnlyFileSystem) Chmod(name string, mode os.FileMode) error {
+	return readOnlyError("chmod", name)
+}
+
+func (readOnlyFileSystem) Lchown(name string, uid, gid int) error {
+	return readOnlyError("lchown", name)
+}
+
+func (readOnlyFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	return readOnlyError("chtimes", name)
+}
+
+func (readOnlyFileSystem) Symlink(oldname, newname string) error {
+	return readOnlyError("symlink", newname)
+}
+
+func (readOnlyFileSystem) Junction(oldname, newname string) error {
+	return readOnlyError("junction", newname)
+}
+
+func (readOnlyFileSystem) Mknod(path, nodeType string, perm os.FileMode, major, minor uint32) error {
+	return readOnlyError("mknod", path)
+}
+
+func (readOnlyFileSystem) Chflags(name string, flags uint32) error {
+	return readOnlyError("chflags", name)
+}
+
+func (readOnlyFileSystem) SetXattr(path, name string, value []byte) error {
+	return readOnlyError("setxattr", path)
+}
+
+func (readOnlyFileSystem) RemoveXattr(path, name string) error {
+	return readOnlyError("removexattr", path)
+}
+
+// Run is refused as there's no telling what a command changes.
+func (readOnlyFileSystem) Run(ctx context.Context, command string, env ...string) (string, error) {
+	return "", readOnlyError("run", command)
+}
+
+// Probe is allowed, as its commands only read.
+func (r readOnlyFileSystem) Probe(ctx context.Context, command string) (string, error) {
+	return r.fileSystem.Probe(ctx, command)
+}
+
+func (readOnlyFileSystem) SetACL(path string, acl *fileACL) error {
+	return readOnlyError("setacl", path)
+}
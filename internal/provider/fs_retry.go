@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+)
+
+// retryFileSystem retries reads from fs that fail with a transient error,
+// such as a busy file or a stale NFS handle, waiting twice as long after
+// each attempt. Changes aren't retried, as an attempt that failed may still
+// have made part of them. It stops waiting once ctx is done.
+type retryFileSystem struct {
+	fileSystem
+
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	ctx        context.Context
+}
+
+func (r *retryFileSystem) bindContext(ctx context.Context) fileSystem {
+	c := *r
+	c.fileSystem = bindContext(ctx, r.fileSystem)
+	c.ctx = ctx
+	return &c
+}
+
+// transientErrors are matched with errors.Is. The shell-based remote
+// targets report them as the same errors, see exitError.
+var transientErrors = append([]error{
+	syscall.EAGAIN,
+	syscall.EBUSY,
+	syscall.ESTALE,
+	syscall.ETXTBSY,
+}, platformTransientErrors...)
+
+func isTransient(err error) bool {
+	for _, target := range transientErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// retry runs fn until it succeeds, fails with a permanent error or runs out
+// of attempts.
+func retry[T any](r *retryFileSystem, fn func() (T, error)) (T, error) {
+	backoff := r.backoff
+	for attempt := 1; ; attempt++ {
+		v, err := fn()
+		if err == nil || attempt >= r.attempts || !isTransient(err) {
+			return v, err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-r.ctx.Done():
+			return v, err
+		}
+		backoff *= 2
+		if backoff > r.maxBackoff {
+			backoff = r.maxBackoff
+		}
+	}
+}
+
+func (r *retryFileSystem) Stat(name string) (os.FileInfo, error) {
+	return retry(r, func() (os.FileInfo, error) { return r.fileSystem.Stat(name) })
+}
+
+func (r *retryFileSystem) Lstat(name string) (os.FileInfo, error) {
+	return retry(r, func() (os.FileInfo, error) { return r.fileSystem.Lstat(name) })
+}
+
+func (r *retryFileSystem) ReadFile(name string) ([]byte, error) {
+	return retry(r, func() ([]byte, error) { return r.fileSystem.ReadFile(name) })
+}
+
+func (r *retryFileSystem) Open(name string) (io.ReadCloser, error) {
+	return retry(r, func() (io.ReadCloser, error) { return r.fileSystem.Open(name) })
+}
+
+func (r *retryFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return retry(r, func() ([]fs.DirEntry, error) { return r.fileSystem.ReadDir(name) })
+}
+
+func (r *retryFileSystem) Readlink(name string) (string, error) {
+	return retry(r, func() (string, error) { return r.fileSystem.Readlink(name) })
+}
+
+func (r *retryFileSystem) Access(name string) (*fileAccess, error) {
+	return retry(r, func() (*fileAccess, error) { return r.fileSystem.Access(name) })
+}
+
+func (r *retryFileSystem) DiskUsage(path string) (*filesystemUsage, error) {
+	return retry(r, func() (*filesystemUsage, error) { return r.fileSystem.DiskUsage(path) })
+}
+
+func (r *retryFileSystem) GetXattr(path, name string) ([]byte, error) {
+	return retry(r, func() ([]byte, error) { return r.fileSystem.GetXattr(path, name) })
+}
+
+func (r *retryFileSystem) GetACL(path string) (*fileACL, error) {
+	return retry(r, func() (*fileACL, error) { return r.fileSystem.GetACL(path) })
+}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package provider
+
+import "golang.org/x/sys/windows"
+
+// Files opened by another process without sharing, which usually only lasts
+// until that process is done with them
+var platformTransientErrors = []error{
+	windows.ERROR_SHARING_VIOLATION,
+	windows.ERROR_LOCK_VIOLATION,
+}
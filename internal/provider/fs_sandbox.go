@@ -0,0 +1,425 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// sandboxFileSystem roots relative paths at base and rejects any path that
+// resolves outside of it once ".." and symlinks are taken into account.
+type sandboxFileSystem struct {
+	fs   fileSystem
+	base string
+}
+
+func newSandboxFileSystem(fsys fileSystem, base string) (*sandboxFileSystem, error) {
+	resolved, err := resolveSymlinks(fsys, filepath.Clean(base), true)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving base_path %s: %s", base, err)
+	}
+	return &sandboxFileSystem{fs: fsys, base: resolved}, nil
+}
+
+func (s *sandboxFileSystem) bindContext(ctx context.Context) fileSystem {
+	c := *s
+	c.fs = bindContext(ctx, s.fs)
+	return &c
+}
+
+// resolve returns the sandboxed form of name. The last element is only
+// followed when it's a symlink and follow is set, so that operations on a
+// link itself (Lstat, Remove, ...) stay possible.
+func (s *sandboxFileSystem) resolve(name string, follow bool) (string, error) {
+	path := name
+	if !filepath.IsAbs(path) {
+		path = s.base + string(filepath.Separator) + path
+	}
+
+	// ".." is applied after symlinks, the same way the kernel does
+	resolved, err := resolveSymlinks(s.fs, path, follow)
+	if err != nil {
+		return "", err
+	}
+
+	if !withinDir(s.base, resolved) {
+		return "", &os.PathError{Op: "resolve", Path: name, Err: fmt.Errorf("resolves to %s, outside of base_path %s", resolved, s.base)}
+	}
+	return resolved, nil
+}
+
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolveSymlinks is filepath.EvalSymlinks for an arbitrary fileSystem,
+// except that elements which don't exist yet are kept as they are. Relative
+// paths stay relative, for fsys to resolve, as in "~/app" with expand_paths.
+func resolveSymlinks(fsys fileSystem, path string, followLast bool) (string, error) {
+	path = filepath.FromSlash(path)
+	volume := filepath.VolumeName(path)
+	rest := strings.Split(strings.TrimPrefix(path[len(volume):], string(filepath.Separator)), string(filepath.Separator))
+	resolved := volume + string(filepath.Separator)
+	if volume == "" && !strings.HasPrefix(path, string(filepath.Separator)) {
+		resolved = ""
+	}
+
+	for links := 0; len(rest) > 0; {
+		elem := rest[0]
+		rest = rest[1:]
+
+		if elem == "" || elem == "." {
+			continue
+		}
+		if elem == ".." {
+			if resolved == "" || resolved == "." || filepath.Base(resolved) == ".." {
+				resolved = filepath.Join(resolved, "..")
+				continue
+			}
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, elem)
+		if len(rest) == 0 && !followLast {
+			return next, nil
+		}
+
+		info, err := fsys.Lstat(next)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Nothing below a missing element can be a symlink yet
+				return filepath.Join(append([]string{next}, rest...)...), nil
+			}
+			return "", err
+		}
+
+		if info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		links++
+		if links > 255 {
+			return "", fmt.Errorf("too many levels of symbolic links in %s", path)
+		}
+
+		target, err := fsys.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+
+		// Restart from the link target, relative to the directory containing the link
+		if filepath.IsAbs(target) {
+			volume = filepath.VolumeName(target)
+			resolved = volume + string(filepath.Separator)
+			target = target[len(volume):]
+		}
+		rest = append(strings.Split(target, string(filepath.Separator)), rest...)
+	}
+
+	if resolved == "" {
+		return ".", nil
+	}
+	return resolved, nil
+}
+
+func (s *sandboxFileSystem) Stat(name string) (os.FileInfo, error) {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.fs.Stat(path)
+}
+
+func (s *sandboxFileSystem) Lstat(name string) (os.FileInfo, error) {
+	path, err := s.resolve(name, false)
+	if err != nil {
+		return nil, err
+	}
+	return s.fs.Lstat(path)
+}
+
+func (s *sandboxFileSystem) ReadFile(name string) ([]byte, error) {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.fs.ReadFile(path)
+}
+
+func (s *sandboxFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return s.fs.WriteFile(path, data, perm)
+}
+
+func (s *sandboxFileSystem) Open(name string) (io.ReadCloser, error) {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.fs.Open(path)
+}
+
+func (s *sandboxFileSystem) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.fs.Create(path, perm)
+}
+
+func (s *sandboxFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.fs.ReadDir(path)
+}
+
+func (s *sandboxFileSystem) Mkdir(name string, perm os.FileMode) error {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return s.fs.Mkdir(path, perm)
+}
+
+func (s *sandboxFileSystem) MkdirAll(name string, perm os.FileMode) error {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return s.fs.MkdirAll(path, perm)
+}
+
+// MkdirTemp creates temporary directories below base_path when no
+// directory is given, rather than in the system temporary directory.
+func (s *sandboxFileSystem) MkdirTemp(dir, pattern string) (string, error) {
+	path, err := s.resolve(dir, true)
+	if err != nil {
+		return "", err
+	}
+	return s.fs.MkdirTemp(path, pattern)
+}
+
+func (s *sandboxFileSystem) Remove(name string) error {
+	path, err := s.resolve(name, false)
+	if err != nil {
+		return err
+	}
+	return s.fs.Remove(path)
+}
+
+func (s *sandboxFileSystem) RemoveAll(name string) error {
+	path, err := s.resolve(name, false)
+	if err != nil {
+		return err
+	}
+	return s.fs.RemoveAll(path)
+}
+
+func (s *sandboxFileSystem) Rename(oldpath, newpath string) error {
+	from, err := s.resolve(oldpath, false)
+	if err != nil {
+		return err
+	}
+	to, err := s.resolve(newpath, false)
+	if err != nil {
+		return err
+	}
+	return s.fs.Rename(from, to)
+}
+
+func (s *sandboxFileSystem) Chmod(name string, mode os.FileMode) error {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return s.fs.Chmod(path, mode)
+}
+
+func (s *sandboxFileSystem) Lchown(name string, uid, gid int) error {
+	path, err := s.resolve(name, false)
+	if err != nil {
+		return err
+	}
+	return s.fs.Lchown(path, uid, gid)
+}
+
+func (s *sandboxFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return s.fs.Chtimes(path, atime, mtime)
+}
+
+func (s *sandboxFileSystem) Readlink(name string) (string, error) {
+	path, err := s.resolve(name, false)
+	if err != nil {
+		return "", err
+	}
+	return s.fs.Readlink(path)
+}
+
+// Symlink only checks where the link is created. Its target is checked
+// whenever something is accessed through it.
+func (s *sandboxFileSystem) Symlink(oldname, newname string) error {
+	path, err := s.resolve(newname, false)
+	if err != nil {
+		return err
+	}
+	return s.fs.Symlink(oldname, path)
+}
+
+// Abs roots relative names at base without resolving symlinks, so that the
+// name stays the same whatever the links in it point to.
+func (s *sandboxFileSystem) Abs(name string) (string, error) {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(s.base, name)
+	}
+	return s.fs.Abs(name)
+}
+
+// Junction checks the target as well as the link, as junctions aren't
+// followed when paths are resolved.
+func (s *sandboxFileSystem) Junction(oldname, newname string) error {
+	target, err := s.resolve(oldname, true)
+	if err != nil {
+		return err
+	}
+	path, err := s.resolve(newname, false)
+	if err != nil {
+		return err
+	}
+	return s.fs.Junction(target, path)
+}
+
+func (s *sandboxFileSystem) Sync(name string) error {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return s.fs.Sync(path)
+}
+
+func (s *sandboxFileSystem) Lock(name string) (func() error, error) {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.fs.Lock(path)
+}
+
+// Resolve confines name to base_path the way the other operations do, for
+// commands that can't be confined themselves.
+func (s *sandboxFileSystem) Resolve(name string) (string, error) {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return "", err
+	}
+	return s.fs.Resolve(path)
+}
+
+// Run can't be confined to base_path, so commands are only ever built from
+// paths given by Resolve.
+func (s *sandboxFileSystem) Run(ctx context.Context, command string, env ...string) (string, error) {
+	return s.fs.Run(ctx, command, env...)
+}
+
+func (s *sandboxFileSystem) Probe(ctx context.Context, command string) (string, error) {
+	return s.fs.Probe(ctx, command)
+}
+
+func (s *sandboxFileSystem) Quote(arg string) string { return s.fs.Quote(arg) }
+
+func (s *sandboxFileSystem) LookupUID(owner string) (int, error) { return s.fs.LookupUID(owner) }
+func (s *sandboxFileSystem) LookupGID(group string) (int, error) { return s.fs.LookupGID(group) }
+func (s *sandboxFileSystem) UserName(uid int) string             { return s.fs.UserName(uid) }
+func (s *sandboxFileSystem) GroupName(gid int) string            { return s.fs.GroupName(gid) }
+
+func (s *sandboxFileSystem) Access(name string) (*fileAccess, error) {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.fs.Access(path)
+}
+
+func (s *sandboxFileSystem) DiskUsage(name string) (*filesystemUsage, error) {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.fs.DiskUsage(path)
+}
+
+func (s *sandboxFileSystem) Mounts() ([]mountInfo, error) { return s.fs.Mounts() }
+
+func (s *sandboxFileSystem) Mknod(name, nodeType string, perm os.FileMode, major, minor uint32) error {
+	path, err := s.resolve(name, false)
+	if err != nil {
+		return err
+	}
+	return s.fs.Mknod(path, nodeType, perm, major, minor)
+}
+
+func (s *sandboxFileSystem) Chflags(name string, flags uint32) error {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return s.fs.Chflags(path, flags)
+}
+
+func (s *sandboxFileSystem) GetXattr(name, attr string) ([]byte, error) {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.fs.GetXattr(path, attr)
+}
+
+func (s *sandboxFileSystem) SetXattr(name, attr string, value []byte) error {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return s.fs.SetXattr(path, attr, value)
+}
+
+func (s *sandboxFileSystem) RemoveXattr(name, attr string) error {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return s.fs.RemoveXattr(path, attr)
+}
+
+func (s *sandboxFileSystem) GetACL(name string) (*fileACL, error) {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.fs.GetACL(path)
+}
+
+func (s *sandboxFileSystem) SetACL(name string, acl *fileACL) error {
+	path, err := s.resolve(name, true)
+	if err != nil {
+		return err
+	}
+	return s.fs.SetACL(path, acl)
+}
+
+func (s *sandboxFileSystem) LookupSID(account string) (string, error) { return s.fs.LookupSID(account) }
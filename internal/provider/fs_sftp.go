@@ -0,0 +1,537 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+type sshConfig struct {
+	host                 string
+	port                 int
+	user                 string
+	password             string
+	privateKey           string
+	privateKeyPath       string
+	privateKeyPassphrase string
+	agent                bool
+	hostKey              string
+	knownHostsFile       string
+	insecureIgnoreHost   bool
+	sudo                 bool
+	sftpServer           string
+	timeout              time.Duration
+}
+
+// sftpFileSystem operates on a remote host over SFTP. With sudo, the SFTP
+// server and the commands run through sudo as root.
+type sftpFileSystem struct {
+	conn   *ssh.Client
+	client *sftp.Client
+	sudo   bool
+
+	accounts accountFiles
+}
+
+func newSFTPFileSystem(c sshConfig) (*sftpFileSystem, error) {
+	auth, err := sshAuthMethods(c)
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeyCallback, err := sshHostKeyCallback(c)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := net.JoinHostPort(c.host, strconv.Itoa(c.port))
+	conn, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            c.user,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         c.timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %s", addr, err)
+	}
+
+	var client *sftp.Client
+	if c.sudo {
+		client, err = newSudoSFTPClient(conn, c.sftpServer)
+	} else {
+		client, err = sftp.NewClient(conn)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error starting SFTP session on %s: %s", addr, err)
+	}
+
+	return &sftpFileSystem{conn: conn, client: client, sudo: c.sudo}, nil
+}
+
+// sftpServers are where the distributions install sftp-server, for hosts
+// whose sshd serves SFTP in-process rather than with the binary.
+var sftpServers = []string{
+	"/usr/lib/openssh/sftp-server",
+	"/usr/libexec/openssh/sftp-server",
+	"/usr/lib/ssh/sftp-server",
+	"/usr/libexec/sftp-server",
+	"/usr/lib/sftp-server",
+}
+
+// findSFTPServerScript prints the sftp-server binary that the sftp subsystem
+// of sshd runs, or otherwise the first of its arguments on the host.
+const findSFTPServerScript = `server=$(awk 'tolower($1) == "subsystem" && $2 == "sftp" { print $3; exit }' /etc/ssh/sshd_config 2>/dev/null)
+for p in "$server" "$@"; do
+	if [ -n "$p" ] && [ -x "$p" ]; then echo "$p"; exit 0; fi
+done
+exit 1`
+
+// newSudoSFTPClient runs the SFTP server through sudo instead of as the
+// sshd subsystem, so that file operations run as root. Without server, the
+// binary is looked for on the host.
+func newSudoSFTPClient(conn *ssh.Client, server string) (*sftp.Client, error) {
+	if server == "" {
+		out, err := (&sftpFileSystem{conn: conn}).Run(context.Background(), shellCommand(append([]string{"sh", "-c", findSFTPServerScript, "sh"}, sftpServers...)...))
+		if err != nil {
+			return nil, fmt.Errorf("sftp-server not found on the host, set sftp_server: %s", err)
+		}
+		server = strings.TrimSpace(out)
+	}
+
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	// -n makes sudo fail instead of waiting for a password prompt
+	if err := session.Start(shellCommand("sudo", "-n", "--", server)); err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	client, err := sftp.NewClientPipe(stdout, stdin)
+	if err != nil {
+		session.Close()
+		return nil, commandError("sudo -n "+server, err, stderr.String())
+	}
+	return client, nil
+}
+
+func sshAuthMethods(c sshConfig) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+
+	key := []byte(c.privateKey)
+	if len(key) == 0 && c.privateKeyPath != "" {
+		var err error
+		key, err = os.ReadFile(c.privateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading private key %s: %s", c.privateKeyPath, err)
+		}
+	}
+
+	if len(key) > 0 {
+		var signer ssh.Signer
+		var err error
+		if c.privateKeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(c.privateKeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing private key: %s", err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); c.agent && sock != "" {
+		agentConn, err := net.Dial("unix", sock)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to SSH agent: %s", err)
+		}
+		methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers))
+	}
+
+	if c.password != "" {
+		methods = append(methods, ssh.Password(c.password))
+	}
+
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no SSH authentication method available: set private_key, private_key_path or password, or run an SSH agent")
+	}
+
+	return methods, nil
+}
+
+func sshHostKeyCallback(c sshConfig) (ssh.HostKeyCallback, error) {
+	if c.hostKey != "" {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(c.hostKey))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing host_key: %s", err)
+		}
+		return ssh.FixedHostKey(key), nil
+	}
+
+	if c.insecureIgnoreHost {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	path := c.knownHostsFile
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("error locating known_hosts: %s", err)
+		}
+		path = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading known hosts %s: %s", path, err)
+	}
+	return callback, nil
+}
+
+func (f *sftpFileSystem) Stat(name string) (os.FileInfo, error)  { return f.client.Stat(name) }
+func (f *sftpFileSystem) Lstat(name string) (os.FileInfo, error) { return f.client.Lstat(name) }
+
+func (f *sftpFileSystem) ReadFile(name string) ([]byte, error) {
+	r, err := f.client.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+func (f *sftpFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	w, err := f.Create(name, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (f *sftpFileSystem) Open(name string) (io.ReadCloser, error) { return f.client.Open(name) }
+
+// Create opens a file for writing, truncating it. Like os.OpenFile, perm
+// only applies when the file is newly created.
+func (f *sftpFileSystem) Create(name string, perm os.FileMode) (io.WriteCloser, error) {
+	_, statErr := f.client.Lstat(name)
+
+	w, err := f.client.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return nil, err
+	}
+
+	if os.IsNotExist(statErr) {
+		if err := w.Chmod(perm); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+func (f *sftpFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	infos, err := f.client.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (f *sftpFileSystem) Mkdir(name string, perm os.FileMode) error {
+	if err := f.client.Mkdir(name); err != nil {
+		return err
+	}
+	return f.client.Chmod(name, perm)
+}
+
+func (f *sftpFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	info, err := f.client.Stat(path)
+	if err == nil {
+		if info.IsDir() {
+			return nil
+		}
+		return &os.PathError{Op: "mkdir", Path: path, Err: fs.ErrExist}
+	}
+
+	if parent := filepath.Dir(path); parent != path {
+		if err := f.MkdirAll(parent, perm); err != nil {
+			return err
+		}
+	}
+
+	err = f.Mkdir(path, perm)
+	if err != nil {
+		// Another process may have created it in the meantime
+		if info, serr := f.client.Stat(path); serr == nil && info.IsDir() {
+			return nil
+		}
+	}
+	return err
+}
+
+func (f *sftpFileSystem) MkdirTemp(dir, pattern string) (string, error) {
+	if dir == "" {
+		dir = "/tmp"
+	}
+	return mkdirTemp(f, dir, pattern)
+}
+
+func (f *sftpFileSystem) Remove(name string) error { return f.client.Remove(name) }
+
+func (f *sftpFileSystem) RemoveAll(path string) error {
+	// Like os.RemoveAll, a missing path is not an error
+	if _, err := f.client.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+	return f.client.RemoveAll(path)
+}
+
+func (f *sftpFileSystem) Rename(oldpath, newpath string) error {
+	// Plain SFTP renames refuse to replace an existing file
+	if _, ok := f.client.HasExtension("posix-rename@openssh.com"); ok {
+		return f.client.PosixRename(oldpath, newpath)
+	}
+	return f.client.Rename(oldpath, newpath)
+}
+
+func (f *sftpFileSystem) Chmod(name string, mode os.FileMode) error {
+	return f.client.Chmod(name, mode)
+}
+
+func (f *sftpFileSystem) Lchown(name string, uid, gid int) error {
+	info, err := f.client.Lstat(name)
+	if err != nil {
+		return err
+	}
+
+	// SFTP has no lchown and chown would follow the link to its target
+	if info.Mode()&os.ModeSymlink != 0 {
+		return nil
+	}
+
+	// -1 keeps the current value, as with os.Lchown
+	if uid < 0 || gid < 0 {
+		currentUID, currentGID, _ := fileOwner(info)
+		if uid < 0 {
+			uid = currentUID
+		}
+		if gid < 0 {
+			gid = currentGID
+		}
+	}
+	return f.client.Chown(name, uid, gid)
+}
+
+func (f *sftpFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	return f.client.Chtimes(name, atime, mtime)
+}
+
+func (f *sftpFileSystem) Readlink(name string) (string, error) { return f.client.ReadLink(name) }
+func (f *sftpFileSystem) Symlink(oldname, newname string) error {
+	return f.client.Symlink(oldname, newname)
+}
+
+// Abs leaves relative names relative to the login directory, which the
+// remote host resolves them against.
+func (f *sftpFileSystem) Abs(name string) (string, error) { return filepath.Clean(name), nil }
+
+func (f *sftpFileSystem) Resolve(name string) (string, error) {
+	return resolveSymlinks(f, filepath.Clean(name), true)
+}
+
+func (f *sftpFileSystem) Junction(oldname, newname string) error {
+	return fmt.Errorf("junctions cannot be created over SFTP")
+}
+
+// Sync relies on the fsync@openssh.com extension and does nothing on
+// servers without it.
+func (f *sftpFileSystem) Sync(name string) error {
+	if _, ok := f.client.HasExtension("fsync@openssh.com"); !ok {
+		return nil
+	}
+
+	r, err := f.client.Open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return r.Sync()
+}
+
+// Run executes the command in a new session, with the login shell of the
+// user, or with sh as root through sudo.
+func (f *sftpFileSystem) Run(ctx context.Context, command string, env ...string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	session, err := f.conn.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	// sudo resets the environment, so env passes the variables on
+	line := command
+	if f.sudo {
+		line = shellCommand(append(append([]string{"sudo", "-n", "--", "env"}, env...), "sh", "-c", command)...)
+		env = nil
+	}
+
+	// Servers only take the variables their AcceptEnv allows, so the shell
+	// exports the others
+	var exports []string
+	for _, v := range env {
+		name, value, _ := strings.Cut(v, "=")
+		if session.Setenv(name, value) != nil {
+			exports = append(exports, name+"="+shellQuote(value))
+		}
+	}
+	if len(exports) > 0 {
+		line = "export " + strings.Join(exports, " ") + "; " + command
+	}
+
+	// Not every server passes signals on, but all of them hang up the
+	// command once its session is closed
+	stop := context.AfterFunc(ctx, func() {
+		session.Signal(ssh.SIGTERM)
+		session.Close()
+	})
+	defer stop()
+
+	out, err := session.CombinedOutput(line)
+	if cerr := ctx.Err(); cerr != nil {
+		return "", commandError(command, cerr, string(out))
+	}
+	if err != nil {
+		return "", commandError(command, err, string(out))
+	}
+	return string(out), nil
+}
+
+func (f *sftpFileSystem) Probe(ctx context.Context, command string) (string, error) {
+	return f.Run(ctx, command)
+}
+
+func (f *sftpFileSystem) Quote(arg string) string { return shellQuote(arg) }
+
+func (f *sftpFileSystem) LookupUID(owner string) (int, error) { return f.accounts.lookupUID(f, owner) }
+func (f *sftpFileSystem) LookupGID(group string) (int, error) { return f.accounts.lookupGID(f, group) }
+func (f *sftpFileSystem) UserName(uid int) string             { return f.accounts.userName(f, uid) }
+func (f *sftpFileSystem) GroupName(gid int) string            { return f.accounts.groupName(f, gid) }
+
+func (f *sftpFileSystem) Access(name string) (*fileAccess, error) {
+	// The script is only run for paths that exist, so a missing one is
+	// reported as such
+	if _, err := f.Stat(name); err != nil {
+		return nil, err
+	}
+	out, err := f.Run(context.Background(), shellCommand("sh", "-c", accessScript, "sh", name))
+	if err != nil {
+		return nil, err
+	}
+	return parseAccess(out)
+}
+
+func (f *sftpFileSystem) DiskUsage(path string) (*filesystemUsage, error) {
+	st, err := f.client.StatVFS(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &filesystemUsage{
+		totalBytes:     st.Blocks * st.Frsize,
+		freeBytes:      st.Bfree * st.Frsize,
+		availableBytes: st.Bavail * st.Frsize,
+		totalInodes:    st.Files,
+		freeInodes:     st.Ffree,
+		hasInodes:      true,
+	}, nil
+}
+
+func (f *sftpFileSystem) Mounts() ([]mountInfo, error) {
+	content, err := f.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return nil, fmt.Errorf("error reading /proc/self/mountinfo on remote host: %s", err)
+	}
+	return parseMountInfo(string(content))
+}
+
+func (f *sftpFileSystem) Lock(name string) (func() error, error) {
+	return nil, fmt.Errorf("files cannot be locked over SFTP")
+}
+
+func (f *sftpFileSystem) Mknod(path, nodeType string, perm os.FileMode, major, minor uint32) error {
+	return fmt.Errorf("device nodes cannot be created over SFTP")
+}
+
+func (f *sftpFileSystem) Chflags(name string, flags uint32) error {
+	return fmt.Errorf("file flags cannot be managed over SFTP")
+}
+
+func (f *sftpFileSystem) GetXattr(path, name string) ([]byte, error) {
+	return nil, fmt.Errorf("extended attributes cannot be managed over SFTP")
+}
+
+func (f *sftpFileSystem) SetXattr(path, name string, value []byte) error {
+	return fmt.Errorf("extended attributes cannot be managed over SFTP")
+}
+
+func (f *sftpFileSystem) RemoveXattr(path, name string) error {
+	return fmt.Errorf("extended attributes cannot be managed over SFTP")
+}
+
+func (f *sftpFileSystem) GetACL(path string) (*fileACL, error) {
+	return nil, fmt.Errorf("ACLs cannot be managed over SFTP")
+}
+
+func (f *sftpFileSystem) SetACL(path string, acl *fileACL) error {
+	return fmt.Errorf("ACLs cannot be managed over SFTP")
+}
+
+func (f *sftpFileSystem) LookupSID(account string) (string, error) {
+	return "", fmt.Errorf("ACLs cannot be managed over SFTP")
+}
@@ -0,0 +1,245 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// shellFileSystem implements the operations of a fileSystem that run as
+// commands on the target, which therefore needs a POSIX shell and the usual
+// coreutils or BusyBox tools. exec runs a command with TZ=UTC and LC_ALL=C
+// and returns its standard output and error and its exit code, stopping the
+// command once ctx is done. The operations other than Run and Probe run
+// their commands with ctx.
+type shellFileSystem struct {
+	exec func(ctx context.Context, args ...string) (string, string, int, error)
+	ctx  context.Context
+}
+
+// run executes a command on the target and returns its standard output. A
+// failing command is reported with exitError.
+func (s *shellFileSystem) run(op, path string, args ...string) (string, error) {
+	stdout, stderr, code, err := s.exec(s.ctx, args...)
+	if err != nil {
+		return "", err
+	}
+
+	if code != 0 {
+		return "", exitError(op, path, args[0], code, stderr)
+	}
+	return stdout, nil
+}
+
+// exitError reports a command that exited with code as a *os.PathError for
+// path, with missing files, existing files and denied permissions mapped to
+// the matching os errors, and transient errors to their errno.
+func exitError(op, path, command string, code int, stderr string) error {
+	msg := strings.TrimSpace(stderr)
+	var cause error = fmt.Errorf("%s exited with %d: %s", command, code, msg)
+	switch {
+	case strings.Contains(msg, "No such file or directory"), strings.Contains(msg, "Directory nonexistent"):
+		cause = os.ErrNotExist
+	case strings.Contains(msg, "Permission denied"):
+		cause = os.ErrPermission
+	case strings.Contains(msg, "File exists"):
+		cause = os.ErrExist
+	case strings.Contains(msg, "Resource temporarily unavailable"):
+		cause = syscall.EAGAIN
+	case strings.Contains(msg, "Device or resource busy"):
+		cause = syscall.EBUSY
+	case strings.Contains(msg, "Stale file handle"), strings.Contains(msg, "Stale NFS file handle"):
+		cause = syscall.ESTALE
+	case strings.Contains(msg, "Text file busy"):
+		cause = syscall.ETXTBSY
+	}
+	return &os.PathError{Op: op, Path: path, Err: cause}
+}
+
+// Run executes the command with sh on the target.
+func (s *shellFileSystem) Run(ctx context.Context, command string, env ...string) (string, error) {
+	args := []string{"sh", "-c", command}
+	if len(env) > 0 {
+		args = append(append([]string{"env"}, env...), args...)
+	}
+	stdout, stderr, code, err := s.exec(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	if code != 0 {
+		return "", commandError(command, fmt.Errorf("exit status %d", code), stdout+stderr)
+	}
+	return stdout + stderr, nil
+}
+
+func (s *shellFileSystem) Probe(ctx context.Context, command string) (string, error) {
+	return s.Run(ctx, command)
+}
+
+func (s *shellFileSystem) Quote(arg string) string { return shellQuote(arg) }
+
+func (s *shellFileSystem) stat(op, name string, args ...string) (os.FileInfo, error) {
+	out, err := s.run(op, name, append(append([]string{"stat"}, args...), "-c", dockerStatFormat, "--", name)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseDockerStat(strings.TrimSuffix(out, "\n"))
+}
+
+func (s *shellFileSystem) Stat(name string) (os.FileInfo, error) {
+	return s.stat("stat", name, "-L")
+}
+
+func (s *shellFileSystem) Lstat(name string) (os.FileInfo, error) {
+	return s.stat("lstat", name)
+}
+
+// readDirScript stats every entry of a directory with a single exec.
+const readDirScript = `cd -- "$1" || exit 1
+for f in * .*; do
+	case "$f" in .|..) continue ;; esac
+	if [ -e "$f" ] || [ -L "$f" ]; then stat -c "$2" -- "$f" || exit 1; fi
+done`
+
+func (s *shellFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	out, err := s.run("readdir", name, "sh", "-c", readDirScript, "sh", name, dockerStatFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []fs.DirEntry
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		info, err := parseDockerStat(line)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+
+	// Match os.ReadDir, which sorts by name
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (s *shellFileSystem) Mkdir(name string, perm os.FileMode) error {
+	_, err := s.run("mkdir", name, "mkdir", "-m", formatPermissions(perm), "--", name)
+	return err
+}
+
+func (s *shellFileSystem) MkdirAll(name string, perm os.FileMode) error {
+	_, err := s.run("mkdir", name, "mkdir", "-p", "-m", formatPermissions(perm), "--", name)
+	return err
+}
+
+func (s *shellFileSystem) Remove(name string) error {
+	_, err := s.run("remove", name, "sh", "-c", `if [ -d "$1" ] && [ ! -L "$1" ]; then rmdir -- "$1"; else rm -- "$1"; fi`, "sh", name)
+	return err
+}
+
+func (s *shellFileSystem) RemoveAll(name string) error {
+	_, err := s.run("remove", name, "rm", "-rf", "--", name)
+	return err
+}
+
+func (s *shellFileSystem) Rename(oldpath, newpath string) error {
+	_, err := s.run("rename", oldpath, "mv", "-f", "--", oldpath, newpath)
+	return err
+}
+
+func (s *shellFileSystem) Chmod(name string, mode os.FileMode) error {
+	_, err := s.run("chmod", name, "chmod", formatPermissions(mode), "--", name)
+	return err
+}
+
+func (s *shellFileSystem) Lchown(name string, uid, gid int) error {
+	var owner string
+	if uid != -1 {
+		owner = strconv.Itoa(uid)
+	}
+	if gid != -1 {
+		owner += ":" + strconv.Itoa(gid)
+	}
+	if owner == "" {
+		return nil
+	}
+
+	_, err := s.run("lchown", name, "chown", "-h", owner, "--", name)
+	return err
+}
+
+func (s *shellFileSystem) Chtimes(name string, atime, mtime time.Time) error {
+	const layout = "2006-01-02 15:04:05"
+	if _, err := s.run("chtimes", name, "touch", "-a", "-d", atime.UTC().Format(layout), "--", name); err != nil {
+		return err
+	}
+	_, err := s.run("chtimes", name, "touch", "-m", "-d", mtime.UTC().Format(layout), "--", name)
+	return err
+}
+
+func (s *shellFileSystem) Readlink(name string) (string, error) {
+	out, err := s.run("readlink", name, "readlink", "--", name)
+	return strings.TrimSuffix(out, "\n"), err
+}
+
+func (s *shellFileSystem) Symlink(oldname, newname string) error {
+	_, err := s.run("symlink", newname, "ln", "-s", "--", oldname, newname)
+	return err
+}
+
+// Abs leaves relative names relative to the working directory of the
+// commands, which the target resolves them against.
+func (s *shellFileSystem) Abs(name string) (string, error) { return path.Clean(name), nil }
+
+func (s *shellFileSystem) Sync(name string) error {
+	_, err := s.run("sync", name, "sync", "--", name)
+	return err
+}
+
+func (s *shellFileSystem) Access(name string) (*fileAccess, error) {
+	out, err := s.run("access", name, "sh", "-c", accessScript, "sh", name)
+	if err != nil {
+		return nil, err
+	}
+	return parseAccess(out)
+}
+
+func (s *shellFileSystem) DiskUsage(name string) (*filesystemUsage, error) {
+	out, err := s.run("statfs", name, "stat", "-f", "-c", "%S %b %f %a %c %d", "--", name)
+	if err != nil {
+		return nil, err
+	}
+
+	var blockSize, blocks, free, available, inodes, freeInodes uint64
+	_, err = fmt.Sscan(out, &blockSize, &blocks, &free, &available, &inodes, &freeInodes)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected stat output %q", out)
+	}
+
+	return &filesystemUsage{
+		totalBytes:     blocks * blockSize,
+		freeBytes:      free * blockSize,
+		availableBytes: available * blockSize,
+		totalInodes:    inodes,
+		freeInodes:     freeInodes,
+		hasInodes:      inodes > 0,
+	}, nil
+}
+
+func (s *shellFileSystem) Mknod(name, nodeType string, perm os.FileMode, major, minor uint32) error {
+	kind := "c"
+	if nodeType == "block" {
+		kind = "b"
+	}
+	_, err := s.run("mknod", name, "mknod", "-m", formatPermissions(perm), "--", name, kind, strconv.Itoa(int(major)), strconv.Itoa(int(minor)))
+	return err
+}
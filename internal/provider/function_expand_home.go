@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+type expandHomeFunction struct{}
+
+func newExpandHomeFunction() function.Function {
+	return expandHomeFunction{}
+}
+
+func (f expandHomeFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "expand_home"
+}
+
+func (f expandHomeFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:     "Expand a leading ~ in a path",
+		Description: "Replaces a leading \"~\" or \"~user\" with the home directory of the current or the named user on the machine running Terraform. Other paths are returned as they are",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "path",
+				Description: "The path to expand",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f expandHomeFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var path string
+	resp.Error = req.Arguments.Get(ctx, &path)
+	if resp.Error != nil {
+		return
+	}
+
+	expanded, err := expandHome(path)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+	resp.Error = resp.Result.Set(ctx, expanded)
+}
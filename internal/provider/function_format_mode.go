@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+type formatModeFunction struct{}
+
+func newFormatModeFunction() function.Function {
+	return formatModeFunction{}
+}
+
+func (f formatModeFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "format_mode"
+}
+
+func (f formatModeFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:     "Format permissions as octal",
+		Description: "Returns numeric permissions in the four-digit octal form that the provider reports them in, such as \"0644\"",
+		Parameters: []function.Parameter{
+			function.Int64Parameter{
+				Name:        "mode",
+				Description: "The permissions, between 0 and 4095 (07777)",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f formatModeFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var mode int64
+	resp.Error = req.Arguments.Get(ctx, &mode)
+	if resp.Error != nil {
+		return
+	}
+
+	if mode < 0 || mode > 07777 {
+		resp.Error = function.NewArgumentFuncError(0, fmt.Sprintf("mode %d is not between 0 and 4095 (07777)", mode))
+		return
+	}
+	perm, err := parsePermissions(strconv.FormatInt(mode, 8))
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+	resp.Error = resp.Result.Set(ctx, formatPermissions(perm))
+}
@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+type joinPathFunction struct{}
+
+func newJoinPathFunction() function.Function {
+	return joinPathFunction{}
+}
+
+func (f joinPathFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "join_path"
+}
+
+func (f joinPathFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:     "Join path elements",
+		Description: "Joins path elements with the separator of the machine running Terraform and cleans the result, the way the provider interprets paths",
+		VariadicParameter: function.StringParameter{
+			Name:        "elements",
+			Description: "The path elements to join",
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f joinPathFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var elements []string
+	resp.Error = req.Arguments.Get(ctx, &elements)
+	if resp.Error != nil {
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, filepath.Join(elements...))
+}
@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+type parseModeFunction struct{}
+
+func newParseModeFunction() function.Function {
+	return parseModeFunction{}
+}
+
+func (f parseModeFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "parse_mode"
+}
+
+func (f parseModeFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:     "Parse octal permissions",
+		Description: "Returns the numeric value of octal permissions in any form that permissions attributes accept, such as \"644\", \"0644\", \"0o644\" or \"2775\"",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "mode",
+				Description: "The permissions to parse",
+			},
+		},
+		Return: function.Int64Return{},
+	}
+}
+
+func (f parseModeFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var mode string
+	resp.Error = req.Arguments.Get(ctx, &mode)
+	if resp.Error != nil {
+		return
+	}
+
+	perm, err := parsePermissions(mode)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+	resp.Error = resp.Result.Set(ctx, int64(unixPermissions(perm)))
+}
@@ -0,0 +1,41 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+type sanitizeFilenameFunction struct{}
+
+func newSanitizeFilenameFunction() function.Function {
+	return sanitizeFilenameFunction{}
+}
+
+func (f sanitizeFilenameFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "sanitize_filename"
+}
+
+func (f sanitizeFilenameFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:     "Make a string safe to use as a file name",
+		Description: "Replaces path separators, characters that Windows doesn't allow and control characters with underscores, removes trailing dots and spaces, prefixes reserved device names such as CON with an underscore and truncates the result to 255 bytes, so that it is a valid file name on Linux, macOS and Windows",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "name",
+				Description: "The string to turn into a file name",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f sanitizeFilenameFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var name string
+	resp.Error = req.Arguments.Get(ctx, &name)
+	if resp.Error != nil {
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, sanitizeFilename(name))
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package provider
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func createJunction(target, link string) error {
+	return fmt.Errorf("junctions are not supported on %s", runtime.GOOS)
+}
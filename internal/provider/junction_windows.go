@@ -0,0 +1,65 @@
+//go:build windows
+
+package provider
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+)
+
+// createJunction creates an NTFS junction point at link, an empty directory
+// with a mount point reparse point. Unlike symlinks, junctions need no
+// privilege, but their target must be a local, absolute path.
+func createJunction(target, link string) error {
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+	target = strings.TrimPrefix(target, `\\?\`)
+
+	if err := os.Mkdir(link, 0777); err != nil {
+		return err
+	}
+	if err := setMountPoint(link, target); err != nil {
+		os.Remove(link)
+		return &os.LinkError{Op: "junction", Old: target, New: link, Err: err}
+	}
+	return nil
+}
+
+func setMountPoint(link, target string) error {
+	name, err := windows.UTF16PtrFromString(link)
+	if err != nil {
+		return err
+	}
+	h, err := windows.CreateFile(name, windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, windows.FILE_FLAG_OPEN_REPARSE_POINT|windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	// The reparse data holds the NT path of the target followed by the path
+	// shown to users, both NUL-terminated
+	substitute := utf16.Encode([]rune(`\??\` + target))
+	display := utf16.Encode([]rune(target))
+	names := append(append(append(substitute, 0), display...), 0)
+
+	buf := make([]byte, 16+2*len(names))
+	binary.LittleEndian.PutUint32(buf[0:], windows.IO_REPARSE_TAG_MOUNT_POINT)
+	binary.LittleEndian.PutUint16(buf[4:], uint16(len(buf)-8))
+	binary.LittleEndian.PutUint16(buf[8:], 0)
+	binary.LittleEndian.PutUint16(buf[10:], uint16(2*len(substitute)))
+	binary.LittleEndian.PutUint16(buf[12:], uint16(2*(len(substitute)+1)))
+	binary.LittleEndian.PutUint16(buf[14:], uint16(2*len(display)))
+	for i, c := range names {
+		binary.LittleEndian.PutUint16(buf[16+2*i:], c)
+	}
+
+	var returned uint32
+	return windows.DeviceIoControl(h, windows.FSCTL_SET_REPARSE_POINT, &buf[0], uint32(len(buf)), nil, 0, &returned, nil)
+}
@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"runtime"
+	"strings"
+)
+
+// lineEndings are the values of the line_endings attribute.
+var lineEndings = []string{"lf", "crlf", "platform", "none"}
+
+// convert returns content with the configured line endings. "platform" is
+// that of the machine running Terraform, the same as for paths.
+func (c textCodec) convert(content string) string {
+	endings := c.lineEndings
+	if endings == "platform" {
+		endings = "lf"
+		if runtime.GOOS == "windows" {
+			endings = "crlf"
+		}
+	}
+
+	switch endings {
+	case "lf":
+		return strings.ReplaceAll(content, "\r\n", "\n")
+	case "crlf":
+		return strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\n", "\r\n")
+	default:
+		return content
+	}
+}
+
+// normalize returns content in the form it's compared and hashed in, with
+// LF line endings unless they are left alone, so that a checkout with CRLF
+// line endings doesn't show as a change.
+func (c textCodec) normalize(content string) string {
+	if c.lineEndings == "" || c.lineEndings == "none" {
+		return content
+	}
+	return strings.ReplaceAll(content, "\r\n", "\n")
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// errLocked is returned by fileSystem.Lock while another process holds the
+// lock.
+var errLocked = errors.New("file is locked by another process")
+
+// lockPollInterval is how often a held lock is tried again.
+const lockPollInterval = 100 * time.Millisecond
+
+func lockSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "Hold an exclusive advisory lock on the file (flock on Unix, LockFileEx on Windows) while reading and rewriting it, so that other programs that lock it don't edit it at the same time",
+	}
+}
+
+func lockTimeoutSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Default:     "30s",
+		Description: "How long to wait for another program to release the lock (e.g., '30s')",
+	}
+}
+
+// lockFromResourceData takes the lock on path when lock is set, waiting up
+// to lock_timeout for another process to release it. A file that doesn't
+// exist yet can't be locked, and isn't. The returned function may be
+// called more than once.
+func lockFromResourceData(fsys fileSystem, d *schema.ResourceData, path string) (func() error, error) {
+	unlock := func() error { return nil }
+	if !d.Get("lock").(bool) {
+		return unlock, nil
+	}
+
+	timeout, err := time.ParseDuration(d.Get("lock_timeout").(string))
+	if err != nil {
+		return nil, fmt.Errorf("invalid lock_timeout %q: %s", d.Get("lock_timeout"), err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, err := fsys.Lstat(path); os.IsNotExist(err) {
+			return unlock, nil
+		}
+
+		release, err := fsys.Lock(path)
+		if err == nil {
+			var once sync.Once
+			return func() error {
+				var err error
+				once.Do(func() { err = release() })
+				return err
+			}, nil
+		}
+		if !errors.Is(err, errLocked) {
+			return nil, fmt.Errorf("error locking %s: %s", path, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for the lock on %s", timeout, path)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !windows
+
+package provider
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func lockLocalFile(path string) (func() error, error) {
+	return nil, fmt.Errorf("file locking is not supported on %s", runtime.GOOS)
+}
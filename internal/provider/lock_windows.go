@@ -0,0 +1,41 @@
+//go:build windows
+
+package provider
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockLocalFile takes a LockFileEx lock. Windows locks are mandatory for
+// the bytes they cover, so the lock is on a range far past the end of the
+// file, where it only excludes other lockers and never reads and writes.
+// The file is opened with FILE_SHARE_DELETE so that an atomic write can
+// still rename over it.
+func lockLocalFile(path string) (func() error, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.CreateFile(name, windows.GENERIC_READ,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, windows.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	overlapped := &windows.Overlapped{Offset: 0xfffffffe, OffsetHigh: 0x7fffffff}
+	err = windows.LockFileEx(h, windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, overlapped)
+	if err != nil {
+		windows.CloseHandle(h)
+		if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+			return nil, errLocked
+		}
+		return nil, &os.PathError{Op: "LockFileEx", Path: path, Err: err}
+	}
+
+	// Closing the handle releases the lock
+	return func() error { return windows.CloseHandle(h) }, nil
+}
@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// modeSpec is a chmod(1) mode: either octal permissions, or symbolic
+// clauses such as "u=rwX,go=rX" applied relative to the current mode.
+type modeSpec struct {
+	octal   os.FileMode
+	clauses []modeClause
+}
+
+type modeClause struct {
+	who  os.FileMode
+	op   byte
+	perm os.FileMode
+
+	// x is the "X" permission: execute, but only for directories and for
+	// files that are already executable by someone
+	x bool
+}
+
+var modeWho = map[byte]os.FileMode{'u': 0700, 'g': 0070, 'o': 0007, 'a': 0777}
+
+var modePerm = map[byte]os.FileMode{'r': 0444, 'w': 0222, 'x': 0111}
+
+func parseModeSpec(s string) (*modeSpec, error) {
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		mode, err := parsePermissions(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mode: %s", s)
+		}
+		return &modeSpec{octal: mode}, nil
+	}
+
+	spec := &modeSpec{}
+	for _, clause := range strings.Split(s, ",") {
+		i := 0
+		var who os.FileMode
+		for ; i < len(clause) && modeWho[clause[i]] != 0; i++ {
+			who |= modeWho[clause[i]]
+		}
+		if who == 0 {
+			who = 0777
+		}
+
+		if i == len(clause) {
+			return nil, fmt.Errorf("invalid mode: %s", s)
+		}
+		for i < len(clause) {
+			c := modeClause{who: who, op: clause[i]}
+			if c.op != '+' && c.op != '-' && c.op != '=' {
+				return nil, fmt.Errorf("invalid mode: %s", s)
+			}
+			for i++; i < len(clause) && strings.IndexByte("+-=", clause[i]) < 0; i++ {
+				switch {
+				case clause[i] == 'X':
+					c.x = true
+				case modePerm[clause[i]] != 0:
+					c.perm |= modePerm[clause[i]]
+				default:
+					return nil, fmt.Errorf("invalid mode: %s", s)
+				}
+			}
+			spec.clauses = append(spec.clauses, c)
+		}
+	}
+
+	return spec, nil
+}
+
+func validateModeSpec(v interface{}, k string) ([]string, []error) {
+	if _, err := parseModeSpec(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s: %s", k, err)}
+	}
+	return nil, nil
+}
+
+// apply returns the permissions that chmod with this mode gives a file
+// that currently has mode.
+func (s *modeSpec) apply(mode os.FileMode, isDir bool) os.FileMode {
+	if s.clauses == nil {
+		return s.octal
+	}
+
+	perm := permissionBits(mode)
+	for _, c := range s.clauses {
+		bits := c.perm
+		if c.x && (isDir || perm&0111 != 0) {
+			bits |= 0111
+		}
+		bits &= c.who
+
+		switch c.op {
+		case '+':
+			perm |= bits
+		case '-':
+			perm &^= bits
+		case '=':
+			perm = perm&^c.who | bits
+		}
+	}
+	return perm
+}
+
+// chmodInPool changes the permissions of a file on pool. Directories are
+// changed right away, because the walk may need them to be readable before
+// it can list what is below them.
+func chmodInPool(fsys fileSystem, pool *workerPool, path string, isDir bool, mode os.FileMode) error {
+	if isDir {
+		return fsys.Chmod(path, mode)
+	}
+	return pool.Go(func() error { return fsys.Chmod(path, mode) })
+}
+
+// walkModes calls fn for every file and directory below root that excludes
+// doesn't match with its current permissions and those that files or dirs
+// give it. Symlinks are skipped, like chmod -R does, and a nil spec leaves
+// that kind of entry alone.
+func walkModes(fsys fileSystem, root string, files, dirs *modeSpec, excludes *excludeMatcher, fn func(path string, isDir bool, current, wanted os.FileMode) error) error {
+	return walkDirExcluding(fsys, root, excludes, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root || entry.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		spec := files
+		if entry.IsDir() {
+			spec = dirs
+		}
+		if spec == nil {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return fn(path, entry.IsDir(), permissionBits(info.Mode()), spec.apply(info.Mode(), entry.IsDir()))
+	})
+}
@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"os"
+	"testing"
+)
+
+func TestModeSpecApply(t *testing.T) {
+	tests := []struct {
+		spec  string
+		mode  os.FileMode
+		isDir bool
+		want  os.FileMode
+	}{
+		{spec: "0640", mode: 0777, want: 0640},
+		{spec: "4755", mode: 0644, want: 0755 | os.ModeSetuid},
+		{spec: "u+x", mode: 0644, want: 0744},
+		{spec: "go-w", mode: 0666, want: 0644},
+		{spec: "a=r", mode: 0755, want: 0444},
+		{spec: "=rw", mode: 0700, want: 0666},
+		{spec: "+x", mode: 0600, want: 0711},
+		{spec: "u=rwx,go=rx", mode: 0600, want: 0755},
+		{spec: "u=rw,u+x", mode: 0000, want: 0700},
+		{spec: "u-x+w", mode: 0500, want: 0600},
+		{spec: "o=", mode: 0777, want: 0770},
+		{spec: "a+X", mode: 0600, want: 0600},
+		{spec: "a+X", mode: 0700, want: 0711},
+		{spec: "a+X", mode: 0600, isDir: true, want: 0711},
+		{spec: "u=rwX,go=rX", mode: 0644, want: 0644},
+		{spec: "u=rwX,go=rX", mode: 0600, isDir: true, want: 0755},
+		{spec: "g-x", mode: 0775 | os.ModeSetgid, want: 0765 | os.ModeSetgid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			spec, err := parseModeSpec(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := spec.apply(tt.mode, tt.isDir); got != tt.want {
+				t.Errorf("apply(%o, %t) = %o, want %o", tt.mode, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseModeSpecInvalid(t *testing.T) {
+	for _, spec := range []string{"", "u", "0999", "12345", "u+z", "u*x", "u+x,", "k+x", "go=u"} {
+		if _, err := parseModeSpec(spec); err == nil {
+			t.Errorf("parseModeSpec(%q) succeeded", spec)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseMountInfo parses the content of /proc/self/mountinfo. It is used for
+// local Linux hosts as well as remote ones, whatever the local platform.
+func parseMountInfo(content string) ([]mountInfo, error) {
+	var mounts []mountInfo
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		m, err := parseMountInfoLine(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, m)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return mounts, nil
+}
+
+// parseMountInfoLine parses one line of /proc/self/mountinfo, e.g.
+//
+//	36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+func parseMountInfoLine(line string) (mountInfo, error) {
+	fields := strings.Fields(line)
+
+	// The optional fields are terminated by a single "-"
+	sep := -1
+	for i := 6; i < len(fields); i++ {
+		if fields[i] == "-" {
+			sep = i
+			break
+		}
+	}
+	if len(fields) < 6 || sep < 0 || len(fields) < sep+4 {
+		return mountInfo{}, fmt.Errorf("malformed mountinfo line %q", line)
+	}
+
+	return mountInfo{
+		device:       unescapeMountField(fields[sep+2]),
+		mountpoint:   unescapeMountField(fields[4]),
+		root:         unescapeMountField(fields[3]),
+		fstype:       fields[sep+1],
+		options:      strings.Split(fields[5], ","),
+		superOptions: strings.Split(fields[sep+3], ","),
+	}, nil
+}
+
+// unescapeMountField decodes the octal escapes (e.g. "\040" for a space)
+// the kernel uses for whitespace and backslashes.
+func unescapeMountField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,55 @@
+//go:build darwin
+
+package provider
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+var mountFlagOptions = []struct {
+	flag   uint32
+	option string
+}{
+	{unix.MNT_SYNCHRONOUS, "sync"},
+	{unix.MNT_NOEXEC, "noexec"},
+	{unix.MNT_NOSUID, "nosuid"},
+	{unix.MNT_NODEV, "nodev"},
+	{unix.MNT_LOCAL, "local"},
+	{unix.MNT_NOATIME, "noatime"},
+}
+
+func listMounts() ([]mountInfo, error) {
+	n, err := unix.Getfsstat(nil, unix.MNT_NOWAIT)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]unix.Statfs_t, n)
+	n, err = unix.Getfsstat(buf, unix.MNT_NOWAIT)
+	if err != nil {
+		return nil, err
+	}
+
+	mounts := make([]mountInfo, 0, n)
+	for _, st := range buf[:n] {
+		options := []string{"rw"}
+		if st.Flags&unix.MNT_RDONLY != 0 {
+			options[0] = "ro"
+		}
+		for _, o := range mountFlagOptions {
+			if st.Flags&o.flag != 0 {
+				options = append(options, o.option)
+			}
+		}
+
+		mounts = append(mounts, mountInfo{
+			device:     unix.ByteSliceToString(st.Mntfromname[:]),
+			mountpoint: unix.ByteSliceToString(st.Mntonname[:]),
+			root:       "/",
+			fstype:     unix.ByteSliceToString(st.Fstypename[:]),
+			options:    options,
+		})
+	}
+
+	return mounts, nil
+}
@@ -0,0 +1,14 @@
+//go:build linux
+
+package provider
+
+import "os"
+
+func listMounts() ([]mountInfo, error) {
+	content, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMountInfo(string(content))
+}
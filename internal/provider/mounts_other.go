@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !windows
+
+package provider
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func listMounts() ([]mountInfo, error) {
+	return nil, fmt.Errorf("listing mounts is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,59 @@
+//go:build windows
+
+package provider
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+func listMounts() ([]mountInfo, error) {
+	buf := make([]uint16, 254)
+	n, err := windows.GetLogicalDriveStrings(uint32(len(buf)), &buf[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// The buffer holds NUL-separated root paths such as "C:\"
+	var mounts []mountInfo
+	for _, root := range strings.Split(windows.UTF16ToString(buf[:n]), "\x00") {
+		if root == "" {
+			continue
+		}
+		mounts = append(mounts, windowsVolume(root))
+	}
+
+	return mounts, nil
+}
+
+func windowsVolume(root string) mountInfo {
+	m := mountInfo{
+		device:     root,
+		mountpoint: root,
+		root:       `\`,
+	}
+
+	p, err := windows.UTF16PtrFromString(root)
+	if err != nil {
+		return m
+	}
+
+	volume := make([]uint16, windows.MAX_PATH)
+	if err := windows.GetVolumeNameForVolumeMountPoint(p, &volume[0], uint32(len(volume))); err == nil {
+		m.device = windows.UTF16ToString(volume)
+	}
+
+	// Drives without media (e.g. an empty card reader) have no filesystem
+	var flags uint32
+	fstype := make([]uint16, windows.MAX_PATH)
+	if err := windows.GetVolumeInformation(p, nil, 0, nil, nil, &flags, &fstype[0], uint32(len(fstype))); err == nil {
+		m.fstype = windows.UTF16ToString(fstype)
+		m.options = []string{"rw"}
+		if flags&windows.FILE_READ_ONLY_VOLUME != 0 {
+			m.options[0] = "ro"
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// onChangeKeys are the attributes of filesystem_file that changing alone
+// doesn't run on_change_command for.
+var onChangeKeys = []string{"on_change_command", "warn_on_modification"}
+
+// runOnChangeCommand runs the on_change_command of a file that was created
+// or updated, with the path and the checksums before and after the change in
+// its environment.
+func runOnChangeCommand(ctx context.Context, fsys fileSystem, d *schema.ResourceData, path, oldSHA256 string) diag.Diagnostics {
+	command := d.Get("on_change_command").(string)
+	if command == "" {
+		return nil
+	}
+
+	target, err := fsys.Resolve(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("on_change_command failed: %s", err))
+	}
+	_, err = fsys.Run(ctx, command,
+		"FILESYSTEM_PATH="+target,
+		"FILESYSTEM_OLD_SHA256="+oldSHA256,
+		"FILESYSTEM_NEW_SHA256="+d.Get("sha256").(string),
+	)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("on_change_command failed: %s", err))
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// lookupUID resolves a user name or numeric ID to a numeric ID.
+func lookupUID(owner string) (int, error) {
+	if id, err := strconv.Atoi(owner); err == nil {
+		return id, nil
+	}
+
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return 0, fmt.Errorf("error looking up user %s: %s", owner, err)
+	}
+
+	id, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return 0, fmt.Errorf("user %s has no numeric ID", owner)
+	}
+	return id, nil
+}
+
+// lookupGID resolves a group name or numeric ID to a numeric ID.
+func lookupGID(group string) (int, error) {
+	if id, err := strconv.Atoi(group); err == nil {
+		return id, nil
+	}
+
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, fmt.Errorf("error looking up group %s: %s", group, err)
+	}
+
+	id, err := strconv.Atoi(g.Gid)
+	if err != nil {
+		return 0, fmt.Errorf("group %s has no numeric ID", group)
+	}
+	return id, nil
+}
+
+// chownFromResourceData applies the optional "owner" and "group" attributes,
+// leaving whichever one is unset untouched.
+func chownFromResourceData(fsys fileSystem, d *schema.ResourceData, path string) error {
+	uid, gid := -1, -1
+
+	if owner := d.Get("owner").(string); owner != "" {
+		id, err := fsys.LookupUID(owner)
+		if err != nil {
+			return err
+		}
+		uid = id
+	}
+
+	if group := d.Get("group").(string); group != "" {
+		id, err := fsys.LookupGID(group)
+		if err != nil {
+			return err
+		}
+		gid = id
+	}
+
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	return fsys.Lchown(path, uid, gid)
+}
+
+// readOwnershipIntoResourceData reports drift of the configured "owner" and
+// "group" attributes. Names are kept as configured while they still resolve
+// to the IDs on disk; otherwise the numeric ID found on disk is recorded.
+func readOwnershipIntoResourceData(fsys fileSystem, d *schema.ResourceData, fileInfo os.FileInfo) diag.Diagnostics {
+	uid, gid, ok := fileOwner(fileInfo)
+	if !ok {
+		return nil
+	}
+
+	if owner := d.Get("owner").(string); owner != "" {
+		if id, err := fsys.LookupUID(owner); err != nil || id != uid {
+			if err := d.Set("owner", strconv.Itoa(uid)); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+	}
+
+	if group := d.Get("group").(string); group != "" {
+		if id, err := fsys.LookupGID(group); err != nil || id != gid {
+			if err := d.Set("group", strconv.Itoa(gid)); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// userName returns the name of a numeric user ID, or the ID itself when it
+// cannot be resolved.
+func userName(uid int) string {
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return strconv.Itoa(uid)
+	}
+	return u.Username
+}
+
+// groupName returns the name of a numeric group ID, or the ID itself when it
+// cannot be resolved.
+func groupName(gid int) string {
+	g, err := user.LookupGroupId(strconv.Itoa(gid))
+	if err != nil {
+		return strconv.Itoa(gid)
+	}
+	return g.Name
+}
@@ -0,0 +1,71 @@
+package provider
+
+import "sync"
+
+// defaultParallelism is how many files recursive operations work on at
+// once when the provider doesn't set parallelism.
+const defaultParallelism = 8
+
+// workerPool runs the per-file work of a directory walk on a bounded number
+// of goroutines, while the walk itself stays sequential. It stops taking new
+// tasks once one has failed.
+type workerPool struct {
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+// newWorkerPool returns a pool running at most n tasks at once. With n of 1
+// or less, tasks run inline in the order they are handed out.
+func newWorkerPool(n int) *workerPool {
+	if n < 1 {
+		n = 1
+	}
+	return &workerPool{slots: make(chan struct{}, n)}
+}
+
+// Go runs task once a worker is free. It returns the error of the first task
+// that failed so far, so that the walk handing out tasks can stop early.
+func (p *workerPool) Go(task func() error) error {
+	if err := p.firstErr(); err != nil {
+		return err
+	}
+
+	if cap(p.slots) == 1 {
+		p.setErr(task())
+		return p.firstErr()
+	}
+
+	p.slots <- struct{}{}
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.slots
+			p.wg.Done()
+		}()
+		p.setErr(task())
+	}()
+	return nil
+}
+
+// Wait waits for every task handed out and returns the first error.
+func (p *workerPool) Wait() error {
+	p.wg.Wait()
+	return p.firstErr()
+}
+
+func (p *workerPool) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+func (p *workerPool) firstErr() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func partSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:          schema.TypeList,
+		Optional:      true,
+		ConflictsWith: []string{"content"},
+		Description:   "Fragments the file is assembled from, joined in order in place of content. Drift is detected with the checksum of the joined fragments",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"content": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "The content of the fragment",
+				},
+				"source": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "A file on the target whose content is the fragment, read on every plan and apply",
+				},
+				"order": {
+					Type:        schema.TypeInt,
+					Optional:    true,
+					Default:     0,
+					Description: "Fragments are joined in ascending order. Fragments with the same order keep the order they are declared in",
+				},
+			},
+		},
+	}
+}
+
+// plannedContent is the content a plan writes to a file, either content
+// itself or its parts joined.
+type plannedContent struct {
+	value string
+	known bool
+	parts bool
+}
+
+// plannedContentFromResourceDiff joins the parts of a file when it has any.
+// Parts that are produced during the same apply, or whose source doesn't
+// exist yet, leave the content unknown.
+func plannedContentFromResourceDiff(fsys fileSystem, d *schema.ResourceDiff) (plannedContent, error) {
+	parts := d.Get("part").([]interface{})
+	if len(parts) == 0 {
+		return plannedContent{value: d.Get("content").(string), known: d.NewValueKnown("content")}, nil
+	}
+
+	planned := plannedContent{parts: true}
+	if config := d.GetRawConfig(); !config.IsWhollyKnown() || !config.GetAttr("part").IsWhollyKnown() {
+		return planned, nil
+	}
+
+	value, err := joinParts(fsys, parts)
+	if os.IsNotExist(err) {
+		return planned, nil
+	}
+	if err != nil {
+		return planned, err
+	}
+	planned.value, planned.known = value, true
+	return planned, nil
+}
+
+// contentFromResourceData returns the content to write to a file.
+func contentFromResourceData(fsys fileSystem, d *schema.ResourceData) (string, error) {
+	if parts := d.Get("part").([]interface{}); len(parts) > 0 {
+		return joinParts(fsys, parts)
+	}
+	return d.Get("content").(string), nil
+}
+
+// joinParts returns the fragments of a file joined in order, reading those
+// with a source from fsys.
+func joinParts(fsys fileSystem, parts []interface{}) (string, error) {
+	type part struct {
+		content string
+		order   int
+	}
+
+	var sorted []part
+	for i, v := range parts {
+		p, _ := v.(map[string]interface{})
+		if p == nil {
+			p = map[string]interface{}{}
+		}
+		content, _ := p["content"].(string)
+		source, _ := p["source"].(string)
+		order, _ := p["order"].(int)
+
+		if (content == "") == (source == "") {
+			return "", fmt.Errorf("part %d: exactly one of content and source must be set", i)
+		}
+		if source != "" {
+			data, err := fsys.ReadFile(source)
+			if err != nil {
+				return "", err
+			}
+			content = string(data)
+		}
+		sorted = append(sorted, part{content: content, order: order})
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].order < sorted[j].order })
+
+	var b strings.Builder
+	for _, p := range sorted {
+		b.WriteString(p.content)
+	}
+	return b.String(), nil
+}
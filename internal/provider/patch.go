@@ -0,0 +1,224 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// hunk is a single "@@ -a,b +c,d @@" section of a unified diff. Lines keep
+// their trailing newline so files without one round-trip exactly.
+type hunk struct {
+	oldStart int
+	newStart int
+	oldLines []string
+	newLines []string
+}
+
+func parseUnifiedDiff(patch string) ([]hunk, error) {
+	var hunks []hunk
+	var current *hunk
+	var oldCount, newCount int
+	headers := 0
+
+	lines := strings.SplitAfter(patch, "\n")
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		// "\ No newline at end of file" applies to the previous line
+		if strings.HasPrefix(line, `\`) {
+			if current == nil || i == 0 {
+				return nil, fmt.Errorf("malformed patch at line %d: unexpected newline marker", i+1)
+			}
+			previous := lines[i-1]
+			if strings.HasPrefix(previous, "-") || strings.HasPrefix(previous, " ") {
+				trimLastNewline(current.oldLines)
+			}
+			if strings.HasPrefix(previous, "+") || strings.HasPrefix(previous, " ") {
+				trimLastNewline(current.newLines)
+			}
+			continue
+		}
+
+		// Inside a hunk until both sides have been consumed
+		if current != nil && (oldCount > 0 || newCount > 0) {
+			body := line[1:]
+			switch line[0] {
+			case ' ':
+				current.oldLines = append(current.oldLines, body)
+				current.newLines = append(current.newLines, body)
+				oldCount--
+				newCount--
+			case '-':
+				current.oldLines = append(current.oldLines, body)
+				oldCount--
+			case '+':
+				current.newLines = append(current.newLines, body)
+				newCount--
+			case '\n':
+				// Some editors strip the leading space of empty context lines
+				current.oldLines = append(current.oldLines, "\n")
+				current.newLines = append(current.newLines, "\n")
+				oldCount--
+				newCount--
+			default:
+				return nil, fmt.Errorf("malformed patch at line %d: unexpected %q in hunk", i+1, strings.TrimRight(line, "\n"))
+			}
+			if oldCount < 0 || newCount < 0 {
+				return nil, fmt.Errorf("malformed patch at line %d: hunk is longer than its header says", i+1)
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "@@ "):
+			h, o, n, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("malformed patch at line %d: %s", i+1, err)
+			}
+			hunks = append(hunks, h)
+			current = &hunks[len(hunks)-1]
+			oldCount, newCount = o, n
+
+		case strings.HasPrefix(line, "--- "):
+			headers++
+			if headers > 1 {
+				return nil, fmt.Errorf("patch modifies more than one file; use one filesystem_patch per file")
+			}
+		}
+	}
+
+	if oldCount > 0 || newCount > 0 {
+		return nil, fmt.Errorf("malformed patch: last hunk is truncated")
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("patch contains no hunks")
+	}
+
+	return hunks, nil
+}
+
+func parseHunkHeader(line string) (hunk, int, int, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") || !strings.HasPrefix(fields[3], "@@") {
+		return hunk{}, 0, 0, fmt.Errorf("invalid hunk header %q", strings.TrimRight(line, "\n"))
+	}
+
+	oldStart, oldCount, err := parseRange(fields[1][1:])
+	if err != nil {
+		return hunk{}, 0, 0, err
+	}
+	newStart, newCount, err := parseRange(fields[2][1:])
+	if err != nil {
+		return hunk{}, 0, 0, err
+	}
+
+	return hunk{oldStart: oldStart, newStart: newStart}, oldCount, newCount, nil
+}
+
+func parseRange(r string) (int, int, error) {
+	start, count, found := strings.Cut(r, ",")
+	s, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid hunk range %q", r)
+	}
+	if !found {
+		return s, 1, nil
+	}
+	c, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid hunk range %q", r)
+	}
+	return s, c, nil
+}
+
+func trimLastNewline(lines []string) {
+	if len(lines) > 0 {
+		lines[len(lines)-1] = strings.TrimSuffix(lines[len(lines)-1], "\n")
+	}
+}
+
+// reverseHunks turns a patch into the one that undoes it.
+func reverseHunks(hunks []hunk) []hunk {
+	reversed := make([]hunk, len(hunks))
+	for i, h := range hunks {
+		reversed[i] = hunk{
+			oldStart: h.newStart,
+			newStart: h.oldStart,
+			oldLines: h.newLines,
+			newLines: h.oldLines,
+		}
+	}
+	return reversed
+}
+
+// applyHunks applies the hunks to content, searching around the expected
+// location like patch(1) does, and fails on the first hunk that doesn't match.
+func applyHunks(content string, hunks []hunk) (string, error) {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	offset := 0
+	minimum := 0
+	for i, h := range hunks {
+		expected := h.oldStart - 1 + offset
+		if len(h.oldLines) == 0 {
+			expected = h.oldStart + offset
+		}
+
+		at := findHunk(lines, h.oldLines, expected, minimum)
+		if at < 0 {
+			return "", fmt.Errorf("hunk #%d does not apply at line %d", i+1, h.oldStart)
+		}
+
+		patched := make([]string, 0, len(lines)-len(h.oldLines)+len(h.newLines))
+		patched = append(patched, lines[:at]...)
+		patched = append(patched, h.newLines...)
+		patched = append(patched, lines[at+len(h.oldLines):]...)
+		lines = patched
+
+		offset = at - (h.oldStart - 1) + len(h.newLines) - len(h.oldLines)
+		if len(h.oldLines) == 0 {
+			offset--
+		}
+		minimum = at + len(h.newLines)
+	}
+
+	return strings.Join(lines, ""), nil
+}
+
+func findHunk(lines, want []string, expected, minimum int) int {
+	matches := func(at int) bool {
+		if at < minimum || at+len(want) > len(lines) {
+			return false
+		}
+		for j, line := range want {
+			if lines[at+j] != line {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Insertions into an empty region only fit where the header says
+	if len(want) == 0 {
+		if expected >= minimum && expected <= len(lines) {
+			return expected
+		}
+		return -1
+	}
+
+	for delta := 0; delta <= len(lines); delta++ {
+		if matches(expected - delta) {
+			return expected - delta
+		}
+		if matches(expected + delta) {
+			return expected + delta
+		}
+	}
+	return -1
+}
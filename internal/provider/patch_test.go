@@ -0,0 +1,285 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  []hunk
+		err   string
+	}{
+		{
+			name:  "headers and context",
+			patch: "--- a/file\n+++ b/file\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+			want:  []hunk{{oldStart: 1, newStart: 1, oldLines: []string{"a\n", "b\n"}, newLines: []string{"a\n", "c\n"}}},
+		},
+		{
+			name:  "pure insert",
+			patch: "@@ -1,0 +2 @@\n+b\n",
+			want:  []hunk{{oldStart: 1, newStart: 2, newLines: []string{"b\n"}}},
+		},
+		{
+			name:  "pure delete",
+			patch: "@@ -2 +1,0 @@\n-b\n",
+			want:  []hunk{{oldStart: 2, newStart: 1, oldLines: []string{"b\n"}}},
+		},
+		{
+			name:  "no newline at end of file",
+			patch: "@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+b\n",
+			want:  []hunk{{oldStart: 1, newStart: 1, oldLines: []string{"a"}, newLines: []string{"b\n"}}},
+		},
+		{
+			name:  "no newline at end of context",
+			patch: "@@ -1,2 +1,2 @@\n-a\n+b\n c\n\\ No newline at end of file\n",
+			want:  []hunk{{oldStart: 1, newStart: 1, oldLines: []string{"a\n", "c"}, newLines: []string{"b\n", "c"}}},
+		},
+		{
+			name:  "empty context line without its space",
+			patch: "@@ -1,2 +1,2 @@\n\n-a\n+b\n",
+			want:  []hunk{{oldStart: 1, newStart: 1, oldLines: []string{"\n", "a\n"}, newLines: []string{"\n", "b\n"}}},
+		},
+		{
+			name:  "more than one file",
+			patch: "--- a/one\n+++ b/one\n@@ -1 +1 @@\n-a\n+b\n--- a/two\n+++ b/two\n@@ -1 +1 @@\n-a\n+b\n",
+			err:   "more than one file",
+		},
+		{
+			name:  "truncated hunk",
+			patch: "@@ -1,2 +1,2 @@\n a\n",
+			err:   "truncated",
+		},
+		{
+			name:  "hunk longer than its header",
+			patch: "@@ -1 +1 @@\n-a\n-b\n",
+			err:   "longer than its header",
+		},
+		{
+			name:  "invalid range",
+			patch: "@@ -x +1 @@\n",
+			err:   "invalid hunk range",
+		},
+		{
+			name:  "no hunks",
+			patch: "--- a/file\n+++ b/file\n",
+			err:   "no hunks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUnifiedDiff(tt.patch)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("parseUnifiedDiff() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseUnifiedDiff() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i].oldStart != tt.want[i].oldStart || got[i].newStart != tt.want[i].newStart ||
+					strings.Join(got[i].oldLines, "|") != strings.Join(tt.want[i].oldLines, "|") ||
+					strings.Join(got[i].newLines, "|") != strings.Join(tt.want[i].newLines, "|") {
+					t.Errorf("hunk %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyHunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		patch   string
+		want    string
+		err     bool
+	}{
+		{
+			name:    "change",
+			content: "a\nb\nc\n",
+			patch:   "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			want:    "a\nB\nc\n",
+		},
+		{
+			name:    "pure insert",
+			content: "a\nc\n",
+			patch:   "@@ -1,0 +2 @@\n+b\n",
+			want:    "a\nb\nc\n",
+		},
+		{
+			name:    "pure insert at the start",
+			content: "b\n",
+			patch:   "@@ -0,0 +1 @@\n+a\n",
+			want:    "a\nb\n",
+		},
+		{
+			name:    "pure insert into an empty file",
+			content: "",
+			patch:   "@@ -0,0 +1,2 @@\n+a\n+b\n",
+			want:    "a\nb\n",
+		},
+		{
+			name:    "pure delete",
+			content: "a\nb\nc\n",
+			patch:   "@@ -2 +1,0 @@\n-b\n",
+			want:    "a\nc\n",
+		},
+		{
+			name:    "delete everything",
+			content: "a\nb\n",
+			patch:   "@@ -1,2 +0,0 @@\n-a\n-b\n",
+			want:    "",
+		},
+		{
+			name:    "hunks after one that adds lines",
+			content: "a\nb\nc\nd\ne\nf\ng\n",
+			patch:   "@@ -1,2 +1,4 @@\n a\n+a1\n+a2\n b\n@@ -6,2 +8,2 @@\n f\n-g\n+G\n",
+			want:    "a\na1\na2\nb\nc\nd\ne\nf\nG\n",
+		},
+		{
+			name:    "hunks after one that removes lines",
+			content: "a\nb\nc\nd\ne\nf\ng\n",
+			patch:   "@@ -1,3 +1 @@\n a\n-b\n-c\n@@ -5 +2,0 @@\n-e\n@@ -7 +4 @@\n-g\n+G\n",
+			want:    "a\nd\nf\nG\n",
+		},
+		{
+			name:    "hunks offset by lines added since the patch was made",
+			content: "x\ny\na\nb\nc\nd\ne\n",
+			patch:   "@@ -1,2 +1,2 @@\n-a\n+A\n b\n@@ -4,2 +4,2 @@\n d\n-e\n+E\n",
+			want:    "x\ny\nA\nb\nc\nd\nE\n",
+		},
+		{
+			name:    "hunks offset by lines removed since the patch was made",
+			content: "c\nd\ne\n",
+			patch:   "@@ -3,2 +3,2 @@\n-c\n+C\n d\n@@ -5 +5 @@\n-e\n+E\n",
+			want:    "C\nd\nE\n",
+		},
+		{
+			name:    "insert offset by an earlier hunk",
+			content: "a\nb\nc\n",
+			patch:   "@@ -1 +1,2 @@\n a\n+a1\n@@ -2,0 +4 @@\n+b1\n",
+			want:    "a\na1\nb\nb1\nc\n",
+		},
+		{
+			name:    "hunks don't match before an earlier hunk",
+			content: "a\na\n",
+			patch:   "@@ -2 +2 @@\n-a\n+b\n@@ -3 +3 @@\n-a\n+c\n",
+			err:     true,
+		},
+		{
+			name:    "add a missing trailing newline",
+			content: "a\nb",
+			patch:   "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+			want:    "a\nb\n",
+		},
+		{
+			name:    "remove the trailing newline",
+			content: "a\nb\n",
+			patch:   "@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+			want:    "a\nb",
+		},
+		{
+			name:    "change a line without a trailing newline",
+			content: "a\nb",
+			patch:   "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+			want:    "a\nc",
+		},
+		{
+			name:    "trailing newline that the patch doesn't expect",
+			content: "a\nb\n",
+			patch:   "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n",
+			err:     true,
+		},
+		{
+			name:    "content that doesn't match",
+			content: "a\nx\nc\n",
+			patch:   "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks, err := parseUnifiedDiff(tt.patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := applyHunks(tt.content, hunks)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("applyHunks() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("applyHunks() = %q, want %q", got, tt.want)
+			}
+
+			// What was applied can be undone
+			undone, err := applyHunks(got, reverseHunks(hunks))
+			if err != nil {
+				t.Fatalf("applyHunks() reversed: %s", err)
+			}
+			if undone != tt.content {
+				t.Errorf("applyHunks() reversed = %q, want %q", undone, tt.content)
+			}
+		})
+	}
+}
+
+func TestApplyHunksAlreadyApplied(t *testing.T) {
+	hunks, err := parseUnifiedDiff("@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := applyHunks("a\nB\nc\n", hunks); err == nil {
+		t.Fatalf("applyHunks() = %q, want an error", got)
+	}
+	if _, err := applyHunks("a\nB\nc\n", reverseHunks(hunks)); err != nil {
+		t.Fatalf("applyHunks() reversed: %s", err)
+	}
+}
+
+func TestFindHunk(t *testing.T) {
+	lines := []string{"a\n", "b\n", "c\n", "b\n", "d\n"}
+	tests := []struct {
+		name     string
+		want     []string
+		expected int
+		minimum  int
+		at       int
+	}{
+		{name: "where expected", want: []string{"c\n"}, expected: 2, at: 2},
+		{name: "before expected", want: []string{"a\n", "b\n"}, expected: 3, at: 0},
+		{name: "after expected", want: []string{"d\n"}, expected: 0, at: 4},
+		{name: "nearest match", want: []string{"b\n"}, expected: 4, at: 3},
+		{name: "nearest match before on a tie", want: []string{"b\n"}, expected: 2, at: 1},
+		{name: "not before minimum", want: []string{"b\n"}, expected: 1, minimum: 2, at: 3},
+		{name: "past the end", want: []string{"d\n", "e\n"}, expected: 4, at: -1},
+		{name: "missing", want: []string{"e\n"}, expected: 0, at: -1},
+		{name: "insert where expected", want: nil, expected: 2, at: 2},
+		{name: "insert at the end", want: nil, expected: 5, at: 5},
+		{name: "insert past the end", want: nil, expected: 6, at: -1},
+		{name: "insert before minimum", want: nil, expected: 1, minimum: 2, at: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if at := findHunk(lines, tt.want, tt.expected, tt.minimum); at != tt.at {
+				t.Errorf("findHunk() = %d, want %d", at, tt.at)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// diffSuppressPath ignores changes between different spellings of the same
+// path, such as "C:/data" and "C:\\data" on Windows or "/data/" and "/data".
+func diffSuppressPath(k, old, new string, d *schema.ResourceData) bool {
+	return old != "" && new != "" && samePath(old, new)
+}
+
+// pathID returns the ID of a resource managing path: its cleaned, absolute
+// form as fsys resolves it.
+func pathID(fsys fileSystem, path string) string {
+	if abs, err := fsys.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// expandHome replaces a leading "~" or "~user" in path with the home
+// directory of the current or the named user on the machine running
+// Terraform.
+func expandHome(path string) (string, error) {
+	return replaceHome(path, localHomeDir)
+}
+
+// replaceHome replaces a leading "~" or "~user" in path with the home
+// directory that homeDir returns for the user, "" being the current one.
+func replaceHome(path string, homeDir func(name string) (string, error)) (string, error) {
+	if !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+
+	end := strings.IndexAny(path, `/`+string(filepath.Separator))
+	if end < 0 {
+		end = len(path)
+	}
+	home, err := homeDir(path[1:end])
+	if err != nil {
+		return "", err
+	}
+	return home + path[end:], nil
+}
+
+// localHomeDir returns the home directory of the named user, or of the
+// current one for "", on the machine running Terraform.
+func localHomeDir(name string) (string, error) {
+	if name == "" {
+		return os.UserHomeDir()
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", fmt.Errorf("error looking up user %s: %s", name, err)
+	}
+	return u.HomeDir, nil
+}
+
+// reservedFilenames are the device names that Windows doesn't allow as file
+// names, with or without an extension.
+var reservedFilenames = regexp.MustCompile(`(?i)^(con|prn|aux|nul|com[0-9]|lpt[0-9])(\..*)?$`)
+
+// maxFilename is the longest file name, in bytes, that common filesystems
+// accept.
+const maxFilename = 255
+
+// sanitizeFilename turns name into a file name that is valid on Linux,
+// macOS and Windows alike. Separators, the characters Windows reserves and
+// control characters become underscores, trailing dots and spaces are
+// removed and reserved device names get a leading underscore.
+func sanitizeFilename(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`<>:"/\|?*`, r) {
+			r = '_'
+		}
+		if b.Len()+utf8.RuneLen(r) > maxFilename {
+			break
+		}
+		b.WriteRune(r)
+	}
+
+	s := strings.TrimRight(b.String(), ". ")
+	if reservedFilenames.MatchString(s) {
+		s = "_" + s
+		if len(s) > maxFilename {
+			s = s[:maxFilename]
+		}
+	}
+	if s == "" {
+		return "_"
+	}
+	return s
+}
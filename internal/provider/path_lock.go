@@ -0,0 +1,176 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// pathLocks serializes operations on the same path, so that resources
+// managing different aspects of one file, such as its content and its
+// permissions, don't interleave when Terraform runs them in parallel.
+type pathLocks struct {
+	mu    sync.Mutex
+	locks map[string]*pathLock
+}
+
+// pathLock is held while its channel holds a value, so that waiting for it
+// can be given up.
+type pathLock struct {
+	held chan struct{}
+	refs int
+}
+
+// lock waits for the operations on paths to finish and returns the function
+// that unlocks them again, failing once ctx is done first. Paths are
+// compared as fsys resolves them, so that a symlink and its target share a
+// lock, and are locked in order, so that operations sharing several paths
+// can't deadlock. Empty paths are skipped.
+func (l *pathLocks) lock(ctx context.Context, fsys fileSystem, paths ...string) (func(), error) {
+	var keys []string
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		key := filepath.Clean(path)
+		if resolved, err := fsys.Resolve(path); err == nil {
+			key = resolved
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var unlocks []func()
+	unlock := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for i, key := range keys {
+		if i > 0 && key == keys[i-1] {
+			continue
+		}
+		u, err := l.lockKey(ctx, key)
+		if err != nil {
+			unlock()
+			return nil, err
+		}
+		unlocks = append(unlocks, u)
+	}
+	return unlock, nil
+}
+
+func (l *pathLocks) lockKey(ctx context.Context, key string) (func(), error) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*pathLock{}
+	}
+	pl := l.locks[key]
+	if pl == nil {
+		pl = &pathLock{held: make(chan struct{}, 1)}
+		l.locks[key] = pl
+	}
+	pl.refs++
+	l.mu.Unlock()
+
+	release := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if pl.refs--; pl.refs == 0 {
+			delete(l.locks, key)
+		}
+	}
+
+	select {
+	case pl.held <- struct{}{}:
+	case <-ctx.Done():
+		release()
+		return nil, fmt.Errorf("error waiting for the lock on %s: %s", key, ctx.Err())
+	}
+	return func() {
+		<-pl.held
+		release()
+	}, nil
+}
+
+// lockedPaths returns, by resource type, the paths the operations of a
+// resource touch. A path that is only known once the resource is created,
+// like that of a temporary directory, is locked through where it's created
+// until then.
+var lockedPaths = map[string]func(d *schema.ResourceData) []string{
+	"filesystem_file":                attributePaths("path"),
+	"filesystem_directory":           attributePaths("path"),
+	"filesystem_directory_tree":      directoryTreePaths,
+	"filesystem_directory_link":      attributePaths("path"),
+	"filesystem_link_farm":           attributePaths("path", "source"),
+	"filesystem_temporary_directory": attributePaths("parent", "path"),
+	"filesystem_copy":                attributePaths("source", "destination"),
+	"filesystem_generated_file":      attributePaths("path"),
+	"filesystem_patch":               attributePaths("path"),
+	"filesystem_device_node":         attributePaths("path"),
+	"filesystem_format":              attributePaths("device"),
+	"filesystem_btrfs_subvolume":     attributePaths("path", "snapshot_of"),
+	"filesystem_quota":               attributePaths("path"),
+	"filesystem_loopback_image":      attributePaths("path", "mountpoint"),
+	"filesystem_tmpfs":               attributePaths("path"),
+	"filesystem_swapfile":            attributePaths("path"),
+	"filesystem_permissions":         attributePaths("path"),
+	"filesystem_ownership":           attributePaths("path"),
+	"filesystem_capability":          attributePaths("path"),
+}
+
+// attributePaths returns the function returning the paths that the string
+// attributes keys of a resource name. An attribute that changes, as when a
+// file moves, names both its old and its new path.
+func attributePaths(keys ...string) func(d *schema.ResourceData) []string {
+	return func(d *schema.ResourceData) []string {
+		var paths []string
+		for _, k := range keys {
+			old, new := d.GetChange(k)
+			paths = append(paths, new.(string))
+			if old != new {
+				paths = append(paths, old.(string))
+			}
+		}
+		return paths
+	}
+}
+
+// withPathLock runs the operations of a resource with the paths it touches
+// locked, as returned by paths.
+func withPathLock(r *schema.Resource, paths func(d *schema.ResourceData) []string) *schema.Resource {
+	if paths == nil {
+		return r
+	}
+
+	wrap := func(f func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+		return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+			conf := meta.(*providerConfig)
+			unlock, err := conf.locks.lock(ctx, withContext(ctx, conf.fs), paths(d)...)
+			if err != nil {
+				return diag.FromErr(err)
+			}
+			defer unlock()
+			return f(ctx, d, meta)
+		}
+	}
+
+	if r.CreateContext != nil {
+		r.CreateContext = wrap(r.CreateContext)
+	}
+	if r.ReadContext != nil {
+		r.ReadContext = wrap(r.ReadContext)
+	}
+	if r.UpdateContext != nil {
+		r.UpdateContext = wrap(r.UpdateContext)
+	}
+	if r.DeleteContext != nil {
+		r.DeleteContext = wrap(r.DeleteContext)
+	}
+	return r
+}
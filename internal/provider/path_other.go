@@ -0,0 +1,26 @@
+//go:build !windows
+
+package provider
+
+import "path/filepath"
+
+// localPath prepares a path for the operating system. Only Windows needs
+// any preparation.
+func localPath(path string) string {
+	return path
+}
+
+// splitStream returns path as is, as only NTFS has alternate data streams
+// and colons are part of file names elsewhere.
+func splitStream(path string) (file, stream string) {
+	return path, ""
+}
+
+func samePath(a, b string) bool {
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// localQuote quotes s as a single word for sh, which runs local commands.
+func localQuote(s string) string {
+	return shellQuote(s)
+}
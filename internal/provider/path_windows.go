@@ -0,0 +1,76 @@
+//go:build windows
+
+package provider
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxPath is the longest path, less room for a file name, that the Windows
+// API accepts without the \\?\ prefix.
+const maxPath = 248
+
+// localPath prepares a path for the Windows API. Separators are normalized,
+// drive-relative paths such as "C:data" are made absolute, and paths that
+// are too long get the \\?\ prefix, or \\?\UNC\ for \\server\share paths.
+func localPath(path string) string {
+	if path == "" || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+
+	p := filepath.FromSlash(path)
+	volume := filepath.VolumeName(p)
+	driveRelative := len(volume) == 2 && (len(p) == 2 || !filepath.IsAbs(p))
+	if len(p) < maxPath && !driveRelative {
+		return p
+	}
+
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	if len(abs) < maxPath {
+		return abs
+	}
+
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
+
+// splitStream splits the path of an NTFS alternate data stream, such as
+// "C:\\data\\setup.exe:Zone.Identifier", into the file holding it and the
+// name of the stream. stream is empty for the path of a file.
+func splitStream(path string) (file, stream string) {
+	volume := len(filepath.VolumeName(path))
+	base := volume + strings.LastIndexAny(path[volume:], `\/`) + 1
+	i := strings.IndexByte(path[base:], ':')
+	if i < 0 {
+		return path, ""
+	}
+	return path[:base+i], path[base+i+1:]
+}
+
+// samePath compares paths the way Windows does: ignoring case, separators
+// and the \\?\ prefix.
+func samePath(a, b string) bool {
+	return strings.EqualFold(displayPath(a), displayPath(b))
+}
+
+func displayPath(path string) string {
+	switch {
+	case strings.HasPrefix(path, `\\?\UNC\`):
+		path = `\\` + path[len(`\\?\UNC\`):]
+	case strings.HasPrefix(path, `\\?\`):
+		path = path[len(`\\?\`):]
+	}
+	return filepath.Clean(path)
+}
+
+// localQuote quotes s as a single word for cmd, which runs local commands.
+// Paths can't contain double quotes, so they need no escaping.
+func localQuote(s string) string {
+	return `"` + s + `"`
+}
@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// parsePermissions accepts octal permissions with or without a leading
+// "0" or "0o", such as "644", "0644" and "0o644". A fourth digit sets the
+// setuid (4), setgid (2) and sticky (1) bits, as in "2775" and "1777".
+func parsePermissions(perm string) (os.FileMode, error) {
+	digits := strings.TrimPrefix(perm, "0o")
+	if digits == "" || len(digits) > 4 {
+		return 0, fmt.Errorf("invalid permission format: %s", perm)
+	}
+
+	m, err := strconv.ParseUint(digits, 8, 32)
+	if err != nil || m > 07777 {
+		return 0, fmt.Errorf("invalid permission format: %s", perm)
+	}
+
+	mode := os.FileMode(m & 0777)
+	if m&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if m&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if m&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, nil
+}
+
+// permissionBits returns the permissions of mode along with its setuid,
+// setgid and sticky bits.
+func permissionBits(mode os.FileMode) os.FileMode {
+	return mode & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+}
+
+// unixPermissions converts the permission bits of an os.FileMode to a
+// st_mode value.
+func unixPermissions(mode os.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= 01000
+	}
+	return m
+}
+
+// formatPermissions returns the permission bits of mode in chmod(1)'s
+// octal notation, the form that permissions are reported in.
+func formatPermissions(mode os.FileMode) string {
+	return fmt.Sprintf("%04o", unixPermissions(mode))
+}
+
+func validatePermissions(v interface{}, path cty.Path) diag.Diagnostics {
+	if _, err := parsePermissions(v.(string)); err != nil {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Invalid permissions",
+			Detail:        fmt.Sprintf("%s, expected octal permissions such as \"0644\"", err),
+			AttributePath: path,
+		}}
+	}
+	return nil
+}
+
+// normalizePermissions stores permissions in the four-digit form that Read
+// reports, so that "644" and "0o644" don't show up as changes.
+func normalizePermissions(v interface{}) string {
+	mode, err := parsePermissions(v.(string))
+	if err != nil {
+		return v.(string)
+	}
+	return formatPermissions(mode)
+}
+
+func diffSuppressPermissions(k, old, new string, d *schema.ResourceData) bool {
+	o, err := parsePermissions(old)
+	if err != nil {
+		return false
+	}
+	n, err := parsePermissions(new)
+	return err == nil && o == n
+}
@@ -2,23 +2,694 @@ package provider
 
 import (
 	"context"
+	"crypto/md5"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
 func New() *schema.Provider {
-	return &schema.Provider{
+	p := &schema.Provider{
+		Schema: map[string]*schema.Schema{
+			"base_path": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateFunc: func(v interface{}, k string) ([]string, []error) {
+					if !filepath.IsAbs(v.(string)) {
+						return nil, []error{fmt.Errorf("%s: %q is not an absolute path", k, v)}
+					}
+					return nil, nil
+				},
+				Description: "Root relative paths at this directory and reject any path that resolves outside of it",
+			},
+			"default_file_permissions": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "0644",
+				ValidateDiagFunc: validatePermissions,
+				Description:      "Permissions of files that don't set their own, in octal format",
+			},
+			"default_directory_permissions": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "0755",
+				ValidateDiagFunc: validatePermissions,
+				Description:      "Permissions of directories that don't set their own, including missing parent directories",
+			},
+			"default_owner": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The user name or numeric ID owning files and directories that don't set their own",
+			},
+			"default_group": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The group name or numeric ID owning files and directories that don't set their own",
+			},
+			"umask": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "0000",
+				ValidateDiagFunc: validatePermissions,
+				Description:      "Bits cleared from the default permissions, in octal format (e.g., '0027')",
+			},
+			"backup": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Copy files and directories aside before they are overwritten or deleted",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"dir": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Directory below which backups mirror the original paths. Defaults to next to the original",
+						},
+						"suffix": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      ".bak",
+							ValidateFunc: validation.StringIsNotEmpty,
+							Description:  "Suffix appended to backup names after the timestamp",
+						},
+						"retention": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      0,
+							ValidateFunc: validation.IntAtLeast(0),
+							Description:  "How many backups of each path to keep. 0 keeps all of them",
+						},
+					},
+				},
+			},
+			"path_policy": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Refuse paths that resources and data sources must not touch, as guardrails for shared configurations",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"require_absolute_paths": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Refuse relative paths",
+						},
+						"deny_patterns": excludesSchema("Gitignore-style patterns of absolute paths to refuse, along with everything below them (e.g., ['/boot', '/proc']). Symlinks and '..' are resolved before matching"),
+						"deny_traversal": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Refuse paths with '..' elements",
+						},
+					},
+				},
+			},
+			"retry": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Retry reads that fail with a transient error, such as EBUSY, EAGAIN, ETXTBSY or a stale NFS handle. Changes are not retried",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"max_attempts": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      3,
+							ValidateFunc: validation.IntAtLeast(1),
+							Description:  "How many times each operation is tried before giving up",
+						},
+						"backoff": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     "250ms",
+							Description: "How long to wait before the first retry (e.g., '250ms'). The wait doubles after each attempt",
+						},
+						"max_backoff": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     "5s",
+							Description: "The longest wait between two attempts",
+						},
+					},
+				},
+			},
+			"parallelism": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      defaultParallelism,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "How many files directory copies, directory checksums, recursive permission and ownership changes and filesystem_files work on at once",
+			},
+			"read_only": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Refuse every create, update and delete while still allowing reads and plans",
+			},
+			"expand_paths": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Expand a leading ~ or ~user and $VAR, ${VAR} and %VAR% environment variables in paths, using the home directories and the environment of the target. Resources plan their paths expanded",
+			},
+			"audit_log": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Append a JSON line to a file on the machine running Terraform for every create, update and delete of a resource",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The file the audit log is appended to. It is created with permissions 0600 when missing",
+						},
+						"run_id": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Identifies the Terraform run in every entry. Defaults to TFC_RUN_ID when set, and otherwise to an ID generated when the provider starts",
+						},
+					},
+				},
+			},
+			"target": {
+				Type:          schema.TypeList,
+				Optional:      true,
+				MaxItems:      1,
+				ConflictsWith: []string{"ssh"},
+				Description:   "Operate inside a running Docker container instead of the machine running Terraform",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"docker_container": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The name or ID of the container",
+						},
+						"docker_host": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							DefaultFunc: schema.EnvDefaultFunc("DOCKER_HOST", "unix:///var/run/docker.sock"),
+							Description: "The Docker daemon address. Defaults to DOCKER_HOST or unix:///var/run/docker.sock",
+						},
+						"docker_user": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "The user commands run as in the container. Defaults to the container's user",
+						},
+						"docker_tls_verify": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							DefaultFunc: func() (interface{}, error) {
+								return os.Getenv("DOCKER_TLS_VERIFY") != "", nil
+							},
+							Description: "Connect to a tcp:// daemon with TLS, verifying it and authenticating with the certificates in docker_cert_path. Defaults to whether DOCKER_TLS_VERIFY is set",
+						},
+						"docker_cert_path": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							DefaultFunc: schema.EnvDefaultFunc("DOCKER_CERT_PATH", "~/.docker"),
+							Description: "The directory holding ca.pem, cert.pem and key.pem for docker_tls_verify. Defaults to DOCKER_CERT_PATH or ~/.docker",
+						},
+						"docker_insecure_tcp": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Allow a tcp:// daemon address without TLS, which lets anyone who can reach the daemon run commands through it",
+						},
+					},
+				},
+			},
+			"become": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Privilege escalation for resources that set become, whose operations then run through sudo or doas on the machine running Terraform or the ssh host, or as another user in the target container",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"method": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "sudo",
+							ValidateFunc: validation.StringInSlice([]string{"sudo", "doas"}, false),
+							Description:  "The command operations run through, 'sudo' or 'doas'. It must let the user the provider runs or logs in as run commands without a password. Ignored with target",
+						},
+						"user": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     "root",
+							Description: "The user to become",
+						},
+					},
+				},
+			},
+			"ssh": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Operate on a remote host over SFTP instead of the machine running Terraform",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"host": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The remote host name or address",
+						},
+						"port": {
+							Type:        schema.TypeInt,
+							Optional:    true,
+							Default:     22,
+							Description: "The SSH port",
+						},
+						"user": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The user to log in as",
+						},
+						"password": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Sensitive:   true,
+							Description: "Password authentication",
+						},
+						"private_key": {
+							Type:          schema.TypeString,
+							Optional:      true,
+							Sensitive:     true,
+							ConflictsWith: []string{"ssh.0.private_key_path"},
+							Description:   "PEM-encoded private key",
+						},
+						"private_key_path": {
+							Type:          schema.TypeString,
+							Optional:      true,
+							ConflictsWith: []string{"ssh.0.private_key"},
+							Description:   "Path to a PEM-encoded private key",
+						},
+						"private_key_passphrase": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Sensitive:   true,
+							Description: "Passphrase for an encrypted private key",
+						},
+						"agent": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     true,
+							Description: "Use the SSH agent from SSH_AUTH_SOCK when available",
+						},
+						"host_key": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "The expected host public key in authorized_keys format. Overrides known_hosts",
+						},
+						"known_hosts_file": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "The known_hosts file used to verify the host. Defaults to ~/.ssh/known_hosts",
+						},
+						"insecure_ignore_host_key": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Skip host key verification",
+						},
+						"sudo": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Run the SFTP server and commands through passwordless sudo so that operations run as root",
+						},
+						"sftp_server": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Path to the sftp-server binary on the remote host, used with sudo. Defaults to the binary the sftp subsystem of sshd runs, or where the common distributions install it",
+						},
+						"timeout": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     "30s",
+							Description: "How long to wait for the connection (e.g., '30s')",
+						},
+					},
+				},
+			},
+		},
+		ConfigureContextFunc: providerConfigure,
 		ResourcesMap: map[string]*schema.Resource{
-			"filesystem_file":      resourceFile(),
-			"filesystem_directory": resourceDirectory(),
+			"filesystem_file":                withPathID(resourceFile(), "path", resourceFileV0()),
+			"filesystem_directory":           withPathID(resourceDirectory(), "path", resourceDirectoryV0()),
+			"filesystem_directory_tree":      resourceDirectoryTree(),
+			"filesystem_directory_link":      resourceDirectoryLink(),
+			"filesystem_link_farm":           resourceLinkFarm(),
+			"filesystem_temporary_directory": resourceTemporaryDirectory(),
+			"filesystem_copy":                resourceCopy(),
+			"filesystem_generated_file":      resourceGeneratedFile(),
+			"filesystem_patch":               resourcePatch(),
+			"filesystem_device_node":         resourceDeviceNode(),
+			"filesystem_format":              resourceFormat(),
+			"filesystem_btrfs_subvolume":     resourceBtrfsSubvolume(),
+			"filesystem_quota":               resourceQuota(),
+			"filesystem_loopback_image":      resourceLoopbackImage(),
+			"filesystem_tmpfs":               resourceTmpfs(),
+			"filesystem_swapfile":            resourceSwapfile(),
+			"filesystem_permissions":         resourcePermissions(),
+			"filesystem_ownership":           resourceOwnership(),
+			"filesystem_capability":          resourceCapability(),
+		},
+		DataSourcesMap: map[string]*schema.Resource{
+			"filesystem_file":              dataSourceFile(),
+			"filesystem_file_lines":        dataSourceFileLines(),
+			"filesystem_file_type":         dataSourceFileType(),
+			"filesystem_directory_entries": dataSourceDirectoryEntries(),
+			"filesystem_checksum":          dataSourceChecksum(),
+			"filesystem_tree_hash":         dataSourceTreeHash(),
+			"filesystem_stat":              dataSourceStat(),
+			"filesystem_disk_usage":        dataSourceDiskUsage(),
+			"filesystem_mounts":            dataSourceMounts(),
+			"filesystem_find":              dataSourceFind(),
+			"filesystem_exists":            dataSourceExists(),
+			"filesystem_access":            dataSourceAccess(),
+			"filesystem_check":             dataSourceCheck(),
+			"filesystem_wait_for":          dataSourceWaitFor(),
 		},
 	}
+
+	// Paths are locked around what is recorded in the audit log, which
+	// reads the files as the user the operations run as
+	for name, r := range p.ResourcesMap {
+		withPathLock(withBecome(withAudit(name, withExpandPaths(p, r))), lockedPaths[name])
+	}
+	return p
+}
+
+// providerConfig is the meta value handed to every resource and data source.
+type providerConfig struct {
+	fs          fileSystem
+	defaults    providerDefaults
+	parallelism int
+	audit       *auditLog
+	locks       *pathLocks
+
+	// scratch is fs without the backup layer, for files such as those of
+	// ephemeral resources that must not be kept once they're removed
+	scratch fileSystem
+
+	// system is fs without the layers that translate and confine paths,
+	// for the files of the target itself that resources manage along with
+	// theirs, such as /proc/swaps and /etc/fstab
+	system fileSystem
+
+	// expand expands the paths of resources as they're planned when
+	// expand_paths is set. It is nil otherwise
+	expand *pathExpander
+
+	// become returns the configuration of resources that set become, which
+	// only differs in fs, scratch and system, for an operation bounded by
+	// ctx. It is nil without a become block
+	become func(ctx context.Context) (*providerConfig, error)
+}
+
+// becoming returns the configuration of a resource that sets become to
+// become, for an operation bounded by ctx.
+func (c *providerConfig) becoming(ctx context.Context, become bool) (*providerConfig, error) {
+	if !become {
+		return c, nil
+	}
+	if c.become == nil {
+		return nil, fmt.Errorf("become is set, but the provider has no become block")
+	}
+	return c.become(ctx)
+}
+
+func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+	conf := &providerConfig{fs: localFileSystem{}, locks: &pathLocks{}}
+
+	// Parse the defaults for resources that don't set their own
+	for k, mode := range map[string]*os.FileMode{
+		"default_file_permissions":      &conf.defaults.filePermissions,
+		"default_directory_permissions": &conf.defaults.directoryPermissions,
+		"umask":                         &conf.defaults.umask,
+	} {
+		perm, err := parsePermissions(d.Get(k).(string))
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
+		*mode = perm
+	}
+	conf.defaults.owner = d.Get("default_owner").(string)
+	conf.defaults.group = d.Get("default_group").(string)
+	conf.parallelism = d.Get("parallelism").(int)
+
+	var sftp *sftpFileSystem
+	var docker *dockerConfig
+	if v, ok := d.GetOk("ssh"); ok && len(v.([]interface{})) > 0 && v.([]interface{})[0] != nil {
+		s := v.([]interface{})[0].(map[string]interface{})
+
+		timeout, err := time.ParseDuration(s["timeout"].(string))
+		if err != nil {
+			return nil, diag.FromErr(fmt.Errorf("invalid ssh timeout %q: %s", s["timeout"], err))
+		}
+
+		fsys, err := newSFTPFileSystem(sshConfig{
+			host:                 s["host"].(string),
+			port:                 s["port"].(int),
+			user:                 s["user"].(string),
+			password:             s["password"].(string),
+			privateKey:           s["private_key"].(string),
+			privateKeyPath:       s["private_key_path"].(string),
+			privateKeyPassphrase: s["private_key_passphrase"].(string),
+			agent:                s["agent"].(bool),
+			hostKey:              s["host_key"].(string),
+			knownHostsFile:       s["known_hosts_file"].(string),
+			insecureIgnoreHost:   s["insecure_ignore_host_key"].(bool),
+			sudo:                 s["sudo"].(bool),
+			sftpServer:           s["sftp_server"].(string),
+			timeout:              timeout,
+		})
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
+		conf.fs = fsys
+		sftp = fsys
+	}
+
+	if v, ok := d.GetOk("target"); ok && len(v.([]interface{})) > 0 && v.([]interface{})[0] != nil {
+		t := v.([]interface{})[0].(map[string]interface{})
+
+		docker = &dockerConfig{
+			host:        t["docker_host"].(string),
+			container:   t["docker_container"].(string),
+			user:        t["docker_user"].(string),
+			tlsVerify:   t["docker_tls_verify"].(bool),
+			certPath:    t["docker_cert_path"].(string),
+			insecureTCP: t["docker_insecure_tcp"].(bool),
+		}
+		fsys, err := newDockerFileSystem(*docker)
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
+		conf.fs = fsys
+	}
+
+	// Resources that set become get a target of their own, whose commands
+	// are bound to the operation running them
+	var becomeFS func(ctx context.Context) fileSystem
+	if v, ok := d.GetOk("become"); ok && len(v.([]interface{})) > 0 {
+		b := map[string]interface{}{"method": "sudo", "user": "root"}
+		if v.([]interface{})[0] != nil {
+			b = v.([]interface{})[0].(map[string]interface{})
+		}
+		c := becomeConfig{method: b["method"].(string), user: b["user"].(string)}
+
+		switch {
+		case docker != nil:
+			// The Engine API runs commands as any user of the container
+			as := *docker
+			as.user = c.user
+			fsys, err := newDockerFileSystem(as)
+			if err != nil {
+				return nil, diag.FromErr(err)
+			}
+			becomeFS = func(context.Context) fileSystem { return fsys }
+		case sftp != nil:
+			fsys := newBecomeFileSystem(sftp, sftp.conn, c)
+			becomeFS = func(ctx context.Context) fileSystem { return fsys.withContext(ctx) }
+		case runtime.GOOS != "linux":
+			return nil, diag.FromErr(fmt.Errorf("become is not supported on %s", runtime.GOOS))
+		default:
+			fsys := newBecomeFileSystem(conf.fs, nil, c)
+			becomeFS = func(ctx context.Context) fileSystem { return fsys.withContext(ctx) }
+		}
+	}
+
+	// Both targets get the same layers on top. Those that don't translate
+	// or confine paths also make up the system filesystem
+	var layers, systemLayers []func(fileSystem) (fileSystem, error)
+
+	if v, ok := d.GetOk("retry"); ok && len(v.([]interface{})) > 0 {
+		r := map[string]interface{}{"max_attempts": 3, "backoff": "250ms", "max_backoff": "5s"}
+		if v.([]interface{})[0] != nil {
+			r = v.([]interface{})[0].(map[string]interface{})
+		}
+
+		backoff, err := time.ParseDuration(r["backoff"].(string))
+		if err != nil {
+			return nil, diag.FromErr(fmt.Errorf("invalid retry backoff %q: %s", r["backoff"], err))
+		}
+		maxBackoff, err := time.ParseDuration(r["max_backoff"].(string))
+		if err != nil {
+			return nil, diag.FromErr(fmt.Errorf("invalid retry max_backoff %q: %s", r["max_backoff"], err))
+		}
+
+		layer := func(fsys fileSystem) (fileSystem, error) {
+			return &retryFileSystem{
+				fileSystem: fsys,
+				attempts:   r["max_attempts"].(int),
+				backoff:    backoff,
+				maxBackoff: maxBackoff,
+				ctx:        context.Background(),
+			}, nil
+		}
+		layers = append(layers, layer)
+		systemLayers = append(systemLayers, layer)
+	}
+
+	backupLayer := -1
+	if v, ok := d.GetOk("backup"); ok && len(v.([]interface{})) > 0 {
+		b := map[string]interface{}{"dir": "", "suffix": ".bak", "retention": 0}
+		if v.([]interface{})[0] != nil {
+			b = v.([]interface{})[0].(map[string]interface{})
+		}
+		backupLayer = len(layers)
+		layer := func(fsys fileSystem) (fileSystem, error) {
+			return &backupFileSystem{
+				fileSystem: fsys,
+				dir:        b["dir"].(string),
+				suffix:     b["suffix"].(string),
+				retention:  b["retention"].(int),
+			}, nil
+		}
+		layers = append(layers, layer)
+		systemLayers = append(systemLayers, layer)
+	}
+
+	if basePath := d.Get("base_path").(string); basePath != "" {
+		layers = append(layers, func(fsys fileSystem) (fileSystem, error) {
+			return newSandboxFileSystem(fsys, basePath)
+		})
+	}
+
+	if v, ok := d.GetOk("path_policy"); ok && len(v.([]interface{})) > 0 && v.([]interface{})[0] != nil {
+		p := v.([]interface{})[0].(map[string]interface{})
+
+		deny, err := newExcludeMatcher(p["deny_patterns"].([]interface{}))
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
+		layers = append(layers, func(fsys fileSystem) (fileSystem, error) {
+			return policyFileSystem{
+				fileSystem:      fsys,
+				requireAbsolute: p["require_absolute_paths"].(bool),
+				denyTraversal:   p["deny_traversal"].(bool),
+				deny:            deny,
+			}, nil
+		})
+	}
+
+	// Paths are expanded before they are checked and confined to base_path,
+	// with the home directories and the environment of the target
+	if d.Get("expand_paths").(bool) {
+		conf.expand = localPathExpander()
+		if sftp != nil || docker != nil {
+			conf.expand = targetPathExpander(conf.fs)
+		}
+		layers = append(layers, func(fsys fileSystem) (fileSystem, error) {
+			return expandFileSystem{fileSystem: fsys, paths: conf.expand}, nil
+		})
+	}
+
+	if d.Get("read_only").(bool) {
+		layer := func(fsys fileSystem) (fileSystem, error) {
+			return readOnlyFileSystem{fsys}, nil
+		}
+		layers = append(layers, layer)
+		systemLayers = append(systemLayers, layer)
+	}
+
+	// Files that must not outlive the run skip the backups
+	scratchLayers := layers
+	if backupLayer >= 0 {
+		scratchLayers = append(append([]func(fileSystem) (fileSystem, error){}, layers[:backupLayer]...), layers[backupLayer+1:]...)
+	}
+
+	base := conf.fs
+	var err error
+	if conf.fs, err = wrapFileSystem(base, layers); err != nil {
+		return nil, diag.FromErr(err)
+	}
+	if conf.scratch, err = wrapFileSystem(base, scratchLayers); err != nil {
+		return nil, diag.FromErr(err)
+	}
+	if conf.system, err = wrapFileSystem(base, systemLayers); err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	if v, ok := d.GetOk("audit_log"); ok && len(v.([]interface{})) > 0 && v.([]interface{})[0] != nil {
+		a := v.([]interface{})[0].(map[string]interface{})
+		conf.audit = &auditLog{path: a["path"].(string), runID: a["run_id"].(string)}
+		if conf.audit.runID == "" {
+			conf.audit.runID = newRunID()
+		}
+	}
+
+	if becomeFS != nil {
+		conf.become = func(ctx context.Context) (*providerConfig, error) {
+			base := becomeFS(ctx)
+			become := *conf
+			become.become = nil
+			var err error
+			if become.fs, err = wrapFileSystem(base, layers); err != nil {
+				return nil, err
+			}
+			if become.scratch, err = wrapFileSystem(base, scratchLayers); err != nil {
+				return nil, err
+			}
+			if become.system, err = wrapFileSystem(base, systemLayers); err != nil {
+				return nil, err
+			}
+			return &become, nil
+		}
+		// The layers are applied once here, so that what they reject fails
+		// the configuration rather than the first resource using become
+		if _, err := conf.become(ctx); err != nil {
+			return nil, diag.FromErr(err)
+		}
+	}
+
+	return conf, nil
+}
+
+// wrapFileSystem applies layers to fsys in order.
+func wrapFileSystem(fsys fileSystem, layers []func(fileSystem) (fileSystem, error)) (fileSystem, error) {
+	for _, layer := range layers {
+		var err error
+		if fsys, err = layer(fsys); err != nil {
+			return nil, err
+		}
+	}
+	return fsys, nil
 }
 
 func resourceFile() *schema.Resource {
@@ -27,26 +698,294 @@ func resourceFile() *schema.Resource {
 		ReadContext:   resourceFileRead,
 		UpdateContext: resourceFileUpdate,
 		DeleteContext: resourceFileDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceFileImport,
+		},
+		CustomizeDiff: customdiff.All(
+			customizeDiffDefaults(providerDefaults.filePerm),
+			resourceFileCustomizeDiff,
+			customizeDiffAppliedChecksum,
+			customizeDiffBackupPath,
+			customizeDiffLatestVersion,
+			customizeDiffMoveOnPathChange,
+			customizeDiffContentDiff,
+			customizeDiffQuarantine,
+		),
 
 		Schema: map[string]*schema.Schema{
 			"path": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "The path to the file",
+				Type:             schema.TypeString,
+				Required:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The path to the file. On Windows, a path such as 'setup.exe:Zone.Identifier' names an alternate data stream of the file. Changing it replaces the file, unless move_on_path_change is set",
+			},
+			"move_on_path_change": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Rename the file when path changes instead of replacing it, keeping its inode, ownership, timestamps and anything else not managed here. The new path must be on the same filesystem and must not exist yet",
+			},
+			"stream": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validateStreamName,
+				Description:  "The NTFS alternate data stream of the file to manage instead of its main content, such as 'Zone.Identifier'. Only supported on Windows",
 			},
 			"content": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Description:      "The content of the file",
+				Default:          "",
+				DiffSuppressFunc: diffSuppressUnstoredContent,
+			},
+			"part": partSchema(),
+			"encoding": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "utf-8",
+				ValidateFunc: validation.StringInSlice(textEncodings, false),
+				Description:  "The character encoding the content is written in and read back from: utf-8, utf-16le, utf-16be or latin-1",
+			},
+			"byte_order_mark": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Start the file with a byte order mark. Only supported with the utf-8 and utf-16 encodings",
+			},
+			"line_endings": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "none",
+				ValidateFunc: validation.StringInSlice(lineEndings, false),
+				Description:  "Convert line endings on write to lf, crlf or those of the platform Terraform runs on, and ignore the difference between them on read. none writes the content as it is",
+			},
+			"compress": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "none",
+				ValidateFunc: validation.StringInSlice(compressions, false),
+				Description:  "Compress the content on write, and decompress the file to compare it with the content on read: none or gzip",
+			},
+			"compression_level": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      6,
+				ValidateFunc: validation.IntBetween(1, 9),
+				Description:  "The gzip compression level, from 1 (fastest) to 9 (smallest)",
+			},
+			"mtime": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateFunc:     validation.IsRFC3339Time,
+				DiffSuppressFunc: diffSuppressMtime,
+				Description:      "The modification time to give the file after writing it, in RFC 3339 format. Drift is detected to the second",
+			},
+			"overwrite": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Replace a file that already exists at path when the resource is created. When false, creating the resource fails instead, so that a file maintained by hand isn't lost",
+			},
+			"follow_symlinks": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Manage the file that a symlink at path points to. Otherwise a symlink at path is an error rather than written through",
+			},
+			"manage_content": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Manage the content of the file. When false, the content is only written when the file is created and is otherwise left to something else, while permissions and ownership are still managed",
+			},
+			"create_parents": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Create missing parent directories. When false, creating a file in a missing directory fails",
+			},
+			"parent_directory_permissions": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validatePermissions,
+				StateFunc:        normalizePermissions,
+				DiffSuppressFunc: diffSuppressPermissions,
+				Description:      "Permissions of the parent directories that are created. Defaults to the provider's default_directory_permissions",
+			},
+			"append": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				ForceNew:    true,
+				Description: "Only manage content at the end of the file, leaving the rest of it alone. On update the previous content is replaced, and on destroy it is removed",
+			},
+			"store_content": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Keep the content in the state. When false, only its SHA-256 is kept and drift is detected by hashing the file. Binary content, with NUL bytes or invalid UTF-8, is never kept",
+			},
+			"sha256": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Hex-encoded SHA-256 checksum of the content, or of the encrypted file when encrypt is set",
+			},
+			"md5": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Hex-encoded MD5 checksum of the content, or of the encrypted file when encrypt is set",
+			},
+			"base64sha256": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Base64-encoded SHA-256 checksum of the content, or of the encrypted file when encrypt is set",
+			},
+			"size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The size of the file on disk in bytes",
+			},
+			"applied_sha256": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The sha256 the content had when Terraform last wrote it, which tells changes made outside Terraform apart from those in the configuration",
+			},
+			"modified_outside": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the content was changed outside Terraform since it was last written",
+			},
+			"show_content_diff": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Show a unified diff of the lines that change in content_diff when planning content changes. Values of lines that look like they assign passwords, tokens or keys are redacted, and files holding private keys aren't diffed",
+			},
+			"content_diff": {
 				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The unified diff between the file and the planned content when show_content_diff is set, truncated to 200 lines. Empty once the change is applied",
+			},
+			"warn_on_modification": {
+				Type:        schema.TypeBool,
 				Optional:    true,
-				Description: "The content of the file",
-				Default:     "",
+				Default:     false,
+				Description: "Warn when the content was changed outside Terraform since it was last written, before it is overwritten",
+			},
+			"plaintext_sha256": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "Hex-encoded SHA-256 checksum of the content of an encrypted file, used to detect changes to it",
+			},
+			"encrypt": encryptSchema(),
+			"atomic": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Write to a temporary file in the same directory, flush it and rename it over the path, so that a crash never leaves a partially written file. Streams are always written in place",
+			},
+			"sync_directory": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Also flush the parent directory after an atomic write, so that the rename survives a power loss",
+			},
+			"lock":         lockSchema(),
+			"lock_timeout": lockTimeoutSchema(),
+			"validate_command": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateValidateCommand,
+				Description:  "Shell command run on the target with %s replaced by a temporary copy of the new file, such as \"visudo -cf %s\". The file is only put in place when the command succeeds",
+			},
+			"on_change_command": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Shell command run on the target after the file is created or changed, such as \"systemctl reload nginx\". FILESYSTEM_PATH, FILESYSTEM_OLD_SHA256 and FILESYSTEM_NEW_SHA256 are set in its environment",
+			},
+			"backup": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Copy the previous version of the file to backup_path before overwriting or deleting it",
+			},
+			"backup_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "Where the previous version is saved. Defaults to the path followed by backup_suffix",
+			},
+			"backup_suffix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     ".bak",
+				Description: "The suffix appended to the path when backup_path isn't set",
+			},
+			"keep_versions": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Archive the previous version of the file to versions_dir each time the content is rewritten, keeping this many of them. 0 keeps none",
+			},
+			"versions_dir": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Where earlier versions are archived, named after the file followed by the UTC time. Defaults to the directory of the file",
+			},
+			"latest_version_path": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The path of the most recently archived version",
 			},
 			"permissions": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ValidateDiagFunc: validatePermissions,
+				StateFunc:        normalizePermissions,
+				DiffSuppressFunc: diffSuppressPermissions,
+				Description:      "File permissions in octal format (e.g., '0644'). Defaults to the provider's default_file_permissions",
+			},
+			"owner": {
 				Type:        schema.TypeString,
 				Optional:    true,
-				Default:     "0644",
-				Description: "File permissions in octal format (e.g., '0644')",
+				Computed:    true,
+				Description: "The user name or numeric ID owning the file. Defaults to the provider's default_owner",
+			},
+			"group": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The group name or numeric ID owning the file. Defaults to the provider's default_group",
+			},
+			"flags": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString, ValidateFunc: validation.StringInSlice(fileFlagNameList(), false)},
+				Description: "File flags as chflags names them (e.g., ['hidden', 'uchg']): hidden, uchg, uappnd, schg, sappnd, nodump or opaque. The ones not listed are cleared. Flags that stop the file from changing, such as uchg, are lifted while Terraform changes or deletes it. Only supported on macOS and FreeBSD",
+			},
+			"strip_quarantine": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Remove the com.apple.quarantine extended attribute, which macOS adds to files written by apps that downloaded them, so that Gatekeeper lets the file run. It is removed again whenever it reappears. Only supported on macOS",
 			},
+			"quarantined": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the file has the com.apple.quarantine extended attribute. Only read when strip_quarantine is set",
+			},
+			"acl":              aclSchema(),
+			"destroy_behavior": destroyBehaviorSchema(),
 		},
 	}
 }
@@ -55,136 +994,1123 @@ func resourceDirectory() *schema.Resource {
 	return &schema.Resource{
 		CreateContext: resourceDirectoryCreate,
 		ReadContext:   resourceDirectoryRead,
+		UpdateContext: resourceDirectoryUpdate,
 		DeleteContext: resourceDirectoryDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceDirectoryImport,
+		},
+		CustomizeDiff: customdiff.All(
+			customizeDiffDefaults(providerDefaults.dirPerm),
+			customizeDiffStatistics,
+			customizeDiffUnmanaged,
+		),
 
 		Schema: map[string]*schema.Schema{
 			"path": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "The path to the directory",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The path to the directory",
 			},
 			"permissions": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validatePermissions,
+				StateFunc:        normalizePermissions,
+				DiffSuppressFunc: diffSuppressPermissions,
+				Description:      "Directory permissions in octal format (e.g., '0755'). Defaults to the provider's default_directory_permissions",
+			},
+			"owner": {
 				Type:        schema.TypeString,
 				Optional:    true,
-				Default:     "0755",
-				ForceNew:    true,
-				Description: "Directory permissions in octal format (e.g., '0755')",
+				Computed:    true,
+				Description: "The user name or numeric ID owning the directory. Defaults to the provider's default_owner",
+			},
+			"group": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The group name or numeric ID owning the directory. Defaults to the provider's default_group",
+			},
+			"force_destroy": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Delete the directory on destroy even if it isn't empty. Otherwise, destroying a directory that holds anything but excludes fails",
+			},
+			"recursive": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Apply file_permissions and directory_permissions to everything below the directory, and report drift when any of it deviates",
+			},
+			"file_permissions": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateModeSpec,
+				Description:  "Permissions of the files below the directory when recursive is set, in octal (e.g., '0644') or symbolic chmod format (e.g., 'u=rwX,go=rX')",
+			},
+			"directory_permissions": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateModeSpec,
+				Description:  "Permissions of the directories below the directory when recursive is set, in octal (e.g., '0755') or symbolic chmod format",
 			},
+			"excludes": excludesSchema("Gitignore-style patterns, relative to the directory, of runtime files such as logs, sockets and caches that recursive, statistics, purge_unmanaged and the emptiness check on destroy leave out (e.g., '*.log' or 'cache/')"),
+			"purge_unmanaged": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Delete the entries of the directory that aren't listed in managed_entries or matched by excludes, along with everything below them, on every apply. Entries that appear are reported in unmanaged_entries",
+			},
+			"managed_entries": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString, ValidateFunc: validateEntryName},
+				Description: "The names of the files and directories directly in the directory that purge_unmanaged keeps, such as those of the filesystem_file resources writing to it",
+			},
+			"unmanaged_entries": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The names of the entries that purge_unmanaged deletes on the next apply",
+			},
+			"statistics": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Walk everything below the directory on every refresh to export file_count, total_size and tree_checksum. This reads every file in the tree",
+			},
+			"file_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of regular files below the directory when statistics is set",
+			},
+			"total_size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The total size in bytes of the regular files below the directory when statistics is set",
+			},
+			"tree_checksum": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "SHA-256 over the relative paths and contents of everything below the directory when statistics is set, computed like the checksum of filesystem_copy",
+			},
+			"acl":              aclSchema(),
+			"destroy_behavior": destroyBehaviorSchema(),
 		},
 	}
 }
 
-func parsePermissions(perm string) (os.FileMode, error) {
-	var mode os.FileMode
-	_, err := fmt.Sscanf(perm, "%o", &mode)
+func resourceFileImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	return importPath(ctx, d, meta, resourceFile().Schema)
+}
+
+func resourceDirectoryImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	return importPath(ctx, d, meta, resourceDirectory().Schema)
+}
+
+// importPath adopts the existing file or directory whose path is given as
+// the import ID. The rest of the attributes are filled in by Read.
+func importPath(ctx context.Context, d *schema.ResourceData, meta interface{}, s map[string]*schema.Schema) ([]*schema.ResourceData, error) {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Id()
+
+	fileInfo, err := fsys.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error importing %s: %s", path, err)
+	}
+
+	if err := d.Set("path", path); err != nil {
+		return nil, err
+	}
+
+	// Read only keeps the owner and group when they are set
+	if uid, gid, ok := fileOwner(fileInfo); ok {
+		if err := d.Set("owner", fsys.UserName(uid)); err != nil {
+			return nil, err
+		}
+		if err := d.Set("group", fsys.GroupName(gid)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Attributes left out of the configuration would otherwise show up as
+	// changes in the first plan
+	for k, v := range s {
+		if v.Default == nil {
+			continue
+		}
+		if err := d.Set(k, v.Default); err != nil {
+			return nil, err
+		}
+	}
+
+	d.SetId(pathID(fsys, path))
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func destroyBehaviorSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      "delete",
+		ValidateFunc: validation.StringInSlice([]string{"delete", "abandon"}, false),
+		Description:  "'delete' removes the path on destroy, 'abandon' only removes it from the state",
+	}
+}
+
+// diffSuppressUnstoredContent compares the configured content against the
+// hash in the state when store_content is false, or the content is binary,
+// as the state then holds no content.
+func diffSuppressUnstoredContent(k, old, new string, d *schema.ResourceData) bool {
+	if d.Id() == "" {
+		return false
+	}
+	// Files assembled from parts are compared by their checksum alone
+	if !d.Get("manage_content").(bool) || len(d.Get("part").([]interface{})) > 0 {
+		return true
+	}
+
+	codec := textCodecFromResourceData(d)
+	if d.Get("store_content").(bool) && !binaryContent(new) {
+		return codec.normalize(old) == codec.normalize(new)
+	}
+
+	// Compare with the checksum of the file, rather than the planned one that
+	// is already that of the new content when the plan is applied
+	sum, _ := d.GetChange(contentChecksumKey(d.Get("encrypt").([]interface{})))
+	hash := sha256.Sum256([]byte(codec.normalize(new)))
+	return hex.EncodeToString(hash[:]) == sum.(string)
+}
+
+func resourceFileCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Get("stream").(string) != "" && runtime.GOOS != "windows" {
+		return fmt.Errorf("stream is only supported on Windows")
+	}
+	if d.Get("flags").(*schema.Set).Len() > 0 && runtime.GOOS != "darwin" && runtime.GOOS != "freebsd" {
+		return fmt.Errorf("flags are only supported on macOS and FreeBSD")
+	}
+	if d.Get("strip_quarantine").(bool) && runtime.GOOS != "darwin" {
+		return fmt.Errorf("strip_quarantine is only supported on macOS")
+	}
+
+	appending := d.Get("append").(bool)
+	if appending && !d.Get("store_content").(bool) {
+		return fmt.Errorf("append requires store_content, as appended content is found by its value")
+	}
+
+	// Content that something else owns is never compared
+	if !d.Get("manage_content").(bool) {
+		if appending || len(d.Get("encrypt").([]interface{})) > 0 {
+			return fmt.Errorf("append and encrypt require manage_content")
+		}
+		return nil
+	}
+	if appending && len(d.Get("part").([]interface{})) > 0 {
+		return fmt.Errorf("append can't be combined with part")
+	}
+
+	// Catch content that the encoding can't represent before anything is written
+	codec := textCodec{
+		encoding:    d.Get("encoding").(string),
+		bom:         d.Get("byte_order_mark").(bool),
+		lineEndings: d.Get("line_endings").(string),
+		compress:    d.Get("compress").(string),
+		level:       d.Get("compression_level").(int),
+	}
+	if err := codec.validate(); err != nil {
+		return err
+	}
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	content, err := plannedContentFromResourceDiff(fsys, d)
 	if err != nil {
-		return 0, fmt.Errorf("invalid permission format: %s", perm)
+		return err
+	}
+	if content.known {
+		if _, err := codec.encode(content.value); err != nil {
+			return fmt.Errorf("content: %s", err)
+		}
+	}
+
+	encrypt := d.Get("encrypt").([]interface{})
+	if len(encrypt) > 0 && d.Get("store_content").(bool) {
+		return fmt.Errorf("encrypt requires store_content = false, so that the plaintext isn't kept in the state")
+	}
+
+	if err := customizeDiffContentChecksum(d, contentChecksumKey(encrypt), codec, content, appending); err != nil {
+		return err
+	}
+
+	// Encryption is never repeatable, so every rewrite changes the file.
+	// Changes to unstored content show in plaintext_sha256
+	if len(encrypt) > 0 && d.HasChanges(fileFormatKeys...) {
+		if err := d.SetNewComputed("sha256"); err != nil {
+			return err
+		}
 	}
-	return mode, nil
+
+	return customizeDiffDigests(d, codec, content, appending)
+}
+
+// customizeDiffDigests plans md5, base64sha256 and size along with sha256,
+// so that other resources can use them in the same plan. Encrypted files and
+// content produced during the apply are only known once written, as is the
+// size of a file that content is appended to.
+func customizeDiffDigests(d *schema.ResourceDiff, codec textCodec, content plannedContent, appending bool) error {
+	if !d.NewValueKnown("sha256") || !content.known {
+		for _, k := range []string{"md5", "base64sha256", "size"} {
+			if err := d.SetNewComputed(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// A checksum other than that of the content is that of an encrypted file
+	sum, sumMD5 := checksumBytes([]byte(codec.normalize(content.value)))
+	if hex.EncodeToString(sum) != d.Get("sha256").(string) {
+		return nil
+	}
+
+	for k, v := range map[string]string{
+		"md5":          hex.EncodeToString(sumMD5),
+		"base64sha256": base64.StdEncoding.EncodeToString(sum),
+	} {
+		if d.Get(k).(string) == v {
+			continue
+		}
+		if err := d.SetNew(k, v); err != nil {
+			return err
+		}
+	}
+
+	if appending {
+		if d.HasChange("sha256") {
+			return d.SetNewComputed("size")
+		}
+		return nil
+	}
+
+	data, err := codec.encode(codec.convert(content.value))
+	if err != nil || d.Get("size").(int) == len(data) {
+		return nil
+	}
+	return d.SetNew("size", len(data))
+}
+
+// checksumBytes returns the SHA-256 and MD5 of data.
+func checksumBytes(data []byte) (sha256Sum, md5Sum []byte) {
+	s := sha256.Sum256(data)
+	m := md5.Sum(data)
+	return s[:], m[:]
+}
+
+// checksumContent returns the SHA-256 and MD5 of a file, reading it once.
+func checksumContent(fsys fileSystem, path string) (sha256Sum, md5Sum []byte, err error) {
+	s, m := sha256.New(), md5.New()
+	if err := hashFile(fsys, path, io.MultiWriter(s, m)); err != nil {
+		return nil, nil, err
+	}
+	return s.Sum(nil), m.Sum(nil), nil
+}
+
+// fileFormatKeys are the attributes of filesystem_file besides the content
+// that a change to rewrites the file for.
+var fileFormatKeys = []string{
+	"plaintext_sha256", "permissions", "encoding", "byte_order_mark",
+	"line_endings", "compress", "compression_level", "encrypt",
+}
+
+// contentChecksumKey returns where the checksum of the content is kept.
+// Encrypted files can't be read back, so it's kept out of sight in
+// plaintext_sha256 and sha256 is that of the file.
+func contentChecksumKey(encrypt []interface{}) string {
+	if len(encrypt) > 0 {
+		return "plaintext_sha256"
+	}
+	return "sha256"
+}
+
+func customizeDiffContentChecksum(d *schema.ResourceDiff, key string, codec textCodec, content plannedContent, appending bool) error {
+	// Appended content that went missing, and files that can't be decoded,
+	// only show in the checksum. Parts are checked every time, as a change to
+	// a source file shows nowhere else
+	if !content.parts && !d.HasChange("content") && !appending && d.Get(key).(string) != "" {
+		return nil
+	}
+
+	// The content may be produced by another resource during the same apply
+	if !content.known {
+		return d.SetNewComputed(key)
+	}
+
+	hash := sha256.Sum256([]byte(codec.normalize(content.value)))
+	if sum := hex.EncodeToString(hash[:]); sum != d.Get(key).(string) {
+		return d.SetNew(key, sum)
+	}
+	return nil
+}
+
+// customizeDiffAppliedChecksum plans applied_sha256 as the checksum that
+// the content is written with, which also clears modified_outside.
+func customizeDiffAppliedChecksum(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" || !d.Get("manage_content").(bool) || !d.HasChange("sha256") {
+		return nil
+	}
+
+	if !d.NewValueKnown("sha256") {
+		if err := d.SetNewComputed("applied_sha256"); err != nil {
+			return err
+		}
+	} else if err := d.SetNew("applied_sha256", d.Get("sha256").(string)); err != nil {
+		return err
+	}
+	if d.Get("modified_outside").(bool) {
+		return d.SetNew("modified_outside", false)
+	}
+	return nil
+}
+
+// customizeDiffBackupPath derives backup_path from the path when it isn't
+// configured, and clears it when backup is disabled.
+func customizeDiffBackupPath(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() || !config.GetAttr("backup_path").IsNull() {
+		return nil
+	}
+
+	backupPath := ""
+	if d.Get("backup").(bool) {
+		if !d.NewValueKnown("path") || !d.NewValueKnown("backup_suffix") {
+			return d.SetNewComputed("backup_path")
+		}
+		backupPath = d.Get("path").(string) + d.Get("backup_suffix").(string)
+	}
+
+	if d.Get("backup_path").(string) == backupPath {
+		return nil
+	}
+	return d.SetNew("backup_path", backupPath)
 }
 
 func resourceFileCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	path := d.Get("path").(string)
-	content := d.Get("content").(string)
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	path := filePathFromResourceData(d)
+	codec := textCodecFromResourceData(d)
 	permStr := d.Get("permissions").(string)
 
+	content, err := contentFromResourceData(fsys, d)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error assembling content for %s: %s", path, err))
+	}
+	content = codec.convert(content)
+
 	// Parse permissions
 	perm, err := parsePermissions(permStr)
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
-	// Make sure the directory exists
-	dir := filepath.Dir(path)
-	err = os.MkdirAll(dir, 0755)
-	if err != nil {
-		return diag.FromErr(fmt.Errorf("error creating directory %s: %s", dir, err))
+	// Make sure the directory exists
+	err = makeParentsFromResourceData(fsys, d, path, conf.defaults.dirPerm())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Refuse to write through a symlink unless it's expected
+	target, err := symlinkTargetFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// A file whose content isn't managed is only written when it's missing
+	write := true
+	if !d.Get("manage_content").(bool) {
+		if _, err := fsys.Lstat(path); err == nil {
+			write = false
+		}
+	}
+
+	// Keep other programs from editing the file until it's written
+	unlock, err := lockFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer unlock()
+
+	// Refuse to replace a file that was there before
+	if write && !d.Get("overwrite").(bool) && !d.Get("append").(bool) {
+		if _, err := fsys.Lstat(path); err == nil {
+			return diag.FromErr(fmt.Errorf("file %s already exists and overwrite is false. Import it to manage it with Terraform", path))
+		}
+	}
+
+	if write {
+		// Back up a file that is already there
+		err = backupFileFromResourceData(withContext(ctx, conf.scratch), d, path, conf.defaults.dirPerm())
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error backing up file %s: %s", path, err))
+		}
+		err = archiveVersionFromResourceData(withContext(ctx, conf.scratch), d, path, conf.defaults.dirPerm())
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error archiving the previous version of file %s: %s", path, err))
+		}
+
+		// Add the content to the end of an existing file instead of replacing it
+		if d.Get("append").(bool) {
+			existing, err := readExisting(fsys, path, codec)
+			if err != nil {
+				return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+			}
+			content = appendContent(existing, content, content)
+		}
+
+		// Write the file
+		err = writeFileFromResourceData(ctx, fsys, d, path, content, perm)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error writing file %s: %s", path, err))
+		}
+	}
+	unlock()
+
+	// Set permissions explicitly in case the file already existed
+	err = fsys.Chmod(target, perm)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting permissions for file %s: %s", path, err))
+	}
+
+	// Set ownership
+	err = chownFromResourceData(fsys, d, target)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting ownership of file %s: %s", path, err))
+	}
+
+	// Set the Windows ACL
+	err = setACLFromResourceData(fsys, d, target)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting ACL of file %s: %s", path, err))
+	}
+
+	// Set the modification time last, as writing the file changes it
+	err = setMtimeFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting modification time of file %s: %s", path, err))
+	}
+
+	// Flags such as uchg go last, as they stop everything above
+	err = stripQuarantineFromResourceData(fsys, d, target)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = setFlagsFromResourceData(fsys, d, target)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting flags of file %s: %s", path, err))
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	diags := resourceFileRead(ctx, d, meta)
+	if diags.HasError() {
+		return diags
+	}
+	return append(diags, runOnChangeCommand(ctx, fsys, d, path, "")...)
+}
+
+// backupFileFromResourceData copies the file at path to backup_path when
+// backup is set, replacing any earlier backup. fsys is the filesystem
+// without the provider's backups, which would otherwise also keep the
+// earlier backup.
+func backupFileFromResourceData(fsys fileSystem, d *schema.ResourceData, path string, dirPerm os.FileMode) error {
+	if !d.Get("backup").(bool) {
+		return nil
+	}
+
+	// Nothing to back up yet. A symlink is backed up as the file it points to
+	if _, err := fsys.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	source, err := resolveSymlinks(fsys, path, true)
+	if err != nil {
+		return err
+	}
+
+	backupPath := d.Get("backup_path").(string)
+	dir := filepath.Dir(backupPath)
+	if err := fsys.MkdirAll(dir, dirPerm); err != nil {
+		return fmt.Errorf("error creating directory %s: %s", dir, err)
+	}
+
+	return copyPath(fsys, source, backupPath, copyOptions{mode: true, timestamps: true})
+}
+
+func validateStreamName(v interface{}, k string) ([]string, []error) {
+	if s := v.(string); s == "" || strings.ContainsAny(s, `:\/`) {
+		return nil, []error{fmt.Errorf("%s must be the name of a stream without a colon or separators, got %q", k, s)}
+	}
+	return nil, nil
+}
+
+// makeParentsFromResourceData creates the missing parent directories of
+// path, unless create_parents is false and they have to exist already.
+func makeParentsFromResourceData(fsys fileSystem, d *schema.ResourceData, path string, dirPerm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if !d.Get("create_parents").(bool) {
+		if info, err := fsys.Stat(dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("directory %s does not exist and create_parents is false", dir)
+		}
+		return nil
+	}
+
+	if v := d.Get("parent_directory_permissions").(string); v != "" {
+		var err error
+		if dirPerm, err = parsePermissions(v); err != nil {
+			return err
+		}
+	}
+	err := fsys.MkdirAll(dir, dirPerm)
+	if err != nil {
+		return fmt.Errorf("error creating directory %s: %s", dir, err)
+	}
+	return nil
+}
+
+// moveFileFromResourceData renames the file from its previous path to its
+// new one, keeping its inode, ownership and timestamps. A file that is in the
+// way is never replaced.
+func moveFileFromResourceData(fsys fileSystem, d *schema.ResourceData, dirPerm os.FileMode) error {
+	o, n := d.GetChange("path")
+	oldPath, path := o.(string), n.(string)
+
+	if _, err := fsys.Lstat(path); err == nil {
+		return fmt.Errorf("error moving file %s to %s: %s already exists", oldPath, path, path)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("error moving file %s to %s: %s", oldPath, path, err)
+	}
+	if err := makeParentsFromResourceData(fsys, d, path, dirPerm); err != nil {
+		return err
+	}
+
+	err := fsys.Rename(oldPath, path)
+	if err != nil {
+		return fmt.Errorf("error moving file %s to %s: %s", oldPath, path, err)
+	}
+	d.SetId(pathID(fsys, path))
+	return nil
+}
+
+// customizeDiffMoveOnPathChange replaces a file whose path changes, unless
+// move_on_path_change moves it instead. Streams are always replaced, as
+// renaming would move the file holding them.
+func customizeDiffMoveOnPathChange(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" || !d.HasChange("path") {
+		return nil
+	}
+	if d.Get("move_on_path_change").(bool) && d.Get("stream").(string) == "" {
+		return nil
+	}
+	return d.ForceNew("path")
+}
+
+// filePathFromResourceData returns the path that a filesystem_file writes
+// to: path, or the stream of it that stream names.
+func filePathFromResourceData(d *schema.ResourceData) string {
+	path := d.Get("path").(string)
+	if stream := d.Get("stream").(string); stream != "" {
+		return path + ":" + stream
+	}
+	return path
+}
+
+// symlinkTargetFromResourceData returns the path that the permissions,
+// ownership and ACL of a file are applied to. That is the file a symlink at
+// path points to when follow_symlinks is set. Otherwise a symlink at path is
+// refused, so that nothing is written through a link that something else
+// put there. A stream has those of the file holding it.
+func symlinkTargetFromResourceData(fsys fileSystem, d *schema.ResourceData, path string) (string, error) {
+	path, _ = splitStream(path)
+	if d.Get("follow_symlinks").(bool) {
+		return resolveSymlinks(fsys, path, true)
+	}
+
+	info, err := fsys.Lstat(path)
+	if err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "", symlinkError(path)
+	}
+	return path, nil
+}
+
+func symlinkError(path string) error {
+	return fmt.Errorf("path %s is a symlink, set follow_symlinks = true to manage the file it points to", path)
+}
+
+// writeFileFromResourceData writes content to path in its encoding,
+// atomically unless atomic is disabled.
+func writeFileFromResourceData(ctx context.Context, fsys fileSystem, d *schema.ResourceData, path, content string, perm os.FileMode) error {
+	codec := textCodecFromResourceData(d)
+	data, err := codec.encode(content)
+	if err != nil {
+		return err
+	}
+
+	// An encrypted file can't be read back, so the checksums of the content
+	// and of the file are recorded as it's written
+	enc, err := encryptionFromResourceData(d)
+	if err != nil {
+		return err
+	}
+	if enc != nil {
+		if data, err = enc.encrypt(data); err != nil {
+			return fmt.Errorf("error encrypting content: %s", err)
+		}
+
+		plain := sha256.Sum256([]byte(codec.normalize(content)))
+		if err := d.Set("plaintext_sha256", hex.EncodeToString(plain[:])); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		if err := d.Set("sha256", hex.EncodeToString(sum[:])); err != nil {
+			return err
+		}
+	}
+
+	// A stream can't be renamed into place, so it's always written in place
+	check := validateCommandCheck(ctx, fsys, d.Get("validate_command").(string))
+	if _, stream := splitStream(path); stream != "" || !d.Get("atomic").(bool) {
+		// Without a rename to hold back, the content is checked in a
+		// temporary file of its own
+		if check != nil {
+			if err := checkTempFile(fsys, path, data, perm, check); err != nil {
+				return err
+			}
+		}
+		return fsys.WriteFile(path, data, perm)
+	}
+	return writeFileAtomic(fsys, path, data, perm, d.Get("sync_directory").(bool), check)
+}
+
+// setMtimeFromResourceData sets the modification time of path to mtime,
+// keeping its access time like touch -m does.
+func setMtimeFromResourceData(fsys fileSystem, d *schema.ResourceData, path string) error {
+	v := d.Get("mtime").(string)
+	if v == "" {
+		return nil
+	}
+
+	mtime, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return err
+	}
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return err
+	}
+	return fsys.Chtimes(path, fileAccessTime(info), mtime)
+}
+
+// diffSuppressMtime compares modification times to the second, as that's
+// all some targets, such as SFTP servers, keep.
+func diffSuppressMtime(k, old, new string, d *schema.ResourceData) bool {
+	o, err := time.Parse(time.RFC3339, old)
+	if err != nil {
+		return false
+	}
+	n, err := time.Parse(time.RFC3339, new)
+	return err == nil && o.Truncate(time.Second).Equal(n.Truncate(time.Second))
+}
+
+func resourceFileRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := filePathFromResourceData(d)
+
+	// Check if the file exists
+	stat := fsys.Lstat
+	if d.Get("follow_symlinks").(bool) {
+		stat = fsys.Stat
+	}
+	fileInfo, err := stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// File was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+	}
+	if fileInfo.Mode()&os.ModeSymlink != 0 {
+		return diag.FromErr(symlinkError(path))
+	}
+
+	// States upgraded before the provider was configured still have the
+	// SHA-256 of the path as their ID
+	d.SetId(pathID(fsys, path))
+
+	// Ensure it's a file, not a directory
+	if fileInfo.IsDir() {
+		return diag.FromErr(fmt.Errorf("path %s is a directory, not a file", path))
+	}
+
+	// The diff is only shown while the change is planned
+	if err := d.Set("content_diff", ""); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Read the file content, or only hash it when it isn't kept in the state.
+	// Appended content is hashed where it should be, at the end of the file.
+	// Unless the file is encrypted, the checksum is of the decoded content
+	var sum, sumMD5 []byte
+	codec := textCodecFromResourceData(d)
+	if !d.Get("manage_content").(bool) {
+		// Content that isn't managed stays out of the state, and is only hashed
+		sum, sumMD5, err = checksumContent(fsys, path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+		}
+	} else if d.Get("append").(bool) {
+		existing, err := readExisting(fsys, path, codec)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+		}
+
+		tail := appendedTail(existing, codec.convert(d.Get("content").(string)))
+		sum, sumMD5 = checksumBytes([]byte(codec.normalize(tail)))
+	} else if len(d.Get("encrypt").([]interface{})) > 0 {
+		sum, sumMD5, err = checksumContent(fsys, path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+		}
+
+		// The content can't be checked, so a file that changed in any way is
+		// taken to no longer hold it
+		if hex.EncodeToString(sum) != d.Get("sha256").(string) {
+			if err := d.Set("plaintext_sha256", ""); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+
+		if err := d.Set("content", ""); err != nil {
+			return diag.FromErr(err)
+		}
+	} else if d.Get("store_content").(bool) || !codec.raw() {
+		data, err := fsys.ReadFile(path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+		}
+
+		// A file that can't be decoded, such as one replaced by an uncompressed
+		// copy, gets no checksum so that it shows as drift and is rewritten
+		content, err := codec.decode(data)
+		if err != nil {
+			content = string(data)
+		} else {
+			content = codec.normalize(content)
+			sum, sumMD5 = checksumBytes([]byte(content))
+		}
+
+		// Binary content is kept out of the state and compared by its checksum
+		if binaryContent(content) && d.Get("store_content").(bool) {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "Binary file content",
+				Detail:   fmt.Sprintf("%s holds binary content, which is left out of the state and compared by its SHA-256 instead. Set store_content to false to manage it without this warning", path),
+			})
+			content = ""
+		}
+		if !d.Get("store_content").(bool) {
+			content = ""
+		}
+		if err := d.Set("content", content); err != nil {
+			return diag.FromErr(err)
+		}
+	} else {
+		sum, sumMD5, err = checksumContent(fsys, path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+		}
+
+		if err := d.Set("content", ""); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	// Content that differs from what was last written was changed outside
+	// Terraform. The first read after the file is written or imported
+	// records what it holds
+	applied := d.Get("applied_sha256").(string)
+	modified := false
+	if !d.Get("manage_content").(bool) {
+		applied = ""
+	} else if applied == "" {
+		applied = hex.EncodeToString(sum)
+	} else {
+		modified = applied != hex.EncodeToString(sum)
 	}
-
-	// Write the file
-	err = os.WriteFile(path, []byte(content), perm)
-	if err != nil {
-		return diag.FromErr(fmt.Errorf("error writing file %s: %s", path, err))
+	if modified && d.Get("warn_on_modification").(bool) {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "File changed outside Terraform",
+			Detail:   fmt.Sprintf("the content of %s changed since Terraform last wrote it, and applying will overwrite the change", path),
+		})
 	}
 
-	// Generate an ID based on path
-	hash := sha256.Sum256([]byte(path))
-	d.SetId(hex.EncodeToString(hash[:]))
-
-	return resourceFileRead(ctx, d, meta)
-}
-
-func resourceFileRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	var diags diag.Diagnostics
-
-	path := d.Get("path").(string)
-
-	// Check if the file exists
-	fileInfo, err := os.Stat(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// File was deleted outside of Terraform
-			d.SetId("")
-			return diags
+	for k, v := range map[string]interface{}{
+		"sha256":           hex.EncodeToString(sum),
+		"md5":              hex.EncodeToString(sumMD5),
+		"base64sha256":     base64.StdEncoding.EncodeToString(sum),
+		"size":             int(fileInfo.Size()),
+		"applied_sha256":   applied,
+		"modified_outside": modified,
+	} {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
 		}
-		return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
 	}
 
-	// Ensure it's a file, not a directory
-	if fileInfo.IsDir() {
-		return diag.FromErr(fmt.Errorf("path %s is a directory, not a file", path))
+	// Set permissions
+	perm := formatPermissions(fileInfo.Mode())
+	if err := d.Set("permissions", perm); err != nil {
+		return diag.FromErr(err)
 	}
 
-	// Read the file content
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+	// Only track the modification time when it's managed
+	if d.Get("mtime").(string) != "" {
+		if err := d.Set("mtime", fileInfo.ModTime().UTC().Format(time.RFC3339Nano)); err != nil {
+			return diag.FromErr(err)
+		}
 	}
 
-	if err := d.Set("content", string(content)); err != nil {
+	// Flags and the quarantine attribute are only tracked when managed too
+	if err := readFlagsIntoResourceData(d, fileInfo); err != nil {
 		return diag.FromErr(err)
 	}
+	if d.Get("strip_quarantine").(bool) {
+		value, err := fsys.GetXattr(path, quarantineXattr)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading %s of %s: %s", quarantineXattr, path, err))
+		}
+		if err := d.Set("quarantined", value != nil); err != nil {
+			return diag.FromErr(err)
+		}
+	}
 
-	// Set permissions
-	perm := fmt.Sprintf("%04o", fileInfo.Mode().Perm())
-	if err := d.Set("permissions", perm); err != nil {
-		return diag.FromErr(err)
+	if diags := readOwnershipIntoResourceData(fsys, d, fileInfo); diags.HasError() {
+		return diags
 	}
 
-	return diags
+	file, _ := splitStream(path)
+	return append(diags, readACLIntoResourceData(fsys, d, file)...)
 }
 
 func resourceFileUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	path := d.Get("path").(string)
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+
+	// Flags such as uchg would stop the file from changing at all
+	oldPath, _ := d.GetChange("path")
+	if err := liftFlagsFromResourceData(fsys, d, oldPath.(string)); err != nil {
+		return diag.FromErr(fmt.Errorf("error lifting flags of file %s: %s", oldPath, err))
+	}
+
+	if d.HasChange("path") {
+		if err := moveFileFromResourceData(fsys, d, conf.defaults.dirPerm()); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	path := filePathFromResourceData(d)
+
+	// The path may have been replaced by a symlink since it was read
+	target, err := symlinkTargetFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Changes to how the file is watched don't run on_change_command
+	changed := d.HasChangesExcept(onChangeKeys...)
+	oldSHA256, _ := d.GetChange("sha256")
 
-	if d.HasChange("content") || d.HasChange("permissions") {
-		content := d.Get("content").(string)
+	manage := d.Get("manage_content").(bool)
+	rewrite := manage && (d.HasChanges("content", "sha256") || d.HasChanges(fileFormatKeys...))
+	if rewrite {
+		codec := textCodecFromResourceData(d)
 		permStr := d.Get("permissions").(string)
 
+		content, err := contentFromResourceData(fsys, d)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error assembling content for %s: %s", path, err))
+		}
+		content = codec.convert(content)
+
 		// Parse permissions
 		perm, err := parsePermissions(permStr)
 		if err != nil {
 			return diag.FromErr(err)
 		}
 
+		unlock, err := lockFromResourceData(fsys, d, path)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		defer unlock()
+
+		// Replace the previously appended content, as it was written
+		if d.Get("append").(bool) {
+			old, _ := d.GetChange("content")
+			oldEndings, _ := d.GetChange("line_endings")
+			oldCodec := codec
+			oldCodec.lineEndings = oldEndings.(string)
+
+			existing, err := readExisting(fsys, path, codec)
+			if err != nil {
+				return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+			}
+			content = appendContent(existing, oldCodec.convert(old.(string)), content)
+		}
+
+		err = backupFileFromResourceData(withContext(ctx, conf.scratch), d, path, conf.defaults.dirPerm())
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error backing up file %s: %s", path, err))
+		}
+		err = archiveVersionFromResourceData(withContext(ctx, conf.scratch), d, path, conf.defaults.dirPerm())
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error archiving the previous version of file %s: %s", path, err))
+		}
+
 		// Write the file with new content and/or permissions
-		err = os.WriteFile(path, []byte(content), perm)
+		err = writeFileFromResourceData(ctx, fsys, d, path, content, perm)
 		if err != nil {
 			return diag.FromErr(fmt.Errorf("error writing file %s: %s", path, err))
 		}
+		unlock()
+
+		// Record the new content as the one that was applied
+		if err := d.Set("applied_sha256", ""); err != nil {
+			return diag.FromErr(err)
+		}
+
+		err = fsys.Chmod(target, perm)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting permissions for file %s: %s", path, err))
+		}
+	} else if !manage && d.HasChange("permissions") {
+		perm, err := parsePermissions(d.Get("permissions").(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		err = fsys.Chmod(target, perm)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting permissions for file %s: %s", path, err))
+		}
+	}
+
+	if d.HasChanges("owner", "group") {
+		err := chownFromResourceData(fsys, d, target)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting ownership of file %s: %s", path, err))
+		}
+	}
+
+	if d.HasChange("acl") {
+		err := setACLFromResourceData(fsys, d, target)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting ACL of file %s: %s", path, err))
+		}
+	}
+
+	if rewrite || d.HasChange("mtime") {
+		err := setMtimeFromResourceData(fsys, d, path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting modification time of file %s: %s", path, err))
+		}
+	}
+
+	err = stripQuarantineFromResourceData(fsys, d, target)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = setFlagsFromResourceData(fsys, d, target)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting flags of file %s: %s", path, err))
 	}
 
-	return resourceFileRead(ctx, d, meta)
+	diags := resourceFileRead(ctx, d, meta)
+	if diags.HasError() || !changed {
+		return diags
+	}
+	return append(diags, runOnChangeCommand(ctx, fsys, d, path, oldSHA256.(string))...)
 }
 
 func resourceFileDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	var diags diag.Diagnostics
 
-	path := d.Get("path").(string)
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	path := filePathFromResourceData(d)
+
+	// Leave the file in place
+	if d.Get("destroy_behavior").(string) == "abandon" {
+		d.SetId("")
+		return diags
+	}
+
+	unlock, err := lockFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer unlock()
+
+	err = liftFlagsFromResourceData(fsys, d, path)
+	if err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("error lifting flags of file %s: %s", path, err))
+	}
+
+	err = backupFileFromResourceData(withContext(ctx, conf.scratch), d, path, conf.defaults.dirPerm())
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error backing up file %s: %s", path, err))
+	}
+
+	// Only take the appended content out of the file
+	if d.Get("append").(bool) {
+		if _, err := symlinkTargetFromResourceData(fsys, d, path); err != nil {
+			return diag.FromErr(err)
+		}
+
+		codec := textCodecFromResourceData(d)
+		existing, err := readExisting(fsys, path, codec)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+		}
+
+		content := codec.convert(d.Get("content").(string))
+		if remaining := removeAppended(existing, content); remaining != existing {
+			// The file exists, so its mode is kept and perm goes unused
+			err = writeFileFromResourceData(ctx, fsys, d, path, remaining, 0)
+			if err != nil {
+				return diag.FromErr(fmt.Errorf("error writing file %s: %s", path, err))
+			}
+		}
+
+		d.SetId("")
+		return diags
+	}
 
 	// Delete the file
-	err := os.Remove(path)
+	err = fsys.Remove(path)
 	if err != nil && !os.IsNotExist(err) {
 		return diag.FromErr(fmt.Errorf("error deleting file %s: %s", path, err))
 	}
@@ -196,6 +2122,7 @@ func resourceFileDelete(ctx context.Context, d *schema.ResourceData, meta interf
 }
 
 func resourceDirectoryCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
 	path := d.Get("path").(string)
 	permStr := d.Get("permissions").(string)
 
@@ -206,20 +2133,45 @@ func resourceDirectoryCreate(ctx context.Context, d *schema.ResourceData, meta i
 	}
 
 	// Create the directory
-	err = os.MkdirAll(path, perm)
+	err = fsys.MkdirAll(path, perm)
 	if err != nil {
 		return diag.FromErr(fmt.Errorf("error creating directory %s: %s", path, err))
 	}
 
 	// Set permissions explicitly in case MkdirAll didn't set them correctly
-	err = os.Chmod(path, perm)
+	err = fsys.Chmod(path, perm)
 	if err != nil {
 		return diag.FromErr(fmt.Errorf("error setting permissions for directory %s: %s", path, err))
 	}
 
-	// Generate an ID based on path
-	hash := sha256.Sum256([]byte(path))
-	d.SetId(hex.EncodeToString(hash[:]))
+	// Set permissions of an existing tree below the directory
+	if d.Get("recursive").(bool) {
+		err = chmodTreeFromResourceData(fsys, d, path, meta.(*providerConfig).parallelism)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting permissions below directory %s: %s", path, err))
+		}
+	}
+
+	// Set ownership
+	err = chownFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting ownership of directory %s: %s", path, err))
+	}
+
+	// Set the Windows ACL
+	err = setACLFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting ACL of directory %s: %s", path, err))
+	}
+
+	// Delete what an existing directory holds that isn't managed
+	err = purgeUnmanagedFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
 
 	return resourceDirectoryRead(ctx, d, meta)
 }
@@ -227,10 +2179,11 @@ func resourceDirectoryCreate(ctx context.Context, d *schema.ResourceData, meta i
 func resourceDirectoryRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	var diags diag.Diagnostics
 
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
 	path := d.Get("path").(string)
 
 	// Check if the directory exists
-	fileInfo, err := os.Stat(path)
+	fileInfo, err := fsys.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Directory was deleted outside of Terraform
@@ -240,27 +2193,300 @@ func resourceDirectoryRead(ctx context.Context, d *schema.ResourceData, meta int
 		return diag.FromErr(fmt.Errorf("error reading directory %s: %s", path, err))
 	}
 
+	// States upgraded before the provider was configured still have the
+	// SHA-256 of the path as their ID
+	d.SetId(pathID(fsys, path))
+
 	// Ensure it's a directory, not a file
 	if !fileInfo.IsDir() {
 		return diag.FromErr(fmt.Errorf("path %s is a file, not a directory", path))
 	}
 
 	// Set permissions
-	perm := fmt.Sprintf("%04o", fileInfo.Mode().Perm())
+	perm := formatPermissions(fileInfo.Mode())
 	if err := d.Set("permissions", perm); err != nil {
 		return diag.FromErr(err)
 	}
 
-	return diags
+	if d.Get("recursive").(bool) {
+		if diags := readTreeModesIntoResourceData(fsys, d, path); diags.HasError() {
+			return diags
+		}
+	}
+
+	if diags := readStatisticsIntoResourceData(fsys, d, path, meta.(*providerConfig).parallelism); diags.HasError() {
+		return diags
+	}
+
+	unmanaged, err := unmanagedEntriesFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading directory %s: %s", path, err))
+	}
+	if err := d.Set("unmanaged_entries", unmanaged); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if diags := readOwnershipIntoResourceData(fsys, d, fileInfo); diags.HasError() {
+		return diags
+	}
+
+	return readACLIntoResourceData(fsys, d, path)
+}
+
+// readStatisticsIntoResourceData records the number, total size and
+// checksum of what is below the directory, or clears them when statistics
+// isn't set.
+func readStatisticsIntoResourceData(fsys fileSystem, d *schema.ResourceData, path string, parallelism int) diag.Diagnostics {
+	values := map[string]interface{}{
+		"file_count":    0,
+		"total_size":    0,
+		"tree_checksum": "",
+	}
+
+	if d.Get("statistics").(bool) {
+		excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		files, size, err := treeSize(fsys, path, excludes)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading directory %s: %s", path, err))
+		}
+		checksum, err := checksumPath(fsys, path, excludes, parallelism)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error computing checksum of %s: %s", path, err))
+		}
+
+		values["file_count"] = files
+		values["total_size"] = int(size)
+		values["tree_checksum"] = checksum
+	}
+
+	for k, v := range values {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return nil
+}
+
+func validateEntryName(v interface{}, k string) ([]string, []error) {
+	name := v.(string)
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return nil, []error{fmt.Errorf("%s must be the name of an entry without directories, got %q", k, name)}
+	}
+	return nil, nil
+}
+
+// unmanagedEntriesFromResourceData returns the names of the entries of the
+// directory that purge_unmanaged deletes, in lexical order. None are when
+// it isn't set.
+func unmanagedEntriesFromResourceData(fsys fileSystem, d *schema.ResourceData, path string) ([]string, error) {
+	unmanaged := []string{}
+	if !d.Get("purge_unmanaged").(bool) {
+		return unmanaged, nil
+	}
+
+	excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+	if err != nil {
+		return nil, err
+	}
+	managed := d.Get("managed_entries").(*schema.Set)
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !managed.Contains(entry.Name()) && !excludes.match(entry.Name(), entry.IsDir()) {
+			unmanaged = append(unmanaged, entry.Name())
+		}
+	}
+	return unmanaged, nil
+}
+
+// purgeUnmanagedFromResourceData deletes the entries of the directory that
+// are neither managed nor excluded.
+func purgeUnmanagedFromResourceData(fsys fileSystem, d *schema.ResourceData, path string) error {
+	unmanaged, err := unmanagedEntriesFromResourceData(fsys, d, path)
+	if err != nil {
+		return err
+	}
+	for _, name := range unmanaged {
+		child := filepath.Join(path, name)
+		if err := fsys.RemoveAll(child); err != nil {
+			return fmt.Errorf("error deleting %s: %s", child, err)
+		}
+	}
+	return nil
+}
+
+// customizeDiffUnmanaged plans deleting the entries that appeared in a
+// directory with purge_unmanaged since it was applied.
+func customizeDiffUnmanaged(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" || !d.Get("purge_unmanaged").(bool) {
+		return nil
+	}
+	if len(d.Get("unmanaged_entries").([]interface{})) == 0 && !d.HasChanges("managed_entries", "excludes") {
+		return nil
+	}
+	return d.SetNew("unmanaged_entries", []string{})
+}
+
+// customizeDiffStatistics leaves the statistics of a directory unknown
+// until it has been walked when statistics is turned on or what it leaves
+// out changes.
+func customizeDiffStatistics(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if !d.HasChange("statistics") && !(d.Get("statistics").(bool) && d.HasChange("excludes")) {
+		return nil
+	}
+	for _, k := range []string{"file_count", "total_size", "tree_checksum"} {
+		if err := d.SetNewComputed(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// treeModesFromResourceData parses file_permissions and
+// directory_permissions, returning nil for those that aren't set, along
+// with the excludes they don't apply to.
+func treeModesFromResourceData(d *schema.ResourceData) (files, dirs *modeSpec, excludes *excludeMatcher, err error) {
+	if v := d.Get("file_permissions").(string); v != "" {
+		if files, err = parseModeSpec(v); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if v := d.Get("directory_permissions").(string); v != "" {
+		if dirs, err = parseModeSpec(v); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if excludes, err = newExcludeMatcher(d.Get("excludes").([]interface{})); err != nil {
+		return nil, nil, nil, err
+	}
+	return files, dirs, excludes, nil
+}
+
+// chmodTreeFromResourceData is chmod -R with file_permissions and
+// directory_permissions, leaving the directory itself alone.
+func chmodTreeFromResourceData(fsys fileSystem, d *schema.ResourceData, path string, parallelism int) error {
+	files, dirs, excludes, err := treeModesFromResourceData(d)
+	if err != nil {
+		return err
+	}
+
+	pool := newWorkerPool(parallelism)
+	err = walkModes(fsys, path, files, dirs, excludes, func(child string, isDir bool, current, wanted os.FileMode) error {
+		if current == wanted {
+			return nil
+		}
+		return chmodInPool(fsys, pool, child, isDir, wanted)
+	})
+	if werr := pool.Wait(); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// readTreeModesIntoResourceData reports drift below the directory by
+// recording the permissions of the first file or directory that deviates
+// in place of the configured ones.
+func readTreeModesIntoResourceData(fsys fileSystem, d *schema.ResourceData, path string) diag.Diagnostics {
+	files, dirs, excludes, err := treeModesFromResourceData(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	drift := map[string]string{}
+	err = walkModes(fsys, path, files, dirs, excludes, func(child string, isDir bool, current, wanted os.FileMode) error {
+		k := "file_permissions"
+		if isDir {
+			k = "directory_permissions"
+		}
+		if _, ok := drift[k]; !ok && current != wanted {
+			drift[k] = formatPermissions(current)
+		}
+		return nil
+	})
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading permissions below directory %s: %s", path, err))
+	}
+
+	for k, v := range drift {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return nil
+}
+
+func resourceDirectoryUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	if d.HasChanges("recursive", "file_permissions", "directory_permissions", "excludes") && d.Get("recursive").(bool) {
+		err := chmodTreeFromResourceData(fsys, d, path, meta.(*providerConfig).parallelism)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting permissions below directory %s: %s", path, err))
+		}
+	}
+
+	if d.HasChanges("owner", "group") {
+		err := chownFromResourceData(fsys, d, path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting ownership of directory %s: %s", path, err))
+		}
+	}
+
+	if d.HasChange("acl") {
+		err := setACLFromResourceData(fsys, d, path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting ACL of directory %s: %s", path, err))
+		}
+	}
+
+	err := purgeUnmanagedFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceDirectoryRead(ctx, d, meta)
 }
 
 func resourceDirectoryDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	var diags diag.Diagnostics
 
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
 	path := d.Get("path").(string)
 
+	// Leave the directory in place
+	if d.Get("destroy_behavior").(string) == "abandon" {
+		d.SetId("")
+		return diags
+	}
+
+	// Anything still in the directory wasn't created by a resource that has
+	// already been destroyed, so only delete it when asked to. Runtime files
+	// that are excluded go with the directory
+	if !d.Get("force_destroy").(bool) {
+		excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		entries, err := fsys.ReadDir(path)
+		if err != nil && !os.IsNotExist(err) {
+			return diag.FromErr(fmt.Errorf("error reading directory %s: %s", path, err))
+		}
+		for _, entry := range entries {
+			if !excludes.match(entry.Name(), entry.IsDir()) {
+				return diag.FromErr(fmt.Errorf("directory %s is not empty, set force_destroy = true to delete it along with its contents", path))
+			}
+		}
+	}
+
 	// Delete the directory
-	err := os.RemoveAll(path)
+	err := fsys.RemoveAll(path)
 	if err != nil {
 		return diag.FromErr(fmt.Errorf("error deleting directory %s: %s", path, err))
 	}
@@ -269,4 +2495,4 @@ func resourceDirectoryDelete(ctx context.Context, d *schema.ResourceData, meta i
 	d.SetId("")
 
 	return diags
-}
\ No newline at end of file
+}
@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+)
+
+func TestProvider(t *testing.T) {
+	if err := New().InternalValidate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestProviderSchema fetches the schema the way Terraform does when it loads
+// the provider, which requires both halves of the mux to agree on it.
+func TestProviderSchema(t *testing.T) {
+	ctx := context.Background()
+	server, err := NewServer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := server().GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range resp.Diagnostics {
+		t.Errorf("%s: %s", d.Summary, d.Detail)
+	}
+}
+
+// TestProviderLockedPaths checks that no resource goes unlocked because it
+// was left out of lockedPaths.
+func TestProviderLockedPaths(t *testing.T) {
+	for name := range New().ResourcesMap {
+		if lockedPaths[name] == nil {
+			t.Errorf("%s has no locked paths", name)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// btrfsSubvolumeInode is the inode number of the root directory of every
+// btrfs subvolume.
+const btrfsSubvolumeInode = 256
+
+func resourceBtrfsSubvolume() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceBtrfsSubvolumeCreate,
+		ReadContext:   resourceBtrfsSubvolumeRead,
+		UpdateContext: resourceBtrfsSubvolumeUpdate,
+		DeleteContext: resourceBtrfsSubvolumeDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The path of the subvolume, on a mounted btrfs filesystem",
+			},
+			"snapshot_of": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "A subvolume to create the subvolume as a snapshot of. Otherwise it's created empty",
+			},
+			"read_only": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Make the subvolume read-only, as snapshots used for backups or sending usually are",
+			},
+			"qgroups": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringMatch(regexp.MustCompile(`^[0-9]+/[0-9]+$`), "must be a qgroup ID such as '1/100'"),
+				},
+				Description: "Quota groups (e.g., '1/100') to assign the subvolume to, which requires quotas enabled on the filesystem",
+			},
+			"force_destroy": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Delete the subvolume on destroy even if it isn't empty. Otherwise, deleting a subvolume that isn't empty fails",
+			},
+			"subvolume_id": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The ID of the subvolume. Its own qgroup is '0/<subvolume_id>'",
+			},
+		},
+	}
+}
+
+// btrfsSubvolumeID returns the ID of the subvolume at path.
+func btrfsSubvolumeID(ctx context.Context, fsys fileSystem, path string) (int, error) {
+	target, err := fsys.Resolve(path)
+	if err != nil {
+		return 0, err
+	}
+	out, err := fsys.Probe(ctx, commandLine(fsys, "btrfs", "inspect-internal", "rootid", target))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(out))
+}
+
+// setBtrfsReadOnly sets the read-only property of the subvolume at path.
+func setBtrfsReadOnly(ctx context.Context, fsys fileSystem, path string, readOnly bool) error {
+	target, err := fsys.Resolve(path)
+	if err != nil {
+		return err
+	}
+	_, err = fsys.Run(ctx, commandLine(fsys, "btrfs", "property", "set", "-ts", target, "ro", strconv.FormatBool(readOnly)))
+	return err
+}
+
+func resourceBtrfsSubvolumeCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+	source := d.Get("snapshot_of").(string)
+
+	// The parent must be on btrfs already, so it's never created
+	if dir := filepath.Dir(path); dir != path {
+		if info, err := fsys.Stat(dir); err != nil || !info.IsDir() {
+			return diag.FromErr(fmt.Errorf("directory %s does not exist", dir))
+		}
+	}
+	if source != "" {
+		if _, err := fsys.Stat(source); err != nil {
+			return diag.FromErr(fmt.Errorf("error reading subvolume %s: %s", source, err))
+		}
+	}
+
+	// Create the subvolume, assigned to its qgroups from the start
+	args := []string{"btrfs", "subvolume", "create"}
+	if source != "" {
+		args = []string{"btrfs", "subvolume", "snapshot"}
+		if d.Get("read_only").(bool) {
+			args = append(args, "-r")
+		}
+	}
+	for _, v := range d.Get("qgroups").(*schema.Set).List() {
+		args = append(args, "-i", v.(string))
+	}
+	if source != "" {
+		target, err := fsys.Resolve(source)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading subvolume %s: %s", source, err))
+		}
+		args = append(args, target)
+	}
+	target, err := fsys.Resolve(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error creating subvolume %s: %s", path, err))
+	}
+	args = append(args, target)
+
+	_, err = fsys.Run(ctx, commandLine(fsys, args...))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error creating subvolume %s: %s", path, err))
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	// Snapshots are made read-only as they are taken, which keeps them
+	// consistent for btrfs send. Other subvolumes are made read-only
+	// afterwards
+	if source == "" && d.Get("read_only").(bool) {
+		if err := setBtrfsReadOnly(ctx, fsys, path, true); err != nil {
+			return diag.FromErr(fmt.Errorf("error making subvolume %s read-only: %s", path, err))
+		}
+	}
+
+	return resourceBtrfsSubvolumeRead(ctx, d, meta)
+}
+
+func resourceBtrfsSubvolumeRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Check if the subvolume exists
+	info, err := fsys.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Subvolume was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading subvolume %s: %s", path, err))
+	}
+	if ino, ok := fileInode(info); !info.IsDir() || (ok && ino != btrfsSubvolumeInode) {
+		return diag.FromErr(fmt.Errorf("path %s is not a btrfs subvolume", path))
+	}
+
+	id, err := btrfsSubvolumeID(ctx, fsys, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading subvolume %s: %s", path, err))
+	}
+	target, err := fsys.Resolve(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading subvolume %s: %s", path, err))
+	}
+	out, err := fsys.Probe(ctx, commandLine(fsys, "btrfs", "property", "get", "-ts", target, "ro"))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading subvolume %s: %s", path, err))
+	}
+
+	if err := d.Set("subvolume_id", id); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("read_only", strings.TrimSpace(out) == "ro=true"); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Without quotas enabled there are no qgroups to show, which is only an
+	// error when some are configured
+	qgroup := fmt.Sprintf("0/%d", id)
+	out, err = fsys.Probe(ctx, commandLine(fsys, "btrfs", "qgroup", "show", "-pc", target))
+	if err != nil {
+		if d.Get("qgroups").(*schema.Set).Len() == 0 {
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading qgroups of subvolume %s: %s", path, err))
+	}
+	parents, err := parseQgroupParents(out, qgroup)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading qgroups of subvolume %s: %s", path, err))
+	}
+	if err := d.Set("qgroups", parents); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
+
+// parseQgroupParents returns the parents of qgroup in the output of
+// "btrfs qgroup show -pc", whose columns differ between versions of
+// btrfs-progs and are found by their header.
+func parseQgroupParents(out, qgroup string) ([]string, error) {
+	lines := splitLines(out)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("unexpected btrfs qgroup show output %q", out)
+	}
+	parent := -1
+	for i, name := range strings.Fields(lines[0]) {
+		if strings.EqualFold(name, "parent") {
+			parent = i
+		}
+	}
+	if parent < 0 {
+		return nil, fmt.Errorf("unexpected btrfs qgroup show output %q", out)
+	}
+
+	parents := []string{}
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) <= parent || fields[0] != qgroup {
+			continue
+		}
+		// Qgroups without parents show dashes
+		for _, p := range strings.Split(fields[parent], ",") {
+			if strings.Trim(p, "-") != "" {
+				parents = append(parents, p)
+			}
+		}
+	}
+	return parents, nil
+}
+
+func resourceBtrfsSubvolumeUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	if d.HasChange("qgroups") {
+		target, err := fsys.Resolve(path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading subvolume %s: %s", path, err))
+		}
+		qgroup := fmt.Sprintf("0/%d", d.Get("subvolume_id").(int))
+		o, n := d.GetChange("qgroups")
+		for _, v := range o.(*schema.Set).Difference(n.(*schema.Set)).List() {
+			_, err := fsys.Run(ctx, commandLine(fsys, "btrfs", "qgroup", "remove", qgroup, v.(string), target))
+			if err != nil {
+				return diag.FromErr(fmt.Errorf("error removing subvolume %s from qgroup %s: %s", path, v, err))
+			}
+		}
+		for _, v := range n.(*schema.Set).Difference(o.(*schema.Set)).List() {
+			_, err := fsys.Run(ctx, commandLine(fsys, "btrfs", "qgroup", "assign", qgroup, v.(string), target))
+			if err != nil {
+				return diag.FromErr(fmt.Errorf("error assigning subvolume %s to qgroup %s: %s", path, v, err))
+			}
+		}
+	}
+
+	if d.HasChange("read_only") {
+		err := setBtrfsReadOnly(ctx, fsys, path, d.Get("read_only").(bool))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting read-only property of subvolume %s: %s", path, err))
+		}
+	}
+
+	return resourceBtrfsSubvolumeRead(ctx, d, meta)
+}
+
+func resourceBtrfsSubvolumeDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	if _, err := fsys.Lstat(path); os.IsNotExist(err) {
+		d.SetId("")
+		return diags
+	}
+
+	// Deleting a subvolume deletes everything in it
+	if !d.Get("force_destroy").(bool) {
+		entries, err := fsys.ReadDir(path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading subvolume %s: %s", path, err))
+		}
+		if len(entries) > 0 {
+			return diag.FromErr(fmt.Errorf("subvolume %s is not empty, set force_destroy = true to delete it along with its contents", path))
+		}
+	}
+
+	target, err := fsys.Resolve(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error deleting subvolume %s: %s", path, err))
+	}
+	_, err = fsys.Run(ctx, commandLine(fsys, "btrfs", "subvolume", "delete", target))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error deleting subvolume %s: %s", path, err))
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceCapability() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceCapabilityCreate,
+		ReadContext:   resourceCapabilityRead,
+		UpdateContext: resourceCapabilityUpdate,
+		DeleteContext: resourceCapabilityDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The path to an existing file whose capabilities are managed",
+			},
+			"capabilities": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateFunc:     validateCapabilities,
+				DiffSuppressFunc: diffSuppressCapabilities,
+				Description:      "Linux file capabilities in the format of setcap (e.g., 'cap_net_bind_service=+ep'). They are removed on destroy",
+			},
+		},
+	}
+}
+
+// setCapabilitiesFromResourceData writes the capabilities of a file, or
+// removes them when there are none.
+func setCapabilitiesFromResourceData(fsys fileSystem, d *schema.ResourceData, path string) error {
+	caps, err := parseCapabilities(d.Get("capabilities").(string))
+	if err != nil {
+		return err
+	}
+	if caps == (fileCapabilities{}) {
+		return fsys.RemoveXattr(path, capabilityXattr)
+	}
+	return fsys.SetXattr(path, capabilityXattr, caps.encode())
+}
+
+func resourceCapabilityCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// The file must already exist, as this resource never creates it
+	if _, err := fsys.Stat(path); err != nil {
+		return diag.FromErr(fmt.Errorf("error reading %s: %s", path, err))
+	}
+
+	err := setCapabilitiesFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting capabilities of %s: %s", path, err))
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	return resourceCapabilityRead(ctx, d, meta)
+}
+
+func resourceCapabilityRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Check if the file exists
+	if _, err := fsys.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			// File was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading %s: %s", path, err))
+	}
+
+	value, err := fsys.GetXattr(path, capabilityXattr)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading capabilities of %s: %s", path, err))
+	}
+	var current fileCapabilities
+	if value != nil {
+		if current, err = decodeCapabilities(value); err != nil {
+			return diag.FromErr(fmt.Errorf("error reading capabilities of %s: %s", path, err))
+		}
+	}
+
+	// Keep the capabilities as configured while they match, as there are
+	// many ways to write the same ones
+	if wanted, err := parseCapabilities(d.Get("capabilities").(string)); err != nil || wanted != current {
+		if err := d.Set("capabilities", current.String()); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
+
+func resourceCapabilityUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	if d.HasChange("capabilities") {
+		err := setCapabilitiesFromResourceData(fsys, d, path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting capabilities of %s: %s", path, err))
+		}
+	}
+
+	return resourceCapabilityRead(ctx, d, meta)
+}
+
+func resourceCapabilityDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Remove the capabilities, leaving the file in place
+	err := fsys.RemoveXattr(path, capabilityXattr)
+	if err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("error removing capabilities of %s: %s", path, err))
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
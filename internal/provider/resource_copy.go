@@ -0,0 +1,570 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceCopy() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceCopyCreate,
+		ReadContext:   resourceCopyRead,
+		UpdateContext: resourceCopyUpdate,
+		DeleteContext: resourceCopyDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+		CustomizeDiff: resourceCopyCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"source": {
+				Type:             schema.TypeString,
+				Required:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The file or directory to copy",
+			},
+			"destination": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The path the source is copied to",
+			},
+			"preserve_mode": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Copy the permission bits of the source",
+			},
+			"preserve_ownership": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Copy the owner and group of the source (usually requires root)",
+			},
+			"preserve_timestamps": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Copy the access and modification times of the source",
+			},
+			"strip_quarantine": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Remove the com.apple.quarantine extended attribute from everything copied, so that Gatekeeper lets downloaded binaries run. Only supported on macOS",
+			},
+			"excludes": excludesSchema("Gitignore-style patterns, relative to the source and destination, of what isn't copied, hashed or deleted, such as logs and caches written into the copy (e.g., '*.log' or 'cache/')"),
+			"checksum": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "SHA-256 checksum of the copied content",
+			},
+			"size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The total size in bytes of the regular files copied",
+			},
+		},
+	}
+}
+
+type copyOptions struct {
+	mode       bool
+	ownership  bool
+	timestamps bool
+
+	// Permissions used when the mode isn't preserved
+	filePerm os.FileMode
+	dirPerm  os.FileMode
+
+	// How many files are copied at once
+	parallelism int
+
+	// What below a directory isn't copied
+	excludes *excludeMatcher
+}
+
+func copyOptionsFromResourceData(d *schema.ResourceData, conf *providerConfig) (copyOptions, error) {
+	excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+	if err != nil {
+		return copyOptions{}, err
+	}
+	return copyOptions{
+		mode:        d.Get("preserve_mode").(bool),
+		ownership:   d.Get("preserve_ownership").(bool),
+		timestamps:  d.Get("preserve_timestamps").(bool),
+		filePerm:    conf.defaults.filePerm(),
+		dirPerm:     conf.defaults.dirPerm(),
+		parallelism: conf.parallelism,
+		excludes:    excludes,
+	}, nil
+}
+
+func resourceCopyCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	source := d.Get("source").(string)
+	destination := d.Get("destination").(string)
+
+	// Make sure the parent directory exists
+	dir := filepath.Dir(destination)
+	err := fsys.MkdirAll(dir, conf.defaults.dirPerm())
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error creating directory %s: %s", dir, err))
+	}
+
+	opts, err := copyOptionsFromResourceData(d, conf)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Copy the source
+	err = copyPath(fsys, source, destination, opts)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error copying %s to %s: %s", source, destination, err))
+	}
+	if d.Get("strip_quarantine").(bool) {
+		if err := stripQuarantineTree(fsys, destination); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	// Use the destination as the ID
+	d.SetId(pathID(fsys, destination))
+
+	return resourceCopyRead(ctx, d, meta)
+}
+
+func resourceCopyRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	destination := d.Get("destination").(string)
+
+	// Check if the destination exists
+	_, err := fsys.Lstat(destination)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Destination was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading %s: %s", destination, err))
+	}
+
+	excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Hash what is on disk so that local modifications show up as drift
+	checksum, err := checksumPath(fsys, destination, excludes, meta.(*providerConfig).parallelism)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error computing checksum of %s: %s", destination, err))
+	}
+
+	if err := d.Set("checksum", checksum); err != nil {
+		return diag.FromErr(err)
+	}
+
+	_, size, err := treeSize(fsys, destination, excludes)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading %s: %s", destination, err))
+	}
+	if err := d.Set("size", int(size)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
+
+func resourceCopyUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	source := d.Get("source").(string)
+	destination := d.Get("destination").(string)
+
+	opts, err := copyOptionsFromResourceData(d, conf)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Remove the previous copy so that files dropped from the source don't
+	// linger, keeping what is excluded
+	err = removeAllExcluding(fsys, destination, opts.excludes)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error deleting %s: %s", destination, err))
+	}
+
+	// Copy the source again
+	err = copyPath(fsys, source, destination, opts)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error copying %s to %s: %s", source, destination, err))
+	}
+	if d.Get("strip_quarantine").(bool) {
+		if err := stripQuarantineTree(fsys, destination); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return resourceCopyRead(ctx, d, meta)
+}
+
+func resourceCopyDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	destination := d.Get("destination").(string)
+
+	excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Delete the copy, leaving what is excluded in place
+	err = removeAllExcluding(fsys, destination, excludes)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error deleting %s: %s", destination, err))
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
+
+func resourceCopyCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	source := d.Get("source").(string)
+
+	if d.Get("strip_quarantine").(bool) && runtime.GOOS != "darwin" {
+		return fmt.Errorf("strip_quarantine is only supported on macOS")
+	}
+
+	// The source may be produced by another resource during the same apply
+	if source == "" || !d.NewValueKnown("source") {
+		return setCopyComputed(d)
+	}
+
+	if _, err := fsys.Lstat(source); os.IsNotExist(err) {
+		return setCopyComputed(d)
+	}
+
+	excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+	if err != nil {
+		return err
+	}
+
+	checksum, err := checksumPath(fsys, source, excludes, meta.(*providerConfig).parallelism)
+	if err != nil {
+		return fmt.Errorf("error computing checksum of %s: %s", source, err)
+	}
+
+	// A differing checksum means the source changed or the copy was modified
+	if checksum != d.Get("checksum").(string) {
+		if err := d.SetNew("checksum", checksum); err != nil {
+			return err
+		}
+	}
+
+	_, size, err := treeSize(fsys, source, excludes)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %s", source, err)
+	}
+	if int(size) != d.Get("size").(int) {
+		return d.SetNew("size", int(size))
+	}
+
+	return nil
+}
+
+// setCopyComputed marks what is only known once the source has been copied.
+func setCopyComputed(d *schema.ResourceDiff) error {
+	if err := d.SetNewComputed("checksum"); err != nil {
+		return err
+	}
+	return d.SetNewComputed("size")
+}
+
+func copyPath(fsys fileSystem, source, destination string, opts copyOptions) error {
+	info, err := fsys.Lstat(source)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return copyEntry(fsys, source, destination, info, opts)
+	}
+
+	// Directories are created as the walk reaches them, so that they exist
+	// before the files below them are copied on the pool
+	pool := newWorkerPool(opts.parallelism)
+	err = walkDirExcluding(fsys, source, opts.excludes, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return copyEntry(fsys, path, filepath.Join(destination, rel), info, opts)
+		}
+		return pool.Go(func() error {
+			return copyEntry(fsys, path, filepath.Join(destination, rel), info, opts)
+		})
+	})
+	if werr := pool.Wait(); err == nil {
+		err = werr
+	}
+	if err != nil {
+		return err
+	}
+
+	// Directory timestamps change while their children are written, so
+	// apply them in a second pass
+	if opts.timestamps {
+		return walkDirExcluding(fsys, source, opts.excludes, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || !entry.IsDir() {
+				return err
+			}
+
+			rel, err := filepath.Rel(source, path)
+			if err != nil {
+				return err
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+
+			return fsys.Chtimes(filepath.Join(destination, rel), fileAccessTime(info), info.ModTime())
+		})
+	}
+
+	return nil
+}
+
+func copyEntry(fsys fileSystem, source, destination string, info os.FileInfo, opts copyOptions) error {
+	perm := permissionBits(info.Mode())
+
+	switch {
+	case info.IsDir():
+		if !opts.mode {
+			perm = opts.dirPerm
+		}
+		err := fsys.MkdirAll(destination, perm)
+		if err != nil {
+			return err
+		}
+
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := fsys.Readlink(source)
+		if err != nil {
+			return err
+		}
+		fsys.Remove(destination)
+		err = fsys.Symlink(target, destination)
+		if err != nil {
+			return err
+		}
+
+		// Links carry no mode of their own and Chtimes would follow them
+		if opts.ownership {
+			if uid, gid, ok := fileOwner(info); ok {
+				return fsys.Lchown(destination, uid, gid)
+			}
+		}
+		return nil
+
+	case info.Mode().IsRegular():
+		if !opts.mode {
+			perm = opts.filePerm
+		}
+		err := copyFileContent(fsys, source, destination, info.Size(), perm)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("%s is not a regular file, directory or symlink", source)
+	}
+
+	// The umask may have masked the requested mode
+	if opts.mode {
+		err := fsys.Chmod(destination, perm)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.ownership {
+		if uid, gid, ok := fileOwner(info); ok {
+			err := fsys.Lchown(destination, uid, gid)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.timestamps && !info.IsDir() {
+		return fsys.Chtimes(destination, fileAccessTime(info), info.ModTime())
+	}
+
+	return nil
+}
+
+// copyFileContent streams source to destination on the same target, so
+// that files of any size are copied without being held in memory.
+func copyFileContent(fsys fileSystem, source, destination string, size int64, perm os.FileMode) error {
+	in, err := fsys.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := fsys.Create(destination, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = streamCopy(out, in, "copying", source, size)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// checksumPath returns the SHA-256 of a file, or for a directory a SHA-256
+// over the sorted relative paths and contents of everything beneath it that
+// excludes doesn't match, hashing up to parallelism files at once.
+func checksumPath(fsys fileSystem, path string, excludes *excludeMatcher, parallelism int) (string, error) {
+	info, err := fsys.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !info.IsDir() {
+		return checksumEntry(fsys, path, info)
+	}
+
+	var (
+		mu      sync.Mutex
+		entries []string
+	)
+	pool := newWorkerPool(parallelism)
+	err = walkDirExcluding(fsys, path, excludes, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		return pool.Go(func() error {
+			sum, err := checksumEntry(fsys, p, info)
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			entries = append(entries, filepath.ToSlash(rel)+"\x00"+sum)
+			return nil
+		})
+	})
+	if werr := pool.Wait(); err == nil {
+		err = werr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	sort.Strings(entries)
+
+	hash := sha256.New()
+	for _, entry := range entries {
+		io.WriteString(hash, entry+"\n")
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// treeSize returns the number and total size of the regular files at or
+// below path that excludes doesn't match.
+func treeSize(fsys fileSystem, path string, excludes *excludeMatcher) (files int, size int64, err error) {
+	err = walkDirExcluding(fsys, path, excludes, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files++
+		size += info.Size()
+		return nil
+	})
+	return files, size, err
+}
+
+func checksumEntry(fsys fileSystem, path string, info os.FileInfo) (string, error) {
+	switch {
+	case info.IsDir():
+		return "dir", nil
+
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := fsys.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		hash := sha256.Sum256([]byte(target))
+		return "link:" + hex.EncodeToString(hash[:]), nil
+	}
+
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := streamCopy(hash, f, "hashing", path, info.Size()); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,216 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourceDeviceNode() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceDeviceNodeCreate,
+		ReadContext:   resourceDeviceNodeRead,
+		UpdateContext: resourceDeviceNodeUpdate,
+		DeleteContext: resourceDeviceNodeDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The path to the device node",
+			},
+			"type": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"character", "block"}, false),
+				Description:  "The device type, either 'character' or 'block'",
+			},
+			"major": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "The major device number",
+			},
+			"minor": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "The minor device number",
+			},
+			"permissions": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "0666",
+				ValidateDiagFunc: validatePermissions,
+				StateFunc:        normalizePermissions,
+				DiffSuppressFunc: diffSuppressPermissions,
+				Description:      "Device node permissions in octal format (e.g., '0666')",
+			},
+			"owner": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The user name or numeric ID owning the device node",
+			},
+			"group": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The group name or numeric ID owning the device node",
+			},
+		},
+	}
+}
+
+func resourceDeviceNodeCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	path := d.Get("path").(string)
+	nodeType := d.Get("type").(string)
+	major := d.Get("major").(int)
+	minor := d.Get("minor").(int)
+	permStr := d.Get("permissions").(string)
+
+	// Parse permissions
+	perm, err := parsePermissions(permStr)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Make sure the directory exists
+	dir := filepath.Dir(path)
+	err = fsys.MkdirAll(dir, conf.defaults.dirPerm())
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error creating directory %s: %s", dir, err))
+	}
+
+	// Create the device node
+	err = fsys.Mknod(path, nodeType, perm, uint32(major), uint32(minor))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error creating device node %s: %s", path, err))
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	// The umask may have masked the requested permissions
+	err = fsys.Chmod(path, perm)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting permissions for device node %s: %s", path, err))
+	}
+
+	err = chownFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting ownership for device node %s: %s", path, err))
+	}
+
+	return resourceDeviceNodeRead(ctx, d, meta)
+}
+
+func resourceDeviceNodeRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Check if the device node exists
+	fileInfo, err := fsys.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Device node was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading device node %s: %s", path, err))
+	}
+
+	// Ensure it's a device node
+	switch {
+	case fileInfo.Mode()&os.ModeCharDevice != 0:
+		if err := d.Set("type", "character"); err != nil {
+			return diag.FromErr(err)
+		}
+	case fileInfo.Mode()&os.ModeDevice != 0:
+		if err := d.Set("type", "block"); err != nil {
+			return diag.FromErr(err)
+		}
+	default:
+		return diag.FromErr(fmt.Errorf("path %s is not a device node", path))
+	}
+
+	if major, minor, ok := deviceNumbers(fileInfo); ok {
+		if err := d.Set("major", int(major)); err != nil {
+			return diag.FromErr(err)
+		}
+		if err := d.Set("minor", int(minor)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	// Set permissions
+	perm := formatPermissions(fileInfo.Mode())
+	if err := d.Set("permissions", perm); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return readOwnershipIntoResourceData(fsys, d, fileInfo)
+}
+
+func resourceDeviceNodeUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	if d.HasChange("permissions") {
+		perm, err := parsePermissions(d.Get("permissions").(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		err = fsys.Chmod(path, perm)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting permissions for device node %s: %s", path, err))
+		}
+	}
+
+	if d.HasChanges("owner", "group") {
+		err := chownFromResourceData(fsys, d, path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting ownership for device node %s: %s", path, err))
+		}
+	}
+
+	return resourceDeviceNodeRead(ctx, d, meta)
+}
+
+func resourceDeviceNodeDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Delete the device node
+	err := fsys.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("error deleting device node %s: %s", path, err))
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
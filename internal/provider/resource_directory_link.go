@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourceDirectoryLink() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceDirectoryLinkCreate,
+		ReadContext:   resourceDirectoryLinkRead,
+		DeleteContext: resourceDirectoryLinkDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+		CustomizeDiff: resourceDirectoryLinkCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The path to the link",
+			},
+			"target": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The directory the link points to, which must exist. A symlink's target may be relative to the directory holding the link",
+			},
+			"type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				Default:      "junction",
+				ValidateFunc: validation.StringInSlice([]string{"junction", "symlink"}, false),
+				Description:  "'junction' for an NTFS junction point, which needs no privilege but only points to absolute local paths and is only supported on Windows, or 'symlink' for a directory symlink, which on Windows needs the Create symbolic links privilege or Developer Mode",
+			},
+		},
+	}
+}
+
+func resourceDirectoryLinkCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Get("type").(string) == "junction" && d.NewValueKnown("target") && !filepath.IsAbs(d.Get("target").(string)) {
+		return fmt.Errorf("the target of a junction must be an absolute path")
+	}
+	return nil
+}
+
+func resourceDirectoryLinkCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	path := d.Get("path").(string)
+	target := d.Get("target").(string)
+
+	// Make sure the directory exists
+	dir := filepath.Dir(path)
+	err := fsys.MkdirAll(dir, conf.defaults.dirPerm())
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error creating directory %s: %s", dir, err))
+	}
+
+	// Windows decides whether a symlink is to a file or a directory when it's
+	// created, so the target has to be there first
+	resolved := target
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(dir, resolved)
+	}
+	if info, err := fsys.Stat(resolved); err != nil || !info.IsDir() {
+		return diag.FromErr(fmt.Errorf("target %s of link %s is not a directory", target, path))
+	}
+
+	// Create the link
+	if d.Get("type").(string) == "junction" {
+		err = fsys.Junction(target, path)
+	} else {
+		err = fsys.Symlink(target, path)
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error creating link %s: %s", path, err))
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	return resourceDirectoryLinkRead(ctx, d, meta)
+}
+
+func resourceDirectoryLinkRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Check if the link exists
+	fileInfo, err := fsys.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Link was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading link %s: %s", path, err))
+	}
+
+	// Junctions are reparse points that Go reports as irregular files
+	linkType := "junction"
+	switch {
+	case fileInfo.Mode()&os.ModeSymlink != 0:
+		linkType = "symlink"
+	case fileInfo.Mode()&os.ModeIrregular == 0:
+		return diag.FromErr(fmt.Errorf("path %s is not a junction or symlink", path))
+	}
+
+	target, err := fsys.Readlink(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading link %s: %s", path, err))
+	}
+
+	if err := d.Set("type", linkType); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("target", target); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
+
+func resourceDirectoryLinkDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Delete the link, leaving the directory it points to alone
+	err := fsys.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("error deleting link %s: %s", path, err))
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
@@ -0,0 +1,387 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceDirectoryTree() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceDirectoryTreeCreate,
+		ReadContext:   resourceDirectoryTreeRead,
+		UpdateContext: resourceDirectoryTreeUpdate,
+		DeleteContext: resourceDirectoryTreeDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+		CustomizeDiff: customizeDiffDefaults(providerDefaults.dirPerm),
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The directory the tree is created in. It is created when missing, and left in place on destroy",
+			},
+			"permissions": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ValidateDiagFunc: validatePermissions,
+				StateFunc:        normalizePermissions,
+				DiffSuppressFunc: diffSuppressPermissions,
+				Description:      "Permissions in octal format of the directories that don't set their own. Defaults to the provider's default_directory_permissions",
+			},
+			"owner": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The user name or numeric ID owning the directories that don't set their own. Defaults to the provider's default_owner",
+			},
+			"group": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The group name or numeric ID owning the directories that don't set their own. Defaults to the provider's default_group",
+			},
+			"directory": {
+				Type:        schema.TypeSet,
+				Required:    true,
+				Description: "A directory of the tree. Missing parents are created with the permissions of the tree, but only the listed directories are managed",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"path": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validateTreePath,
+							Description:  "The path of the directory relative to the path of the tree",
+						},
+						"permissions": {
+							Type:             schema.TypeString,
+							Optional:         true,
+							ValidateDiagFunc: validateOptionalPermissions,
+							Description:      "Directory permissions in octal format (e.g., '0750'). Defaults to the permissions of the tree",
+						},
+						"owner": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "The user name or numeric ID owning the directory. Defaults to the owner of the tree",
+						},
+						"group": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "The group name or numeric ID owning the directory. Defaults to the group of the tree",
+						},
+					},
+				},
+			},
+			"force_destroy": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Delete directories removed from the tree, or the whole tree on destroy, even if they aren't empty. Otherwise, deleting a directory that isn't empty fails",
+			},
+		},
+	}
+}
+
+// treeDirectory is a directory of a filesystem_directory_tree.
+type treeDirectory struct {
+	path        string
+	permissions string
+	owner       string
+	group       string
+}
+
+func validateTreePath(v interface{}, k string) ([]string, []error) {
+	p := filepath.Clean(v.(string))
+	if filepath.IsAbs(p) || p == "." || p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+		return nil, []error{fmt.Errorf("%s must be a path below the tree, got %q", k, v)}
+	}
+	return nil, nil
+}
+
+func validateOptionalPermissions(v interface{}, path cty.Path) diag.Diagnostics {
+	if v.(string) == "" {
+		return nil
+	}
+	return validatePermissions(v, path)
+}
+
+// treeDirectories returns the directories of a tree sorted by path, so that
+// parents come before what is below them.
+func treeDirectories(s *schema.Set) []treeDirectory {
+	var dirs []treeDirectory
+	for _, v := range s.List() {
+		m := v.(map[string]interface{})
+		dirs = append(dirs, treeDirectory{
+			path:        m["path"].(string),
+			permissions: m["permissions"].(string),
+			owner:       m["owner"].(string),
+			group:       m["group"].(string),
+		})
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return filepath.Clean(dirs[i].path) < filepath.Clean(dirs[j].path)
+	})
+	return dirs
+}
+
+// directoryTreePaths returns the paths a tree touches: its root, before and
+// after it moves, and each of its directories.
+func directoryTreePaths(d *schema.ResourceData) []string {
+	old, root := d.GetChange("path")
+	paths := []string{old.(string), root.(string)}
+	for _, dir := range treeDirectories(d.Get("directory").(*schema.Set)) {
+		paths = append(paths, filepath.Join(root.(string), dir.path))
+	}
+	return paths
+}
+
+// wanted returns what a directory should have, falling back to the
+// permissions, owner and group of the tree.
+func (t treeDirectory) wanted(d *schema.ResourceData) treeDirectory {
+	if t.permissions == "" {
+		t.permissions = d.Get("permissions").(string)
+	}
+	if t.owner == "" {
+		t.owner = d.Get("owner").(string)
+	}
+	if t.group == "" {
+		t.group = d.Get("group").(string)
+	}
+	return t
+}
+
+// applyTreeDirectories creates the directories of a tree and applies their
+// permissions and ownership. Permissions are applied deepest first, so that
+// restrictive parents don't get in the way of creating what is below them.
+func applyTreeDirectories(fsys fileSystem, d *schema.ResourceData, root string) error {
+	rootPerm, err := parsePermissions(d.Get("permissions").(string))
+	if err != nil {
+		return err
+	}
+
+	err = fsys.MkdirAll(root, rootPerm)
+	if err != nil {
+		return fmt.Errorf("error creating directory %s: %s", root, err)
+	}
+
+	dirs := treeDirectories(d.Get("directory").(*schema.Set))
+	for _, dir := range dirs {
+		path := filepath.Join(root, dir.path)
+		err := fsys.MkdirAll(path, rootPerm)
+		if err != nil {
+			return fmt.Errorf("error creating directory %s: %s", path, err)
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir := dirs[i].wanted(d)
+		path := filepath.Join(root, dir.path)
+
+		perm, err := parsePermissions(dir.permissions)
+		if err != nil {
+			return err
+		}
+		err = fsys.Chmod(path, perm)
+		if err != nil {
+			return fmt.Errorf("error setting permissions for directory %s: %s", path, err)
+		}
+
+		uid, gid := -1, -1
+		if dir.owner != "" {
+			if uid, err = fsys.LookupUID(dir.owner); err != nil {
+				return err
+			}
+		}
+		if dir.group != "" {
+			if gid, err = fsys.LookupGID(dir.group); err != nil {
+				return err
+			}
+		}
+		if uid != -1 || gid != -1 {
+			err = fsys.Lchown(path, uid, gid)
+			if err != nil {
+				return fmt.Errorf("error setting ownership of directory %s: %s", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// removeTreeDirectories deletes directories of a tree deepest first, leaving
+// those that are still parents of keep.
+func removeTreeDirectories(fsys fileSystem, root string, dirs []treeDirectory, keep []treeDirectory, force bool) error {
+	for i := len(dirs) - 1; i >= 0; i-- {
+		rel := filepath.Clean(dirs[i].path)
+		if treeHasChild(keep, rel) {
+			continue
+		}
+
+		path := filepath.Join(root, rel)
+		if !force {
+			entries, err := fsys.ReadDir(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("error reading directory %s: %s", path, err)
+			}
+			if len(entries) > 0 {
+				return fmt.Errorf("directory %s is not empty, set force_destroy = true to delete it along with its contents", path)
+			}
+		}
+
+		err := fsys.RemoveAll(path)
+		if err != nil {
+			return fmt.Errorf("error deleting directory %s: %s", path, err)
+		}
+	}
+	return nil
+}
+
+// treeHasChild reports whether any of dirs is rel or below it.
+func treeHasChild(dirs []treeDirectory, rel string) bool {
+	for _, dir := range dirs {
+		p := filepath.Clean(dir.path)
+		if p == rel || strings.HasPrefix(p, rel+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func resourceDirectoryTreeCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	err := applyTreeDirectories(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	return resourceDirectoryTreeRead(ctx, d, meta)
+}
+
+func resourceDirectoryTreeRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	root := d.Get("path").(string)
+
+	// Check if the tree exists
+	info, err := fsys.Stat(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The tree was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading directory %s: %s", root, err))
+	}
+	if !info.IsDir() {
+		return diag.FromErr(fmt.Errorf("path %s is a file, not a directory", root))
+	}
+
+	// Report drift per directory: those that are gone are left out, and the
+	// others record what differs from what they should have
+	var entries []interface{}
+	for _, dir := range treeDirectories(d.Get("directory").(*schema.Set)) {
+		path := filepath.Join(root, dir.path)
+		info, err := fsys.Stat(path)
+		if os.IsNotExist(err) || (err == nil && !info.IsDir()) {
+			continue
+		}
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading directory %s: %s", path, err))
+		}
+
+		wanted := dir.wanted(d)
+		if perm, err := parsePermissions(wanted.permissions); err != nil || perm != permissionBits(info.Mode()) {
+			dir.permissions = formatPermissions(info.Mode())
+		}
+		if uid, gid, ok := fileOwner(info); ok {
+			if wanted.owner != "" {
+				if id, err := fsys.LookupUID(wanted.owner); err != nil || id != uid {
+					dir.owner = strconv.Itoa(uid)
+				}
+			}
+			if wanted.group != "" {
+				if id, err := fsys.LookupGID(wanted.group); err != nil || id != gid {
+					dir.group = strconv.Itoa(gid)
+				}
+			}
+		}
+
+		entries = append(entries, map[string]interface{}{
+			"path":        dir.path,
+			"permissions": dir.permissions,
+			"owner":       dir.owner,
+			"group":       dir.group,
+		})
+	}
+
+	if err := d.Set("directory", entries); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
+
+func resourceDirectoryTreeUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	root := d.Get("path").(string)
+
+	// Delete the directories that were removed from the tree
+	o, n := d.GetChange("directory")
+	removed := treeDirectories(o.(*schema.Set).Difference(n.(*schema.Set)))
+	kept := treeDirectories(n.(*schema.Set))
+	err := removeTreeDirectories(fsys, root, removed, kept, d.Get("force_destroy").(bool))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	err = applyTreeDirectories(fsys, d, root)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceDirectoryTreeRead(ctx, d, meta)
+}
+
+func resourceDirectoryTreeDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	root := d.Get("path").(string)
+
+	// Delete the directories of the tree, leaving the path itself in place
+	dirs := treeDirectories(d.Get("directory").(*schema.Set))
+	err := removeTreeDirectories(fsys, root, dirs, nil, d.Get("force_destroy").(bool))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
@@ -0,0 +1,399 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// files manages a set of small files as one resource, which keeps plans and
+// the state small where for_each over filesystem_file would create a
+// resource per file.
+type files struct {
+	provider *frameworkProvider
+}
+
+type filesModel struct {
+	Files         types.Map  `tfsdk:"files"`
+	CreateParents types.Bool `tfsdk:"create_parents"`
+	Become        types.Bool `tfsdk:"become"`
+}
+
+type filesEntryModel struct {
+	Content     types.String `tfsdk:"content"`
+	Permissions types.String `tfsdk:"permissions"`
+}
+
+// filesEntryType is the object type of the entries of files.
+var filesEntryType = types.ObjectType{AttrTypes: map[string]attr.Type{
+	"content":     types.StringType,
+	"permissions": types.StringType,
+}}
+
+func newFiles() resource.Resource {
+	return &files{}
+}
+
+var (
+	_ resource.ResourceWithConfigure      = &files{}
+	_ resource.ResourceWithModifyPlan     = &files{}
+	_ resource.ResourceWithValidateConfig = &files{}
+)
+
+func (r *files) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_files"
+}
+
+func (r *files) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manage a set of files, such as hundreds of small configuration files, as one resource",
+		Attributes: map[string]schema.Attribute{
+			"files": schema.MapNestedAttribute{
+				Required:    true,
+				Description: "The files to manage by path. Files removed from the map are deleted",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"content": schema.StringAttribute{
+							Optional:    true,
+							Computed:    true,
+							Default:     stringdefault.StaticString(""),
+							Description: "The content of the file",
+						},
+						"permissions": schema.StringAttribute{
+							Optional:    true,
+							Computed:    true,
+							Description: "File permissions in octal format (e.g., '0644'). Defaults to the provider's default_file_permissions",
+						},
+					},
+				},
+			},
+			"create_parents": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(true),
+				Description: "Create missing parent directories with the provider's default_directory_permissions. When false, creating a file in a missing directory fails",
+			},
+			"become": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Perform the operations of this resource with the privileges of the provider's become block, such as through sudo as root",
+			},
+		},
+	}
+}
+
+func (r *files) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if p, ok := req.ProviderData.(*frameworkProvider); ok {
+		r.provider = p
+	}
+}
+
+func (r *files) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var model filesModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() || model.Files.IsNull() || model.Files.IsUnknown() {
+		return
+	}
+
+	entries := map[string]filesEntryModel{}
+	resp.Diagnostics.Append(model.Files.ElementsAs(ctx, &entries, false)...)
+	for name, entry := range entries {
+		if name == "" {
+			resp.Diagnostics.AddAttributeError(path.Root("files"), "Invalid path", "file paths must not be empty")
+		}
+		if v := entry.Permissions.ValueString(); v != "" {
+			if _, err := parsePermissions(v); err != nil {
+				resp.Diagnostics.AddAttributeError(path.Root("files").AtMapKey(name).AtName("permissions"), "Invalid permissions", err.Error())
+			}
+		}
+	}
+}
+
+// ModifyPlan plans the provider's default_file_permissions for files that
+// don't set their own, so that the plan shows what will be applied.
+func (r *files) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	conf := r.provider.config()
+	if conf == nil {
+		return
+	}
+
+	var config, plan filesModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || config.Files.IsUnknown() || plan.Files.IsUnknown() {
+		return
+	}
+
+	configured := map[string]filesEntryModel{}
+	planned := map[string]filesEntryModel{}
+	resp.Diagnostics.Append(config.Files.ElementsAs(ctx, &configured, true)...)
+	resp.Diagnostics.Append(plan.Files.ElementsAs(ctx, &planned, true)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	perm := types.StringValue(formatPermissions(conf.defaults.filePerm()))
+	for name, entry := range planned {
+		if c, ok := configured[name]; ok && c.Permissions.IsNull() {
+			entry.Permissions = perm
+			planned[name] = entry
+		}
+	}
+
+	value, diags := types.MapValueFrom(ctx, filesEntryType, planned)
+	resp.Diagnostics.Append(diags...)
+	if !resp.Diagnostics.HasError() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("files"), value)...)
+	}
+}
+
+func (r *files) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan filesModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.apply(ctx, filesModel{}, plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *files) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state filesModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	conf := r.provider.config()
+	if conf == nil {
+		resp.Diagnostics.AddError("Provider not configured", "the filesystem provider must be configured before resources are read")
+		return
+	}
+	conf, err := conf.becoming(ctx, state.Become.ValueBool())
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid become", err.Error())
+		return
+	}
+	fsys := withContext(ctx, conf.fs)
+
+	entries := map[string]filesEntryModel{}
+	resp.Diagnostics.Append(state.Files.ElementsAs(ctx, &entries, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Report drift per file: those that are gone are left out, and the
+	// others record what is on disk
+	var mu sync.Mutex
+	current := map[string]filesEntryModel{}
+	pool := newWorkerPool(conf.parallelism)
+	for name, entry := range entries {
+		err := pool.Go(func() error {
+			unlock, err := conf.locks.lock(ctx, fsys, name)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+			info, err := fsys.Stat(name)
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("error reading file %s: %s", name, err)
+			}
+			if info.IsDir() {
+				return fmt.Errorf("path %s is a directory, not a file", name)
+			}
+			data, err := fsys.ReadFile(name)
+			if err != nil {
+				return fmt.Errorf("error reading file %s: %s", name, err)
+			}
+
+			if string(data) != entry.Content.ValueString() {
+				entry.Content = types.StringValue(string(data))
+			}
+			if perm, err := parsePermissions(entry.Permissions.ValueString()); err != nil || perm != permissionBits(info.Mode()) {
+				entry.Permissions = types.StringValue(formatPermissions(info.Mode()))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			current[name] = entry
+			return nil
+		})
+		if err != nil {
+			break
+		}
+	}
+	if err := pool.Wait(); err != nil {
+		resp.Diagnostics.AddError("Error reading files", err.Error())
+		return
+	}
+
+	value, diags := types.MapValueFrom(ctx, filesEntryType, current)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	state.Files = value
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *files) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var state, plan filesModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.apply(ctx, state, plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *files) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state filesModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.apply(ctx, state, filesModel{})...)
+}
+
+// apply writes the files of plan that differ from state, and deletes those
+// of state that plan no longer has.
+func (r *files) apply(ctx context.Context, state, plan filesModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	conf := r.provider.config()
+	if conf == nil {
+		diags.AddError("Provider not configured", "the filesystem provider must be configured before resources are applied")
+		return diags
+	}
+	// Deleting goes by the state
+	become := plan.Become
+	if plan.Files.IsNull() {
+		become = state.Become
+	}
+	conf, err := conf.becoming(ctx, become.ValueBool())
+	if err != nil {
+		diags.AddError("Invalid become", err.Error())
+		return diags
+	}
+	fsys := withContext(ctx, conf.fs)
+
+	previous := map[string]filesEntryModel{}
+	wanted := map[string]filesEntryModel{}
+	if !state.Files.IsNull() {
+		diags.Append(state.Files.ElementsAs(ctx, &previous, false)...)
+	}
+	if !plan.Files.IsNull() {
+		diags.Append(plan.Files.ElementsAs(ctx, &wanted, false)...)
+	}
+	if diags.HasError() {
+		return diags
+	}
+
+	// Parent directories are created up front, as files are written on the
+	// pool in no particular order
+	names := make([]string, 0, len(wanted))
+	for name, entry := range wanted {
+		if old, ok := previous[name]; !ok || !old.Content.Equal(entry.Content) || !old.Permissions.Equal(entry.Permissions) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if plan.CreateParents.ValueBool() {
+		for _, name := range names {
+			dir := filepath.Dir(name)
+			if err := fsys.MkdirAll(dir, conf.defaults.dirPerm()); err != nil {
+				diags.AddError("Error creating directory", fmt.Sprintf("error creating directory %s: %s", dir, err))
+				return diags
+			}
+		}
+	}
+
+	pool := newWorkerPool(conf.parallelism)
+	for _, name := range names {
+		entry := wanted[name]
+		operation := "update"
+		if _, ok := previous[name]; !ok {
+			operation = "create"
+		}
+		err := pool.Go(func() error {
+			unlock, err := conf.locks.lock(ctx, fsys, name)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+			return conf.audit.record(fsys, "filesystem_files", operation, name, func() error {
+				perm, err := parsePermissions(entry.Permissions.ValueString())
+				if err != nil {
+					return err
+				}
+				err = writeFileAtomic(fsys, name, []byte(entry.Content.ValueString()), perm, false, nil)
+				if err == nil {
+					// The umask may have masked the requested mode
+					err = fsys.Chmod(name, perm)
+				}
+				if err != nil {
+					return fmt.Errorf("error writing file %s: %s", name, err)
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			break
+		}
+	}
+
+	for name := range previous {
+		if _, ok := wanted[name]; ok {
+			continue
+		}
+		err := pool.Go(func() error {
+			unlock, err := conf.locks.lock(ctx, fsys, name)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+			return conf.audit.record(fsys, "filesystem_files", "delete", name, func() error {
+				err := fsys.Remove(name)
+				if err != nil && !os.IsNotExist(err) {
+					return fmt.Errorf("error deleting file %s: %s", name, err)
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			break
+		}
+	}
+
+	if err := pool.Wait(); err != nil {
+		diags.AddError("Error applying files", err.Error())
+	}
+	return diags
+}
@@ -0,0 +1,312 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourceFormat() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceFormatCreate,
+		ReadContext:   resourceFormatRead,
+		UpdateContext: resourceFormatUpdate,
+		DeleteContext: resourceFormatDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"device": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The block device, or image file, to create the filesystem on (e.g., '/dev/sdb1')",
+			},
+			"type": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"ext4", "xfs", "btrfs"}, false),
+				Description:  "The filesystem type, 'ext4', 'xfs' or 'btrfs'",
+			},
+			"label": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The label of the filesystem",
+			},
+			"uuid": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				ValidateFunc:     validation.IsUUID,
+				DiffSuppressFunc: diffSuppressUUID,
+				Description:      "The UUID of the filesystem, for mounts and fstab entries. Defaults to a random one",
+			},
+			"options": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Additional arguments to mkfs (e.g., ['-m', '1'])",
+			},
+			"force": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Format a device that blkid finds a filesystem, partition table or other signature on, destroying its data. Otherwise creating the resource fails instead, unless the device already holds the filesystem as configured",
+			},
+		},
+	}
+}
+
+// diffSuppressUUID ignores the case of UUIDs.
+func diffSuppressUUID(k, old, new string, d *schema.ResourceData) bool {
+	return strings.EqualFold(old, new)
+}
+
+// mkfsCommand returns the command that creates a filesystem of the given
+// type, with overwrite set when the device may hold another one.
+func mkfsCommand(fsys fileSystem, fsType, device, label, uuid string, options []string, overwrite bool) string {
+	args := []string{"mkfs." + fsType, "-q"}
+	switch {
+	case overwrite && fsType == "ext4":
+		args = append(args, "-F")
+	case overwrite:
+		args = append(args, "-f")
+	}
+	switch {
+	case uuid != "" && fsType == "xfs":
+		args = append(args, "-m", "uuid="+uuid)
+	case uuid != "":
+		args = append(args, "-U", uuid)
+	}
+	if label != "" {
+		args = append(args, "-L", label)
+	}
+	args = append(args, options...)
+	args = append(args, device)
+	return commandLine(fsys, args...)
+}
+
+// blkidSignature returns the type of filesystem, partition table or other
+// signature that blkid finds on device, or "" when there is none.
+func blkidSignature(ctx context.Context, fsys fileSystem, device string) (string, error) {
+	target, err := fsys.Resolve(device)
+	if err != nil {
+		return "", err
+	}
+	// blkid exits with status 2 when it finds nothing
+	command := commandLine(fsys, "blkid", "-p", "-o", "export", target) + "; status=$?; [ $status -eq 2 ] || exit $status"
+	out, err := fsys.Run(ctx, command)
+	if err != nil {
+		return "", err
+	}
+
+	values := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			values[k] = v
+		}
+	}
+	for _, k := range []string{"TYPE", "PTTYPE"} {
+		if values[k] != "" {
+			return values[k], nil
+		}
+	}
+	return "", nil
+}
+
+// filesystemSuperblock is what a filesystem records about itself.
+type filesystemSuperblock struct {
+	fsType string
+	uuid   string
+	label  string
+}
+
+// superblockSize covers the superblocks of all supported filesystems, the
+// last of which is that of btrfs at 64 KiB. It also covers the first page
+// of swap areas, which is 64 KiB at most.
+const superblockSize = 0x10000 + 0x1000
+
+// readSuperblock identifies the filesystem on device from its superblock,
+// returning nil when it isn't one of the supported types. Unlike blkid, it
+// needs no command, so that it works in read-only mode too.
+func readSuperblock(fsys fileSystem, device string) (*filesystemSuperblock, error) {
+	f, err := fsys.Open(device)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, superblockSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	buf = buf[:n]
+
+	switch {
+	// ext2, ext3 and ext4 share a superblock at 1 KiB, told apart by features
+	case len(buf) >= 2048 && binary.LittleEndian.Uint16(buf[1024+0x38:]) == 0xef53:
+		sb := buf[1024:]
+		fsType := "ext2"
+		if binary.LittleEndian.Uint32(sb[0x60:])&0x40 != 0 {
+			fsType = "ext4"
+		} else if binary.LittleEndian.Uint32(sb[0x5c:])&0x4 != 0 {
+			fsType = "ext3"
+		}
+		return &filesystemSuperblock{fsType: fsType, uuid: formatUUID(sb[0x68:0x78]), label: cString(sb[0x78:0x88])}, nil
+	case len(buf) >= 120 && bytes.HasPrefix(buf, []byte("XFSB")):
+		return &filesystemSuperblock{fsType: "xfs", uuid: formatUUID(buf[32:48]), label: cString(buf[108:120])}, nil
+	case len(buf) >= superblockSize && bytes.Equal(buf[0x10040:0x10048], []byte("_BHRfS_M")):
+		sb := buf[0x10000:]
+		return &filesystemSuperblock{fsType: "btrfs", uuid: formatUUID(sb[0x20:0x30]), label: cString(sb[0x12b:0x22b])}, nil
+	// Swap areas end their first page with a signature, and keep their UUID
+	// and label after the 1 KiB reserved for boot loaders
+	case swapPageSize(buf) > 0:
+		return &filesystemSuperblock{fsType: "swap", uuid: formatUUID(buf[1036:1052]), label: cString(buf[1052:1068])}, nil
+	}
+	return nil, nil
+}
+
+// swapPageSize returns the page size of the swap area that starts with buf,
+// or 0 when it isn't one.
+func swapPageSize(buf []byte) int {
+	for size := 0x1000; size <= 0x10000; size *= 2 {
+		if len(buf) >= size && bytes.Equal(buf[size-10:size], []byte("SWAPSPACE2")) {
+			return size
+		}
+	}
+	return 0
+}
+
+func formatUUID(b []byte) string {
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+// cString returns b up to its first NUL byte.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+func resourceFormatCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	device := d.Get("device").(string)
+	fsType := d.Get("type").(string)
+	label := d.Get("label").(string)
+	uuid := d.Get("uuid").(string)
+
+	// The device must already exist
+	if _, err := fsys.Stat(device); err != nil {
+		return diag.FromErr(fmt.Errorf("error reading device %s: %s", device, err))
+	}
+
+	signature, err := blkidSignature(ctx, fsys, device)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error probing device %s: %s", device, err))
+	}
+
+	// A device that already holds the filesystem as configured is adopted
+	// rather than formatted again
+	if signature != "" {
+		sb, err := readSuperblock(fsys, device)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading device %s: %s", device, err))
+		}
+		if sb != nil && sb.fsType == fsType && sb.label == label && (uuid == "" || strings.EqualFold(sb.uuid, uuid)) {
+			d.SetId(pathID(fsys, device))
+			return resourceFormatRead(ctx, d, meta)
+		}
+		if !d.Get("force").(bool) {
+			return diag.FromErr(fmt.Errorf("device %s already holds %s, set force = true to format it and lose its data", device, signature))
+		}
+	}
+
+	var options []string
+	for _, v := range d.Get("options").([]interface{}) {
+		options = append(options, v.(string))
+	}
+	target, err := fsys.Resolve(device)
+	if err == nil {
+		_, err = fsys.Run(ctx, mkfsCommand(fsys, fsType, target, label, uuid, options, signature != ""))
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error formatting device %s: %s", device, err))
+	}
+
+	// Use the device as the ID
+	d.SetId(pathID(fsys, device))
+
+	return resourceFormatRead(ctx, d, meta)
+}
+
+func resourceFormatRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	device := d.Get("device").(string)
+
+	// Check if the device exists
+	sb, err := readSuperblock(fsys, device)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The device is gone, along with the filesystem
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading device %s: %s", device, err))
+	}
+
+	// A device that was wiped or formatted with something else shows as a
+	// type change, so that the filesystem is created again
+	if sb == nil {
+		sb = &filesystemSuperblock{}
+	}
+	for k, v := range map[string]interface{}{
+		"type":  sb.fsType,
+		"uuid":  sb.uuid,
+		"label": sb.label,
+	} {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
+
+func resourceFormatUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// Only force can change in place, and it only matters on create
+	return resourceFormatRead(ctx, d, meta)
+}
+
+func resourceFormatDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	// The filesystem and its data are left on the device
+	d.SetId("")
+
+	return diags
+}
@@ -0,0 +1,280 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceGeneratedFile() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceGeneratedFileCreate,
+		ReadContext:   resourceGeneratedFileRead,
+		UpdateContext: resourceGeneratedFileUpdate,
+		DeleteContext: resourceGeneratedFileDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+		CustomizeDiff: customdiff.All(
+			customizeDiffDefaults(providerDefaults.filePerm),
+			customizeDiffGeneratedFile,
+		),
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The path to write the output of command to. It is deleted on destroy",
+			},
+			"command": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The shell command whose standard output becomes the content of the file, run on the target with the path in FILESYSTEM_PATH. Changing it runs it again",
+			},
+			"triggers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Arbitrary values that run command again when they change, such as the version of the tool producing the file",
+			},
+			"permissions": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ValidateDiagFunc: validatePermissions,
+				StateFunc:        normalizePermissions,
+				DiffSuppressFunc: diffSuppressPermissions,
+				Description:      "File permissions in octal format (e.g., '0644'). Defaults to the provider's default_file_permissions",
+			},
+			"owner": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The user name or numeric ID owning the file. Defaults to the provider's default_owner",
+			},
+			"group": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The group name or numeric ID owning the file. Defaults to the provider's default_group",
+			},
+			"sha256": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "SHA-256 checksum of the generated content",
+			},
+			"size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The size of the generated file in bytes",
+			},
+		},
+	}
+}
+
+// customizeDiffGeneratedFile marks what is only known once the command runs
+// again.
+func customizeDiffGeneratedFile(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" || !d.HasChanges("command", "triggers") {
+		return nil
+	}
+	if err := d.SetNewComputed("sha256"); err != nil {
+		return err
+	}
+	return d.SetNewComputed("size")
+}
+
+// generateFileFromResourceData runs command with its standard output going
+// to a temporary file next to path, which replaces path once the command
+// succeeds. The output is written on the target as it is produced, so it is
+// never held in memory.
+func generateFileFromResourceData(ctx context.Context, fsys fileSystem, d *schema.ResourceData, path string, dirPerm os.FileMode) error {
+	perm, err := parsePermissions(d.Get("permissions").(string))
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	err = fsys.MkdirAll(dir, dirPerm)
+	if err != nil {
+		return fmt.Errorf("error creating directory %s: %s", dir, err)
+	}
+
+	tmp, err := tempName(path)
+	if err != nil {
+		return err
+	}
+
+	// The redirection keeps the mode of the file it writes to
+	w, err := fsys.Create(tmp, perm)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("error writing file %s: %s", path, err)
+	}
+	defer fsys.Remove(tmp)
+
+	target, err := fsys.Resolve(path)
+	if err != nil {
+		return err
+	}
+	targetTmp, err := fsys.Resolve(tmp)
+	if err != nil {
+		return err
+	}
+	command := "(" + d.Get("command").(string) + "\n) > " + fsys.Quote(targetTmp)
+	_, err = fsys.Run(ctx, command, "FILESYSTEM_PATH="+target)
+	if err != nil {
+		return fmt.Errorf("command failed: %s", err)
+	}
+
+	err = fsys.Chmod(tmp, perm)
+	if err != nil {
+		return fmt.Errorf("error setting permissions for file %s: %s", path, err)
+	}
+	err = chownFromResourceData(fsys, d, tmp)
+	if err != nil {
+		return fmt.Errorf("error setting ownership of file %s: %s", path, err)
+	}
+
+	err = fsys.Rename(tmp, path)
+	if err != nil {
+		return fmt.Errorf("error writing file %s: %s", path, err)
+	}
+	return nil
+}
+
+func resourceGeneratedFileCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	path := d.Get("path").(string)
+
+	err := generateFileFromResourceData(ctx, fsys, d, path, conf.defaults.dirPerm())
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error generating file %s: %s", path, err))
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	// Record the content as generated, rather than as drift
+	if err := d.Set("sha256", ""); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceGeneratedFileRead(ctx, d, meta)
+}
+
+func resourceGeneratedFileRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Check if the file exists
+	fileInfo, err := fsys.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// File was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+	}
+	if fileInfo.IsDir() {
+		return diag.FromErr(fmt.Errorf("path %s is a directory, not a file", path))
+	}
+
+	sum, err := checksumFile(fsys, path, sha256.New())
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+	}
+
+	// A file changed outside Terraform is generated again, as there is no
+	// telling what the command would produce now
+	if applied := d.Get("sha256").(string); applied != "" && applied != hex.EncodeToString(sum) {
+		d.SetId("")
+		return diags
+	}
+
+	for k, v := range map[string]interface{}{
+		"sha256":      hex.EncodeToString(sum),
+		"size":        int(fileInfo.Size()),
+		"permissions": formatPermissions(fileInfo.Mode()),
+	} {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return readOwnershipIntoResourceData(fsys, d, fileInfo)
+}
+
+func resourceGeneratedFileUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	path := d.Get("path").(string)
+
+	if d.HasChanges("command", "triggers") {
+		err := generateFileFromResourceData(ctx, fsys, d, path, conf.defaults.dirPerm())
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error generating file %s: %s", path, err))
+		}
+		if err := d.Set("sha256", ""); err != nil {
+			return diag.FromErr(err)
+		}
+		return resourceGeneratedFileRead(ctx, d, meta)
+	}
+
+	if d.HasChange("permissions") {
+		perm, err := parsePermissions(d.Get("permissions").(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		err = fsys.Chmod(path, perm)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting permissions for file %s: %s", path, err))
+		}
+	}
+
+	if d.HasChanges("owner", "group") {
+		err := chownFromResourceData(fsys, d, path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting ownership of file %s: %s", path, err))
+		}
+	}
+
+	return resourceGeneratedFileRead(ctx, d, meta)
+}
+
+func resourceGeneratedFileDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Delete the file
+	err := fsys.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("error deleting file %s: %s", path, err))
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
@@ -0,0 +1,529 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceLinkFarm() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceLinkFarmCreate,
+		ReadContext:   resourceLinkFarmRead,
+		UpdateContext: resourceLinkFarmUpdate,
+		DeleteContext: resourceLinkFarmDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+		CustomizeDiff: customdiff.All(
+			customizeDiffDefaults(providerDefaults.dirPerm),
+			customizeDiffLinkFarm,
+		),
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The prefix the links are created in, such as /usr/local. It is created when missing, and left in place on destroy",
+			},
+			"source": {
+				Type:             schema.TypeString,
+				Required:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The package directory whose files are linked into path. Changing it, such as to the directory of a new version, relinks the files",
+			},
+			"excludes": excludesSchema("Gitignore-style patterns, relative to source, of what isn't linked (e.g., 'README*' or 'share/doc/')"),
+			"relative": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Point the links at source relative to the directory holding them, as stow does, so that the prefix and the packages can move together",
+			},
+			"force": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Replace files and links into other directories that are in the way of links. Otherwise they fail the apply. Directories in the way always do",
+			},
+			"permissions": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ValidateDiagFunc: validatePermissions,
+				StateFunc:        normalizePermissions,
+				DiffSuppressFunc: diffSuppressPermissions,
+				Description:      "Permissions in octal format of the directories created to hold links. Defaults to the provider's default_directory_permissions",
+			},
+			"owner": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The user name or numeric ID owning the directories created to hold links. Defaults to the provider's default_owner",
+			},
+			"group": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The group name or numeric ID owning the directories created to hold links. Defaults to the provider's default_group",
+			},
+			"links": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The targets of the links by the path of each relative to path. Links that are missing or were replaced outside Terraform are left out, so that they are linked again",
+			},
+			"directories": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The directories created below path to hold links, relative to path. They are deleted once they no longer hold anything",
+			},
+		},
+	}
+}
+
+// farmLinks returns the links that mirror the files of source into path, by
+// the slash-separated path of each relative to path. Directories are
+// created rather than linked, so that several packages can share them. A
+// source that is a symlink, such as one to the current version, is walked
+// as the directory it points to, but linked through.
+func farmLinks(fsys fileSystem, source, path string, excludes *excludeMatcher, relative bool) (map[string]string, error) {
+	source = filepath.Clean(source)
+	root, err := resolveSymlinks(fsys, source, true)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fsys.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("source %s is not a directory", source)
+	}
+
+	links := map[string]string{}
+	err = walkDirExcluding(fsys, root, excludes, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		rel := relSlash(root, p)
+		target := filepath.Join(source, filepath.FromSlash(rel))
+		if relative {
+			link := filepath.Join(path, filepath.FromSlash(rel))
+			if r, err := filepath.Rel(filepath.Dir(link), target); err == nil {
+				target = r
+			}
+		}
+		links[rel] = target
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// resolveLinkTarget returns where target, read from the link at link,
+// points, relative to the directory of the link when it isn't absolute.
+func resolveLinkTarget(link, target string) string {
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(link), target)
+	}
+	return filepath.Clean(target)
+}
+
+// pathWithin reports whether path is dir or below it.
+func pathWithin(path, dir string) bool {
+	dir = filepath.Clean(dir)
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// ownLink returns the target of the symlink at link when it points into
+// one of sources, which is how stow tells its links from anything else.
+func ownLink(fsys fileSystem, link string, sources ...string) (string, bool) {
+	target, err := fsys.Readlink(link)
+	if err != nil {
+		return "", false
+	}
+	resolved := resolveLinkTarget(link, target)
+	for _, source := range sources {
+		if pathWithin(resolved, source) {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+func linkFarmFromResourceDiff(fsys fileSystem, d *schema.ResourceDiff) (map[string]string, error) {
+	excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+	if err != nil {
+		return nil, err
+	}
+	return farmLinks(fsys, d.Get("source").(string), d.Get("path").(string), excludes, d.Get("relative").(bool))
+}
+
+// customizeDiffLinkFarm plans the links the source calls for, which differ
+// from the state when files were added to or removed from it, or when Read
+// left out links that need linking again.
+func customizeDiffLinkFarm(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	for _, k := range []string{"path", "source", "excludes", "relative"} {
+		if !d.NewValueKnown(k) {
+			return d.SetNewComputed("links")
+		}
+	}
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	links, err := linkFarmFromResourceDiff(fsys, d)
+	if err != nil {
+		// The source may only be created during the apply
+		if os.IsNotExist(err) {
+			return d.SetNewComputed("links")
+		}
+		return fmt.Errorf("error reading source %s: %s", d.Get("source"), err)
+	}
+
+	current := map[string]string{}
+	for rel, target := range d.Get("links").(map[string]interface{}) {
+		current[rel] = target.(string)
+	}
+	if d.Id() == "" {
+		return d.SetNew("links", links)
+	}
+	if reflect.DeepEqual(current, links) {
+		return nil
+	}
+
+	// Relinking may create and delete the directories holding links
+	if err := d.SetNewComputed("directories"); err != nil {
+		return err
+	}
+	return d.SetNew("links", links)
+}
+
+// linkFarmDirectories returns the directories created for a link farm.
+func linkFarmDirectories(d *schema.ResourceData) []string {
+	var dirs []string
+	for _, v := range d.Get("directories").([]interface{}) {
+		dirs = append(dirs, v.(string))
+	}
+	return dirs
+}
+
+// pruneFarmDirectories deletes the directories of dirs that are empty,
+// deepest first, and returns the others.
+func pruneFarmDirectories(fsys fileSystem, root string, dirs []string) ([]string, error) {
+	sort.Strings(dirs)
+
+	var kept []string
+	for i := len(dirs) - 1; i >= 0; i-- {
+		path := filepath.Join(root, filepath.FromSlash(dirs[i]))
+		entries, err := fsys.ReadDir(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading directory %s: %s", path, err)
+		}
+		if len(entries) > 0 {
+			kept = append([]string{dirs[i]}, kept...)
+			continue
+		}
+		if err := fsys.Remove(path); err != nil {
+			return nil, fmt.Errorf("error deleting directory %s: %s", path, err)
+		}
+	}
+	return kept, nil
+}
+
+// mkdirFarm creates the directory at rel below root with its missing
+// parents, applying the permissions and ownership of the farm, and returns
+// the directories it created.
+func mkdirFarm(fsys fileSystem, d *schema.ResourceData, root, rel string) ([]string, error) {
+	if rel == "." {
+		return nil, nil
+	}
+
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	info, err := fsys.Stat(path)
+	if err == nil {
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s is in the way of links, as it isn't a directory", path)
+		}
+		return nil, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	created, err := mkdirFarm(fsys, d, root, filepath.ToSlash(filepath.Dir(filepath.FromSlash(rel))))
+	if err != nil {
+		return nil, err
+	}
+
+	perm, err := parsePermissions(d.Get("permissions").(string))
+	if err != nil {
+		return nil, err
+	}
+	if err := fsys.Mkdir(path, perm); err != nil {
+		return nil, fmt.Errorf("error creating directory %s: %s", path, err)
+	}
+	if err := fsys.Chmod(path, perm); err != nil {
+		return nil, fmt.Errorf("error setting permissions for directory %s: %s", path, err)
+	}
+	if err := chownFromResourceData(fsys, d, path); err != nil {
+		return nil, fmt.Errorf("error setting ownership of directory %s: %s", path, err)
+	}
+	return append(created, rel), nil
+}
+
+// applyLinkFarm links the files of source into path, removing the links of
+// the previous source that it no longer has. Everything in the way is
+// checked before anything changes, so that a conflict leaves the farm as it
+// was.
+func applyLinkFarm(fsys fileSystem, d *schema.ResourceData, dirPerm os.FileMode) error {
+	root := d.Get("path").(string)
+	oldSource, newSource := d.GetChange("source")
+	sources := []string{oldSource.(string), newSource.(string)}
+	force := d.Get("force").(bool)
+
+	excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+	if err != nil {
+		return err
+	}
+	links, err := farmLinks(fsys, newSource.(string), root, excludes, d.Get("relative").(bool))
+	if err != nil {
+		return fmt.Errorf("error reading source %s: %s", newSource, err)
+	}
+
+	var rels []string
+	for rel := range links {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+
+	// Find what has to be replaced, and what can't be
+	var replace, conflicts []string
+	for _, rel := range rels {
+		link := filepath.Join(root, filepath.FromSlash(rel))
+		info, err := fsys.Lstat(link)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %s: %s", link, err)
+		}
+
+		switch {
+		case info.IsDir():
+			conflicts = append(conflicts, link+" is a directory")
+			continue
+		case info.Mode()&os.ModeSymlink != 0:
+			if target, _ := fsys.Readlink(link); target == links[rel] {
+				continue
+			}
+			if _, ok := ownLink(fsys, link, sources...); ok {
+				replace = append(replace, rel)
+				continue
+			}
+			// Links that lead nowhere hold nothing worth keeping
+			if _, err := fsys.Stat(link); os.IsNotExist(err) {
+				replace = append(replace, rel)
+				continue
+			}
+		}
+
+		if !force {
+			conflicts = append(conflicts, link+" already exists")
+			continue
+		}
+		replace = append(replace, rel)
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("links are in the way of other files, set force = true to replace those that aren't directories: %s", strings.Join(conflicts, ", "))
+	}
+
+	// Remove the links of files the source no longer has
+	o, _ := d.GetChange("links")
+	for rel := range o.(map[string]interface{}) {
+		if _, ok := links[rel]; ok {
+			continue
+		}
+		link := filepath.Join(root, filepath.FromSlash(rel))
+		if _, ok := ownLink(fsys, link, sources...); !ok {
+			continue
+		}
+		if err := fsys.Remove(link); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error deleting link %s: %s", link, err)
+		}
+	}
+
+	for _, rel := range replace {
+		link := filepath.Join(root, filepath.FromSlash(rel))
+		if err := fsys.Remove(link); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error deleting %s: %s", link, err)
+		}
+	}
+
+	err = fsys.MkdirAll(root, dirPerm)
+	if err != nil {
+		return fmt.Errorf("error creating directory %s: %s", root, err)
+	}
+
+	dirs := linkFarmDirectories(d)
+	for _, rel := range rels {
+		link := filepath.Join(root, filepath.FromSlash(rel))
+		created, err := mkdirFarm(fsys, d, root, filepath.ToSlash(filepath.Dir(filepath.FromSlash(rel))))
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, created...)
+
+		if target, err := fsys.Readlink(link); err == nil && target == links[rel] {
+			continue
+		}
+		if err := fsys.Symlink(links[rel], link); err != nil {
+			return fmt.Errorf("error creating link %s: %s", link, err)
+		}
+	}
+
+	// Directories that only held removed links go with them
+	dirs, err = pruneFarmDirectories(fsys, root, dirs)
+	if err != nil {
+		return err
+	}
+	return d.Set("directories", dirs)
+}
+
+func resourceLinkFarmCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	path := d.Get("path").(string)
+
+	err := applyLinkFarm(fsys, d, conf.defaults.dirPerm())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	return resourceLinkFarmRead(ctx, d, meta)
+}
+
+func resourceLinkFarmRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	root := d.Get("path").(string)
+	source := d.Get("source").(string)
+
+	// Keep the links that are still in place, warning about those that are
+	// broken, and leave out those that are gone or were taken over
+	links := map[string]string{}
+	for rel := range d.Get("links").(map[string]interface{}) {
+		link := filepath.Join(root, filepath.FromSlash(rel))
+		info, err := fsys.Lstat(link)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading link %s: %s", link, err))
+		}
+
+		target, ok := "", false
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, ok = ownLink(fsys, link, source)
+		}
+		if !ok {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "Link replaced outside Terraform",
+				Detail:   fmt.Sprintf("%s no longer links into %s. It is linked again on the next apply, which fails unless force is set.", link, source),
+			})
+			continue
+		}
+		// A broken link is still in place, and is removed once the source
+		// no longer has what it points to
+		if _, err := fsys.Stat(link); os.IsNotExist(err) {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "Broken link",
+				Detail:   fmt.Sprintf("%s points to %s, which doesn't exist.", link, target),
+			})
+		}
+		links[rel] = target
+	}
+
+	var dirs []string
+	for _, rel := range linkFarmDirectories(d) {
+		if info, err := fsys.Stat(filepath.Join(root, filepath.FromSlash(rel))); err == nil && info.IsDir() {
+			dirs = append(dirs, rel)
+		}
+	}
+
+	if err := d.Set("links", links); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("directories", dirs); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
+
+func resourceLinkFarmUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+
+	err := applyLinkFarm(fsys, d, conf.defaults.dirPerm())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceLinkFarmRead(ctx, d, meta)
+}
+
+func resourceLinkFarmDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	root := d.Get("path").(string)
+	source := d.Get("source").(string)
+
+	// Only delete the links that still point into the source
+	for rel := range d.Get("links").(map[string]interface{}) {
+		link := filepath.Join(root, filepath.FromSlash(rel))
+		if _, ok := ownLink(fsys, link, source); !ok {
+			continue
+		}
+		if err := fsys.Remove(link); err != nil && !os.IsNotExist(err) {
+			return diag.FromErr(fmt.Errorf("error deleting link %s: %s", link, err))
+		}
+	}
+
+	_, err := pruneFarmDirectories(fsys, root, linkFarmDirectories(d))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
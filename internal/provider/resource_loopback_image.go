@@ -0,0 +1,360 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourceLoopbackImage() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceLoopbackImageCreate,
+		ReadContext:   resourceLoopbackImageRead,
+		UpdateContext: resourceLoopbackImageUpdate,
+		DeleteContext: resourceLoopbackImageDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The path to the image file. It is deleted on destroy",
+			},
+			"size": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "The size of the image in bytes. The image is sparse, so it only takes up the space written to it",
+			},
+			"type": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"ext4", "xfs", "btrfs"}, false),
+				Description:  "The filesystem type, 'ext4', 'xfs' or 'btrfs'",
+			},
+			"label": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The label of the filesystem",
+			},
+			"format_options": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Additional arguments to mkfs (e.g., ['-m', '0'])",
+			},
+			"mountpoint": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The directory to mount the filesystem on. It is created when missing. The filesystem isn't mounted when unset",
+			},
+			"mount_options": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Options to mount the filesystem with (e.g., ['noatime'])",
+			},
+			"loop_device": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The loop device the image is attached to (e.g., '/dev/loop0')",
+			},
+			"uuid": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The UUID of the filesystem",
+			},
+		},
+	}
+}
+
+// loopDevice returns the loop device that path is attached to, or "" when
+// it isn't attached.
+func loopDevice(ctx context.Context, fsys fileSystem, path string) (string, error) {
+	target, err := fsys.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	out, err := fsys.Probe(ctx, commandLine(fsys, "losetup", "--noheadings", "--output", "NAME", "--associated", target))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], nil
+}
+
+// loopMountpoint returns where device is mounted, or "" when it isn't.
+func loopMountpoint(fsys fileSystem, device string) (string, error) {
+	mounts, err := fsys.Mounts()
+	if err != nil {
+		return "", err
+	}
+	for _, m := range mounts {
+		if m.device == device {
+			return m.mountpoint, nil
+		}
+	}
+	return "", nil
+}
+
+// mountLoopDevice mounts device as configured, when a mountpoint is set.
+func mountLoopDevice(ctx context.Context, fsys fileSystem, d *schema.ResourceData, device string, dirPerm os.FileMode) error {
+	mountpoint := d.Get("mountpoint").(string)
+	if mountpoint == "" {
+		return nil
+	}
+
+	err := fsys.MkdirAll(mountpoint, dirPerm)
+	if err != nil {
+		return fmt.Errorf("error creating directory %s: %s", mountpoint, err)
+	}
+
+	args := []string{"mount"}
+	var options []string
+	for _, v := range d.Get("mount_options").([]interface{}) {
+		options = append(options, v.(string))
+	}
+	if len(options) > 0 {
+		args = append(args, "-o", strings.Join(options, ","))
+	}
+	target, err := fsys.Resolve(mountpoint)
+	if err != nil {
+		return fmt.Errorf("error mounting %s on %s: %s", device, mountpoint, err)
+	}
+	_, err = fsys.Run(ctx, commandLine(fsys, append(args, device, target)...))
+	if err != nil {
+		return fmt.Errorf("error mounting %s on %s: %s", device, mountpoint, err)
+	}
+	return nil
+}
+
+// detachLoopImage unmounts and detaches the image at path, whatever is left
+// of it.
+func detachLoopImage(ctx context.Context, fsys fileSystem, path string) error {
+	device, err := loopDevice(ctx, fsys, path)
+	if err != nil || device == "" {
+		return err
+	}
+	for {
+		mountpoint, err := loopMountpoint(fsys, device)
+		if err != nil {
+			return err
+		}
+		if mountpoint == "" {
+			break
+		}
+		_, err = fsys.Run(ctx, commandLine(fsys, "umount", mountpoint))
+		if err != nil {
+			return fmt.Errorf("error unmounting %s: %s", mountpoint, err)
+		}
+	}
+	_, err = fsys.Run(ctx, commandLine(fsys, "losetup", "--detach", device))
+	if err != nil {
+		return fmt.Errorf("error detaching %s: %s", device, err)
+	}
+	return nil
+}
+
+func resourceLoopbackImageCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	path := d.Get("path").(string)
+	fsType := d.Get("type").(string)
+	label := d.Get("label").(string)
+	target, err := fsys.Resolve(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error creating image %s: %s", path, err))
+	}
+
+	// An image that already holds the filesystem as configured, such as one
+	// that was detached by a reboot, is attached again rather than replaced
+	created := false
+	_, err = fsys.Stat(path)
+	switch {
+	case err == nil:
+		sb, err := readSuperblock(fsys, path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading image %s: %s", path, err))
+		}
+		if sb == nil || sb.fsType != fsType || sb.label != label {
+			return diag.FromErr(fmt.Errorf("path %s already exists and doesn't hold the filesystem as configured", path))
+		}
+	case os.IsNotExist(err):
+		err := fsys.MkdirAll(filepath.Dir(path), conf.defaults.dirPerm())
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error creating directory %s: %s", filepath.Dir(path), err))
+		}
+		_, err = fsys.Run(ctx, commandLine(fsys, "truncate", "--size", strconv.Itoa(d.Get("size").(int)), target))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error creating image %s: %s", path, err))
+		}
+		created = true
+
+		var options []string
+		for _, v := range d.Get("format_options").([]interface{}) {
+			options = append(options, v.(string))
+		}
+		_, err = fsys.Run(ctx, mkfsCommand(fsys, fsType, target, label, "", options, false))
+		if err != nil {
+			fsys.Remove(path)
+			return diag.FromErr(fmt.Errorf("error formatting image %s: %s", path, err))
+		}
+	default:
+		return diag.FromErr(fmt.Errorf("error reading image %s: %s", path, err))
+	}
+
+	// Undo what was done so far when attaching or mounting fails, so that
+	// the next apply starts over
+	undo := func() {
+		detachLoopImage(ctx, fsys, path)
+		if created {
+			fsys.Remove(path)
+		}
+	}
+
+	device, err := loopDevice(ctx, fsys, path)
+	if err == nil && device == "" {
+		var out string
+		out, err = fsys.Run(ctx, commandLine(fsys, "losetup", "--find", "--show", target))
+		device = strings.TrimSpace(out)
+	}
+	if err != nil {
+		undo()
+		return diag.FromErr(fmt.Errorf("error attaching image %s: %s", path, err))
+	}
+
+	mountpoint, err := loopMountpoint(fsys, device)
+	if err == nil && mountpoint == "" {
+		err = mountLoopDevice(ctx, fsys, d, device, conf.defaults.dirPerm())
+	}
+	if err != nil {
+		undo()
+		return diag.FromErr(err)
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	return resourceLoopbackImageRead(ctx, d, meta)
+}
+
+func resourceLoopbackImageRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Check if the image exists
+	sb, err := readSuperblock(fsys, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The image was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading image %s: %s", path, err))
+	}
+	if sb == nil {
+		sb = &filesystemSuperblock{}
+	}
+
+	device, err := loopDevice(ctx, fsys, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading image %s: %s", path, err))
+	}
+	if device == "" {
+		// The image was detached, so it is attached again
+		d.SetId("")
+		return diags
+	}
+
+	// A filesystem that was unmounted shows as a change of mountpoint, so
+	// that it is mounted again
+	mountpoint, err := loopMountpoint(fsys, device)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading mounts: %s", err))
+	}
+
+	for k, v := range map[string]interface{}{
+		"type":        sb.fsType,
+		"label":       sb.label,
+		"uuid":        sb.uuid,
+		"loop_device": device,
+		"mountpoint":  mountpoint,
+	} {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
+
+func resourceLoopbackImageUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	device := d.Get("loop_device").(string)
+
+	// Remount the filesystem where it should be, with its current options
+	mountpoint, err := loopMountpoint(fsys, device)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading mounts: %s", err))
+	}
+	if mountpoint != "" {
+		_, err := fsys.Run(ctx, commandLine(fsys, "umount", mountpoint))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error unmounting %s: %s", mountpoint, err))
+		}
+	}
+	err = mountLoopDevice(ctx, fsys, d, device, conf.defaults.dirPerm())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceLoopbackImageRead(ctx, d, meta)
+}
+
+func resourceLoopbackImageDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Unmount and detach the image before deleting it
+	err := detachLoopImage(ctx, fsys, path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = fsys.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("error deleting image %s: %s", path, err))
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
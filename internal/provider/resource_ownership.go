@@ -0,0 +1,270 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceOwnership() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceOwnershipCreate,
+		ReadContext:   resourceOwnershipRead,
+		UpdateContext: resourceOwnershipUpdate,
+		DeleteContext: resourceOwnershipDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The path to an existing file or directory whose ownership is managed",
+			},
+			"owner": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				AtLeastOneOf: []string{"owner", "group"},
+				Description:  "The user name or numeric ID that should own the path. Left alone when unset",
+			},
+			"group": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The group name or numeric ID that should own the path. Left alone when unset",
+			},
+			"recursive": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Also apply the ownership to everything below a directory. Symlinks are changed themselves rather than followed",
+			},
+			"excludes": excludesSchema("Gitignore-style patterns, relative to the path, of what recursive leaves alone (e.g., '*.sock' or 'cache/')"),
+			"restore_on_destroy": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Put back the owner and group that paths had before this resource changed them when it is destroyed",
+			},
+			"previous_ownership": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The numeric uid:gid each changed path had before this resource first changed it, by path",
+			},
+		},
+	}
+}
+
+// ownershipFromResourceData resolves owner and group to numeric IDs, with
+// -1 for those that aren't set.
+func ownershipFromResourceData(fsys fileSystem, d *schema.ResourceData) (uid, gid int, err error) {
+	uid, gid = -1, -1
+	if owner := d.Get("owner").(string); owner != "" {
+		if uid, err = fsys.LookupUID(owner); err != nil {
+			return 0, 0, err
+		}
+	}
+	if group := d.Get("group").(string); group != "" {
+		if gid, err = fsys.LookupGID(group); err != nil {
+			return 0, 0, err
+		}
+	}
+	return uid, gid, nil
+}
+
+// walkOwnership calls fn for path and, when recursive, everything below it
+// that excludes doesn't match with its current owner and group. Paths whose
+// owner can't be determined, as on Windows, are skipped.
+func walkOwnership(fsys fileSystem, path string, recursive bool, excludes *excludeMatcher, fn func(path string, uid, gid int) error) error {
+	return walkDirExcluding(fsys, path, excludes, func(child string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if uid, gid, ok := fileOwner(info); ok {
+			if err := fn(child, uid, gid); err != nil {
+				return err
+			}
+		}
+
+		if !recursive && entry.IsDir() {
+			return fs.SkipDir
+		}
+		return nil
+	})
+}
+
+// chownFromOwnershipResourceData applies the ownership, recording the
+// previous one of every path it changes for the first time.
+func chownFromOwnershipResourceData(fsys fileSystem, d *schema.ResourceData, path string, parallelism int) error {
+	wantUID, wantGID, err := ownershipFromResourceData(fsys, d)
+	if err != nil {
+		return err
+	}
+	excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+	if err != nil {
+		return err
+	}
+
+	previous := d.Get("previous_ownership").(map[string]interface{})
+	pool := newWorkerPool(parallelism)
+	err = walkOwnership(fsys, path, d.Get("recursive").(bool), excludes, func(child string, uid, gid int) error {
+		if (wantUID == -1 || uid == wantUID) && (wantGID == -1 || gid == wantGID) {
+			return nil
+		}
+		if _, ok := previous[child]; !ok {
+			previous[child] = fmt.Sprintf("%d:%d", uid, gid)
+		}
+		return pool.Go(func() error { return fsys.Lchown(child, wantUID, wantGID) })
+	})
+	if werr := pool.Wait(); err == nil {
+		err = werr
+	}
+
+	// Record what was changed even when a later path failed, so destroy can
+	// still restore it
+	if serr := d.Set("previous_ownership", previous); err == nil {
+		err = serr
+	}
+	return err
+}
+
+func resourceOwnershipCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// The path must already exist, as this resource never creates it
+	if _, err := fsys.Lstat(path); err != nil {
+		return diag.FromErr(fmt.Errorf("error reading %s: %s", path, err))
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	err := chownFromOwnershipResourceData(fsys, d, path, meta.(*providerConfig).parallelism)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting ownership of %s: %s", path, err))
+	}
+
+	return resourceOwnershipRead(ctx, d, meta)
+}
+
+func resourceOwnershipRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	wantUID, wantGID, err := ownershipFromResourceData(fsys, d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Report drift with the owner or group of the first path that deviates
+	driftUID, driftGID := -1, -1
+	err = walkOwnership(fsys, path, d.Get("recursive").(bool), excludes, func(child string, uid, gid int) error {
+		if driftUID == -1 && wantUID != -1 && uid != wantUID {
+			driftUID = uid
+		}
+		if driftGID == -1 && wantGID != -1 && gid != wantGID {
+			driftGID = gid
+		}
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The path was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading ownership of %s: %s", path, err))
+	}
+
+	if driftUID != -1 {
+		if err := d.Set("owner", strconv.Itoa(driftUID)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	if driftGID != -1 {
+		if err := d.Set("group", strconv.Itoa(driftGID)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return diags
+}
+
+func resourceOwnershipUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	if d.HasChanges("owner", "group", "recursive", "excludes") {
+		err := chownFromOwnershipResourceData(fsys, d, path, meta.(*providerConfig).parallelism)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting ownership of %s: %s", path, err))
+		}
+	}
+
+	return resourceOwnershipRead(ctx, d, meta)
+}
+
+func resourceOwnershipDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+
+	// Put back the recorded ownership of the paths that still exist. The
+	// paths themselves are left in place
+	if d.Get("restore_on_destroy").(bool) {
+		for child, v := range d.Get("previous_ownership").(map[string]interface{}) {
+			uid, gid, err := parseOwnership(v.(string))
+			if err != nil {
+				return diag.FromErr(err)
+			}
+			err = fsys.Lchown(child, uid, gid)
+			if err != nil && !os.IsNotExist(err) {
+				return diag.FromErr(fmt.Errorf("error restoring ownership of %s: %s", child, err))
+			}
+		}
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
+
+// parseOwnership parses a uid:gid pair recorded in previous_ownership.
+func parseOwnership(s string) (uid, gid int, err error) {
+	u, g, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid ownership: %s", s)
+	}
+	if uid, err = strconv.Atoi(u); err != nil {
+		return 0, 0, fmt.Errorf("invalid ownership: %s", s)
+	}
+	if gid, err = strconv.Atoi(g); err != nil {
+		return 0, 0, fmt.Errorf("invalid ownership: %s", s)
+	}
+	return uid, gid, nil
+}
@@ -0,0 +1,170 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourcePatch() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourcePatchCreate,
+		ReadContext:   resourcePatchRead,
+		UpdateContext: resourcePatchUpdate,
+		DeleteContext: resourcePatchDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The path to the file to patch",
+			},
+			"patch": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "A unified diff for a single file. File names in the headers are ignored",
+				ValidateFunc: func(v interface{}, k string) ([]string, []error) {
+					if _, err := parseUnifiedDiff(v.(string)); err != nil {
+						return nil, []error{fmt.Errorf("%s: %s", k, err)}
+					}
+					return nil, nil
+				},
+			},
+			"lock":         lockSchema(),
+			"lock_timeout": lockTimeoutSchema(),
+		},
+	}
+}
+
+func resourcePatchCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	hunks, err := parseUnifiedDiff(d.Get("patch").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	unlock, err := lockFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer unlock()
+
+	content, err := fsys.ReadFile(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+	}
+
+	patched, err := applyHunks(string(content), hunks)
+	if err != nil {
+		// Adopt a patch that has already been applied, like patch -N
+		if _, rerr := applyHunks(string(content), reverseHunks(hunks)); rerr != nil {
+			return diag.FromErr(fmt.Errorf("error patching file %s: %s", path, err))
+		}
+	} else {
+		// Write the patched content, keeping the existing permissions
+		err = fsys.WriteFile(path, []byte(patched), 0644)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error writing file %s: %s", path, err))
+		}
+	}
+	unlock()
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	return resourcePatchRead(ctx, d, meta)
+}
+
+func resourcePatchRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	hunks, err := parseUnifiedDiff(d.Get("patch").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	content, err := fsys.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// File was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+	}
+
+	// The patch is in place when reversing it would succeed
+	if _, err := applyHunks(string(content), reverseHunks(hunks)); err != nil {
+		d.SetId("")
+		return diags
+	}
+
+	return diags
+}
+
+// resourcePatchUpdate only sees changes to how the file is locked, as
+// everything else forces a new patch.
+func resourcePatchUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	return resourcePatchRead(ctx, d, meta)
+}
+
+func resourcePatchDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	hunks, err := parseUnifiedDiff(d.Get("patch").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	unlock, err := lockFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer unlock()
+
+	content, err := fsys.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading file %s: %s", path, err))
+	}
+
+	// Reverse the patch
+	original, err := applyHunks(string(content), reverseHunks(hunks))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reverting patch on file %s: %s", path, err))
+	}
+
+	err = fsys.WriteFile(path, []byte(original), 0644)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error writing file %s: %s", path, err))
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
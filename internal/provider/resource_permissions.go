@@ -0,0 +1,214 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourcePermissions() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourcePermissionsCreate,
+		ReadContext:   resourcePermissionsRead,
+		UpdateContext: resourcePermissionsUpdate,
+		DeleteContext: resourcePermissionsDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The path to an existing file or directory whose permissions are managed",
+			},
+			"permissions": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateModeSpec,
+				Description:  "Permissions in octal (e.g., '0640') or symbolic chmod format (e.g., 'go-w' or 'u=rwX,go=rX')",
+			},
+			"recursive": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Also apply the permissions to everything below a directory, skipping symlinks like chmod -R does",
+			},
+			"excludes": excludesSchema("Gitignore-style patterns, relative to the path, of what recursive leaves alone (e.g., '*.sock' or 'cache/')"),
+			"restore_on_destroy": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Put back the permissions that paths had before this resource changed them when it is destroyed",
+			},
+			"previous_permissions": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The permissions each changed path had before this resource first changed it, by path",
+			},
+		},
+	}
+}
+
+// walkPermissions calls fn for path and, when recursive, everything below
+// it that excludes doesn't match with its current permissions and those
+// that spec gives it.
+func walkPermissions(fsys fileSystem, path string, spec *modeSpec, recursive bool, excludes *excludeMatcher, fn func(path string, isDir bool, current, wanted os.FileMode) error) error {
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(path, info.IsDir(), permissionBits(info.Mode()), spec.apply(info.Mode(), info.IsDir())); err != nil {
+		return err
+	}
+
+	if recursive && info.IsDir() {
+		return walkModes(fsys, path, spec, spec, excludes, fn)
+	}
+	return nil
+}
+
+// chmodFromPermissionsResourceData applies the permissions, recording the
+// previous ones of every path it changes for the first time.
+func chmodFromPermissionsResourceData(fsys fileSystem, d *schema.ResourceData, path string, parallelism int) error {
+	spec, err := parseModeSpec(d.Get("permissions").(string))
+	if err != nil {
+		return err
+	}
+	excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+	if err != nil {
+		return err
+	}
+
+	previous := d.Get("previous_permissions").(map[string]interface{})
+	pool := newWorkerPool(parallelism)
+	err = walkPermissions(fsys, path, spec, d.Get("recursive").(bool), excludes, func(child string, isDir bool, current, wanted os.FileMode) error {
+		if current == wanted {
+			return nil
+		}
+		if _, ok := previous[child]; !ok {
+			previous[child] = formatPermissions(current)
+		}
+		return chmodInPool(fsys, pool, child, isDir, wanted)
+	})
+	if werr := pool.Wait(); err == nil {
+		err = werr
+	}
+
+	// Record what was changed even when a later path failed, so destroy can
+	// still restore it
+	if serr := d.Set("previous_permissions", previous); err == nil {
+		err = serr
+	}
+	return err
+}
+
+func resourcePermissionsCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// The path must already exist, as this resource never creates it
+	if _, err := fsys.Stat(path); err != nil {
+		return diag.FromErr(fmt.Errorf("error reading %s: %s", path, err))
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	err := chmodFromPermissionsResourceData(fsys, d, path, meta.(*providerConfig).parallelism)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting permissions for %s: %s", path, err))
+	}
+
+	return resourcePermissionsRead(ctx, d, meta)
+}
+
+func resourcePermissionsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	spec, err := parseModeSpec(d.Get("permissions").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	excludes, err := newExcludeMatcher(d.Get("excludes").([]interface{}))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Report drift with the permissions of the first path that deviates
+	drift := ""
+	err = walkPermissions(fsys, path, spec, d.Get("recursive").(bool), excludes, func(child string, isDir bool, current, wanted os.FileMode) error {
+		if drift == "" && current != wanted {
+			drift = formatPermissions(current)
+		}
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The path was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading permissions of %s: %s", path, err))
+	}
+
+	if drift != "" {
+		if err := d.Set("permissions", drift); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return diags
+}
+
+func resourcePermissionsUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	if d.HasChanges("permissions", "recursive", "excludes") {
+		err := chmodFromPermissionsResourceData(fsys, d, path, meta.(*providerConfig).parallelism)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error setting permissions for %s: %s", path, err))
+		}
+	}
+
+	return resourcePermissionsRead(ctx, d, meta)
+}
+
+func resourcePermissionsDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+
+	// Put back the recorded permissions of the paths that still exist. The
+	// paths themselves are left in place
+	if d.Get("restore_on_destroy").(bool) {
+		for child, v := range d.Get("previous_permissions").(map[string]interface{}) {
+			perm, err := parsePermissions(v.(string))
+			if err != nil {
+				return diag.FromErr(err)
+			}
+			err = fsys.Chmod(child, perm)
+			if err != nil && !os.IsNotExist(err) {
+				return diag.FromErr(fmt.Errorf("error restoring permissions of %s: %s", child, err))
+			}
+		}
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
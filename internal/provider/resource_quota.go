@@ -0,0 +1,327 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// quotaFlags are the setquota and repquota flags of each type of quota.
+var quotaFlags = map[string]string{"user": "-u", "group": "-g", "project": "-P"}
+
+func resourceQuota() *schema.Resource {
+	limit := func(description string) *schema.Schema {
+		return &schema.Schema{
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Default:      0,
+			ValidateFunc: validation.IntAtLeast(0),
+			Description:  description,
+		}
+	}
+
+	return &schema.Resource{
+		CreateContext: resourceQuotaCreate,
+		ReadContext:   resourceQuotaRead,
+		UpdateContext: resourceQuotaUpdate,
+		DeleteContext: resourceQuotaDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "A directory on the filesystem the quota applies to. For project quotas, the directory tree that makes up the project",
+			},
+			"type": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"user", "group", "project"}, false),
+				Description:  "The type of quota, 'user', 'group' or 'project'",
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The user or group name or numeric ID, or the numeric ID of the project",
+			},
+			"block_soft_limit": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          0,
+				ValidateFunc:     validation.IntAtLeast(0),
+				DiffSuppressFunc: diffSuppressQuotaBlocks,
+				Description:      "The number of bytes that may be used for the grace period, rounded up to KiB. 0 is no limit",
+			},
+			"block_hard_limit": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Default:          0,
+				ValidateFunc:     validation.IntAtLeast(0),
+				DiffSuppressFunc: diffSuppressQuotaBlocks,
+				Description:      "The number of bytes that may never be exceeded, rounded up to KiB. 0 is no limit",
+			},
+			"inode_soft_limit": limit("The number of files and directories that may exist for the grace period. 0 is no limit"),
+			"inode_hard_limit": limit("The number of files and directories that may never be exceeded. 0 is no limit"),
+			"mountpoint": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The mount point of the filesystem the quota is set on",
+			},
+			"bytes_used": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of bytes currently used",
+			},
+			"inodes_used": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of files and directories that currently exist",
+			},
+		},
+	}
+}
+
+// diffSuppressQuotaBlocks ignores differences within the KiB that block
+// limits are kept in.
+func diffSuppressQuotaBlocks(k, old, new string, d *schema.ResourceData) bool {
+	o, err := strconv.ParseInt(old, 10, 64)
+	if err != nil {
+		return false
+	}
+	n, err := strconv.ParseInt(new, 10, 64)
+	return err == nil && quotaKiB(o) == quotaKiB(n)
+}
+
+func quotaKiB(n int64) int64 {
+	return (n + 1023) / 1024
+}
+
+// quotaUsage is a row of repquota.
+type quotaUsage struct {
+	bytes, blockSoft, blockHard  int64
+	inodes, inodeSoft, inodeHard int64
+}
+
+// quotaMount returns the mount point of the filesystem holding path, along
+// with its type.
+func quotaMount(fsys fileSystem, path string) (mountpoint, fstype string, err error) {
+	resolved, err := fsys.Resolve(path)
+	if err != nil {
+		return "", "", err
+	}
+	mounts, err := fsys.Mounts()
+	if err != nil {
+		return "", "", err
+	}
+
+	// The innermost mount holding the path wins
+	for _, m := range mounts {
+		if withinDir(m.mountpoint, resolved) && len(m.mountpoint) >= len(mountpoint) {
+			mountpoint, fstype = m.mountpoint, m.fstype
+		}
+	}
+	if mountpoint == "" {
+		return "", "", fmt.Errorf("no mount holds %s", path)
+	}
+	return mountpoint, fstype, nil
+}
+
+// quotaID returns the numeric ID that a quota of the resource is kept for.
+func quotaID(fsys fileSystem, d *schema.ResourceData) (int, error) {
+	name := d.Get("name").(string)
+	switch d.Get("type").(string) {
+	case "user":
+		return fsys.LookupUID(name)
+	case "group":
+		return fsys.LookupGID(name)
+	}
+	id, err := strconv.Atoi(name)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid project ID %q", name)
+	}
+	return id, nil
+}
+
+// readQuota returns the usage and limits of id from repquota, which lists
+// every ID that uses anything or has limits.
+func readQuota(ctx context.Context, fsys fileSystem, quotaType, mountpoint string, id int) (quotaUsage, error) {
+	var q quotaUsage
+	out, err := fsys.Probe(ctx, commandLine(fsys, "repquota", quotaFlags[quotaType], "-n", "-p", mountpoint))
+	if err != nil {
+		return q, err
+	}
+
+	// Rows are "#ID flags used soft hard grace used soft hard grace", with
+	// blocks in KiB
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 10 || fields[0] != "#"+strconv.Itoa(id) {
+			continue
+		}
+		var values [6]int64
+		for i, field := range []string{fields[2], fields[3], fields[4], fields[6], fields[7], fields[8]} {
+			if values[i], err = strconv.ParseInt(field, 10, 64); err != nil {
+				return q, fmt.Errorf("unexpected repquota output: %q", line)
+			}
+		}
+		return quotaUsage{
+			bytes: values[0] * 1024, blockSoft: values[1] * 1024, blockHard: values[2] * 1024,
+			inodes: values[3], inodeSoft: values[4], inodeHard: values[5],
+		}, nil
+	}
+	return q, nil
+}
+
+// setQuotaFromResourceData sets the limits of the quota, first making path
+// a project when it's a project quota.
+func setQuotaFromResourceData(ctx context.Context, fsys fileSystem, d *schema.ResourceData, path string, project bool) error {
+	quotaType := d.Get("type").(string)
+	mountpoint, fstype, err := quotaMount(fsys, path)
+	if err != nil {
+		return err
+	}
+	id, err := quotaID(fsys, d)
+	if err != nil {
+		return err
+	}
+
+	// Files created below the directory inherit its project
+	if project && quotaType == "project" {
+		target, err := fsys.Resolve(path)
+		if err != nil {
+			return fmt.Errorf("error assigning %s to project %d: %s", path, id, err)
+		}
+		command := commandLine(fsys, "chattr", "-R", "+P", "-p", strconv.Itoa(id), target)
+		if fstype == "xfs" {
+			command = commandLine(fsys, "xfs_quota", "-x", "-c", fmt.Sprintf("project -s -p %s %d", fsys.Quote(target), id), mountpoint)
+		}
+		if _, err := fsys.Run(ctx, command); err != nil {
+			return fmt.Errorf("error assigning %s to project %d: %s", path, id, err)
+		}
+	}
+
+	_, err = fsys.Run(ctx, commandLine(fsys, "setquota", quotaFlags[quotaType], strconv.Itoa(id),
+		strconv.FormatInt(quotaKiB(int64(d.Get("block_soft_limit").(int))), 10),
+		strconv.FormatInt(quotaKiB(int64(d.Get("block_hard_limit").(int))), 10),
+		strconv.Itoa(d.Get("inode_soft_limit").(int)),
+		strconv.Itoa(d.Get("inode_hard_limit").(int)),
+		mountpoint))
+	return err
+}
+
+func resourceQuotaCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// The directory must already exist, as this resource never creates it
+	if info, err := fsys.Stat(path); err != nil || !info.IsDir() {
+		return diag.FromErr(fmt.Errorf("directory %s does not exist", path))
+	}
+
+	err := setQuotaFromResourceData(ctx, fsys, d, path, true)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting quota for %s: %s", path, err))
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	return resourceQuotaRead(ctx, d, meta)
+}
+
+func resourceQuotaRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Check if the directory exists
+	if _, err := fsys.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			// The directory was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading %s: %s", path, err))
+	}
+
+	mountpoint, _, err := quotaMount(fsys, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading quota for %s: %s", path, err))
+	}
+	id, err := quotaID(fsys, d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	q, err := readQuota(ctx, fsys, d.Get("type").(string), mountpoint, id)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading quota for %s: %s", path, err))
+	}
+
+	for k, v := range map[string]interface{}{
+		"mountpoint":       mountpoint,
+		"bytes_used":       int(q.bytes),
+		"inodes_used":      int(q.inodes),
+		"block_soft_limit": int(q.blockSoft),
+		"block_hard_limit": int(q.blockHard),
+		"inode_soft_limit": int(q.inodeSoft),
+		"inode_hard_limit": int(q.inodeHard),
+	} {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
+
+func resourceQuotaUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	err := setQuotaFromResourceData(ctx, fsys, d, path, false)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error setting quota for %s: %s", path, err))
+	}
+
+	return resourceQuotaRead(ctx, d, meta)
+}
+
+func resourceQuotaDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Lift the limits. Files keep the project they were assigned to
+	for _, k := range []string{"block_soft_limit", "block_hard_limit", "inode_soft_limit", "inode_hard_limit"} {
+		if err := d.Set(k, 0); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	err := setQuotaFromResourceData(ctx, fsys, d, path, false)
+	if err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("error removing quota for %s: %s", path, err))
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
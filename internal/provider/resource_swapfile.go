@@ -0,0 +1,486 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// fstabPath is where the swap files enabled on boot are listed.
+const fstabPath = "/etc/fstab"
+
+func resourceSwapfile() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceSwapfileCreate,
+		ReadContext:   resourceSwapfileRead,
+		UpdateContext: resourceSwapfileUpdate,
+		DeleteContext: resourceSwapfileDelete,
+		CustomizeDiff: func(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+			// A swap file that no longer holds a swap area gets a new one,
+			// with a new UUID, as it's enabled again
+			if d.Id() != "" && d.Get("active").(bool) && d.Get("uuid").(string) == "" {
+				return d.SetNewComputed("uuid")
+			}
+			return nil
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The path to the swap file. It is deleted on destroy",
+			},
+			"size": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IntAtLeast(40960),
+				Description:  "The size of the swap file in bytes, at least 10 pages of 4 KiB. It is allocated up front, as swap files can't be sparse",
+			},
+			"label": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringLenBetween(0, 16),
+				Description:  "The label of the swap area, of up to 16 bytes",
+			},
+			"priority": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      -1,
+				ValidateFunc: validation.IntBetween(-1, 32767),
+				Description:  "The priority of the swap area, from 0 to 32767. Areas with a higher priority are used first. -1 leaves it to the kernel",
+			},
+			"active": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Whether the swap file is enabled. A swap file that was disabled, such as by a reboot, or whose swap area was overwritten, is enabled again",
+			},
+			"fstab": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Add an entry for the swap file to /etc/fstab, so that it is enabled on boot. The entry is removed on destroy",
+			},
+			"uuid": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The UUID of the swap area",
+			},
+		},
+	}
+}
+
+// swapArea is an entry of /proc/swaps.
+type swapArea struct {
+	path     string
+	priority int
+}
+
+// activeSwap returns the swap area that the resolved path target is enabled
+// as, or nil when it isn't enabled. /proc/swaps is read from system.
+func activeSwap(system fileSystem, target string) (*swapArea, error) {
+	data, err := system.ReadFile("/proc/swaps")
+	if err != nil {
+		return nil, err
+	}
+
+	// The first line holds the column names
+	lines := splitLines(string(data))
+	if len(lines) > 0 {
+		lines = lines[1:]
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || unescapeMountField(fields[0]) != target {
+			continue
+		}
+		priority, err := strconv.Atoi(fields[4])
+		if err != nil {
+			return nil, fmt.Errorf("malformed /proc/swaps line %q", line)
+		}
+		return &swapArea{path: target, priority: priority}, nil
+	}
+	return nil, nil
+}
+
+// mkswap formats the swap file at path, resolved to target, with the
+// configured label.
+func mkswap(ctx context.Context, fsys fileSystem, d *schema.ResourceData, path, target string) error {
+	args := []string{"mkswap"}
+	if label := d.Get("label").(string); label != "" {
+		args = append(args, "--label", label)
+	}
+	_, err := fsys.Run(ctx, commandLine(fsys, append(args, target)...))
+	if err != nil {
+		return fmt.Errorf("error formatting swap file %s: %s", path, err)
+	}
+	return nil
+}
+
+// swapon enables the swap file at path, resolved to target, with the
+// configured priority.
+func swapon(ctx context.Context, fsys fileSystem, d *schema.ResourceData, path, target string) error {
+	args := []string{"swapon"}
+	if priority := d.Get("priority").(int); priority >= 0 {
+		args = append(args, "--priority", strconv.Itoa(priority))
+	}
+	_, err := fsys.Run(ctx, commandLine(fsys, append(args, target)...))
+	if err != nil {
+		return fmt.Errorf("error enabling swap file %s: %s", path, err)
+	}
+	return nil
+}
+
+// swapoff disables the swap file at path, resolved to target. What it holds
+// moves back into memory, so it fails when there isn't enough of it.
+func swapoff(ctx context.Context, fsys fileSystem, path, target string) error {
+	_, err := fsys.Run(ctx, commandLine(fsys, "swapoff", target))
+	if err != nil {
+		return fmt.Errorf("error disabling swap file %s: %s", path, err)
+	}
+	return nil
+}
+
+// escapeMountField escapes whitespace and backslashes the way fstab and the
+// kernel do, undone by unescapeMountField.
+func escapeMountField(s string) string {
+	return strings.NewReplacer(" ", `\040`, "\t", `\011`, "\n", `\012`, `\`, `\134`).Replace(s)
+}
+
+// swapFstabEntry returns the fstab line that enables the swap file at the
+// resolved path target as configured.
+func swapFstabEntry(d *schema.ResourceData, target string) string {
+	options := "sw"
+	if priority := d.Get("priority").(int); priority >= 0 {
+		options += ",pri=" + strconv.Itoa(priority)
+	}
+	return fmt.Sprintf("%s none swap %s 0 0", escapeMountField(target), options)
+}
+
+// readFstabEntry returns the line of fstab for path, or "" when it has none.
+func readFstabEntry(fsys fileSystem, path string) (string, error) {
+	data, err := fsys.ReadFile(fstabPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	for _, line := range splitLines(string(data)) {
+		if fields := strings.Fields(line); len(fields) > 0 && unescapeMountField(fields[0]) == path {
+			return line, nil
+		}
+	}
+	return "", nil
+}
+
+// setFstabEntry replaces the lines of fstab for path with entry, or removes
+// them when entry is empty. The other lines are left as they are.
+func setFstabEntry(fsys fileSystem, path, entry string) error {
+	data, err := fsys.ReadFile(fstabPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var lines []string
+	for _, line := range splitLines(string(data)) {
+		if fields := strings.Fields(line); len(fields) > 0 && unescapeMountField(fields[0]) == path {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if entry != "" {
+		lines = append(lines, entry)
+	}
+
+	content := ""
+	if len(lines) > 0 {
+		content = strings.Join(lines, "\n") + "\n"
+	}
+	if content == string(data) {
+		return nil
+	}
+	return writeFileAtomic(fsys, fstabPath, []byte(content), 0644, true, nil)
+}
+
+// fstabEntryFromResourceData adds or removes the fstab entry of the swap
+// file at the resolved path target as configured. fstab is written through
+// system.
+func fstabEntryFromResourceData(system fileSystem, d *schema.ResourceData, target string) error {
+	entry := ""
+	if d.Get("fstab").(bool) {
+		entry = swapFstabEntry(d, target)
+	}
+	err := setFstabEntry(system, target, entry)
+	if err != nil {
+		return fmt.Errorf("error updating %s: %s", fstabPath, err)
+	}
+	return nil
+}
+
+func resourceSwapfileCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	system := withContext(ctx, conf.system)
+	path := d.Get("path").(string)
+	size := strconv.Itoa(d.Get("size").(int))
+	target, err := fsys.Resolve(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error creating swap file %s: %s", path, err))
+	}
+
+	// A swap file as configured, such as one that was disabled by a reboot,
+	// is enabled again rather than replaced
+	created := false
+	fileInfo, err := fsys.Stat(path)
+	switch {
+	case err == nil:
+		sb, err := readSuperblock(fsys, path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading swap file %s: %s", path, err))
+		}
+		if sb == nil || sb.fsType != "swap" || sb.label != d.Get("label").(string) || fileInfo.Size() != int64(d.Get("size").(int)) {
+			return diag.FromErr(fmt.Errorf("path %s already exists and isn't a swap file as configured", path))
+		}
+	case os.IsNotExist(err):
+		err := fsys.MkdirAll(filepath.Dir(path), conf.defaults.dirPerm())
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error creating directory %s: %s", filepath.Dir(path), err))
+		}
+
+		// Only root may read swap, which holds the memory of any process
+		w, err := fsys.Create(path, 0600)
+		if err == nil {
+			err = w.Close()
+		}
+		if err == nil {
+			err = fsys.Chmod(path, 0600)
+		}
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error creating swap file %s: %s", path, err))
+		}
+		created = true
+
+		// Filesystems that can't preallocate get the zeros written instead
+		command := commandLine(fsys, "fallocate", "--length", size, target) + " || " + commandLine(fsys, "head", "-c", size, "/dev/zero") + " > " + fsys.Quote(target)
+		_, err = fsys.Run(ctx, command)
+		if err != nil {
+			fsys.Remove(path)
+			return diag.FromErr(fmt.Errorf("error allocating swap file %s: %s", path, err))
+		}
+
+		if err := mkswap(ctx, fsys, d, path, target); err != nil {
+			fsys.Remove(path)
+			return diag.FromErr(err)
+		}
+	default:
+		return diag.FromErr(fmt.Errorf("error reading swap file %s: %s", path, err))
+	}
+
+	// Undo what was done so far when enabling the swap file fails, so that
+	// the next apply starts over
+	enabled := false
+	undo := func() {
+		if enabled {
+			swapoff(ctx, fsys, path, target)
+		}
+		if created {
+			fsys.Remove(path)
+		}
+	}
+
+	area, err := activeSwap(system, target)
+	if err == nil && area == nil && d.Get("active").(bool) {
+		err = swapon(ctx, fsys, d, path, target)
+		enabled = err == nil
+	}
+	if err == nil {
+		err = fstabEntryFromResourceData(system, d, target)
+	}
+	if err != nil {
+		undo()
+		return diag.FromErr(err)
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	return resourceSwapfileRead(ctx, d, meta)
+}
+
+func resourceSwapfileRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	system := withContext(ctx, conf.system)
+	path := d.Get("path").(string)
+
+	// Check if the swap file exists
+	fileInfo, err := fsys.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The swap file was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading swap file %s: %s", path, err))
+	}
+	target, err := fsys.Resolve(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading swap file %s: %s", path, err))
+	}
+	sb, err := readSuperblock(fsys, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading swap file %s: %s", path, err))
+	}
+	area, err := activeSwap(system, target)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading swap areas: %s", err))
+	}
+
+	// A swap file that was disabled, or no longer holds a swap area, shows
+	// as inactive, so that it is formatted and enabled again in place
+	formatted := sb != nil && sb.fsType == "swap"
+	values := map[string]interface{}{
+		"size":   int(fileInfo.Size()),
+		"active": formatted && area != nil,
+		"uuid":   "",
+	}
+	if formatted {
+		values["label"] = sb.label
+		values["uuid"] = sb.uuid
+	}
+
+	// The kernel picks a priority below 0 when none is configured, and a
+	// disabled swap file has none
+	if area != nil && (d.Get("priority").(int) >= 0 || area.priority >= 0) {
+		values["priority"] = area.priority
+	}
+
+	// An fstab entry that went missing or changed shows as fstab being
+	// unset, so that it is written again
+	inFstab := false
+	if d.Get("fstab").(bool) {
+		entry, err := readFstabEntry(system, target)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading %s: %s", fstabPath, err))
+		}
+		inFstab = entry == swapFstabEntry(d, target)
+	}
+	values["fstab"] = inFstab
+
+	for k, v := range values {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return diags
+}
+
+func resourceSwapfileUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	system := withContext(ctx, conf.system)
+	path := d.Get("path").(string)
+	target, err := fsys.Resolve(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error updating swap file %s: %s", path, err))
+	}
+
+	if d.HasChanges("active", "priority") {
+		sb, err := readSuperblock(fsys, path)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading swap file %s: %s", path, err))
+		}
+		area, err := activeSwap(system, target)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error reading swap areas: %s", err))
+		}
+		formatted := sb != nil && sb.fsType == "swap"
+		active := d.Get("active").(bool)
+
+		// The priority is only set when the swap file is enabled
+		if area != nil && (!active || !formatted || d.HasChange("priority")) {
+			if err := swapoff(ctx, fsys, path, target); err != nil {
+				return diag.FromErr(err)
+			}
+			area = nil
+		}
+		if active && !formatted {
+			if err := mkswap(ctx, fsys, d, path, target); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+		if active && area == nil {
+			if err := swapon(ctx, fsys, d, path, target); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+	}
+
+	if d.HasChanges("priority", "fstab") {
+		err := fstabEntryFromResourceData(system, d, target)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return resourceSwapfileRead(ctx, d, meta)
+}
+
+func resourceSwapfileDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	system := withContext(ctx, conf.system)
+	path := d.Get("path").(string)
+	target, err := fsys.Resolve(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error deleting swap file %s: %s", path, err))
+	}
+
+	area, err := activeSwap(system, target)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading swap areas: %s", err))
+	}
+	if area != nil {
+		if err := swapoff(ctx, fsys, path, target); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	err = setFstabEntry(system, target, "")
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error updating %s: %s", fstabPath, err))
+	}
+	err = fsys.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("error deleting swap file %s: %s", path, err))
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
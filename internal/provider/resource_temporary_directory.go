@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceTemporaryDirectory() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceTemporaryDirectoryCreate,
+		ReadContext:   resourceTemporaryDirectoryRead,
+		DeleteContext: resourceTemporaryDirectoryDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"parent": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The directory in which the temporary directory is created. Defaults to the system temporary directory",
+			},
+			"pattern": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     "terraform-",
+				Description: "Name pattern passed to os.MkdirTemp; a trailing '*' is replaced by the random part",
+			},
+			"permissions": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				Default:          "0700",
+				ValidateDiagFunc: validatePermissions,
+				StateFunc:        normalizePermissions,
+				DiffSuppressFunc: diffSuppressPermissions,
+				Description:      "Directory permissions in octal format (e.g., '0700')",
+			},
+			"path": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The path to the created temporary directory",
+			},
+		},
+	}
+}
+
+func resourceTemporaryDirectoryCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	parent := d.Get("parent").(string)
+	pattern := d.Get("pattern").(string)
+	permStr := d.Get("permissions").(string)
+
+	// Parse permissions
+	perm, err := parsePermissions(permStr)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Make sure the parent directory exists
+	if parent != "" {
+		err = fsys.MkdirAll(parent, conf.defaults.dirPerm())
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error creating directory %s: %s", parent, err))
+		}
+	}
+
+	// Create the uniquely-named directory
+	path, err := fsys.MkdirTemp(parent, pattern)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error creating temporary directory in %s: %s", parent, err))
+	}
+
+	// MkdirTemp always uses 0700, so apply the requested permissions
+	err = fsys.Chmod(path, perm)
+	if err != nil {
+		fsys.RemoveAll(path)
+		return diag.FromErr(fmt.Errorf("error setting permissions for directory %s: %s", path, err))
+	}
+
+	if err := d.Set("path", path); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	return resourceTemporaryDirectoryRead(ctx, d, meta)
+}
+
+func resourceTemporaryDirectoryRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Check if the directory exists
+	fileInfo, err := fsys.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Directory was deleted outside of Terraform
+			d.SetId("")
+			return diags
+		}
+		return diag.FromErr(fmt.Errorf("error reading directory %s: %s", path, err))
+	}
+
+	// Ensure it's a directory, not a file
+	if !fileInfo.IsDir() {
+		return diag.FromErr(fmt.Errorf("path %s is a file, not a directory", path))
+	}
+
+	// Set permissions
+	perm := formatPermissions(fileInfo.Mode())
+	if err := d.Set("permissions", perm); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
+
+func resourceTemporaryDirectoryDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Delete the directory and everything in it
+	err := removeAllForce(fsys, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error deleting directory %s: %s", path, err))
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
+
+// removeAllForce behaves like RemoveAll but also clears out subdirectories
+// that were made read-only by whatever used the directory.
+func removeAllForce(fsys fileSystem, path string) error {
+	err := fsys.RemoveAll(path)
+	if err == nil {
+		return nil
+	}
+
+	// Grant ourselves write access on every directory and retry
+	walkDir(fsys, path, func(p string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.IsDir() {
+			fsys.Chmod(p, 0700)
+		}
+		return nil
+	})
+
+	return fsys.RemoveAll(path)
+}
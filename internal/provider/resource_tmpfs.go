@@ -0,0 +1,294 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourceTmpfs() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceTmpfsCreate,
+		ReadContext:   resourceTmpfsRead,
+		UpdateContext: resourceTmpfsUpdate,
+		DeleteContext: resourceTmpfsDelete,
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(20 * time.Minute),
+			Read:   schema.DefaultTimeout(20 * time.Minute),
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(20 * time.Minute),
+		},
+		CustomizeDiff: customizeDiffDefaults(providerDefaults.dirPerm),
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: diffSuppressPath,
+				Description:      "The directory to mount the tmpfs on. It is created when missing, and left in place on destroy",
+			},
+			"size": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "The most memory the tmpfs may use in bytes, rounded up to whole pages. Changing it resizes the tmpfs without losing its content",
+			},
+			"mount_options": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Additional options to mount the tmpfs with (e.g., ['noexec', 'nosuid'])",
+			},
+			"permissions": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ValidateDiagFunc: validatePermissions,
+				StateFunc:        normalizePermissions,
+				DiffSuppressFunc: diffSuppressPermissions,
+				Description:      "Permissions of the root of the tmpfs in octal format (e.g., '1777'). Defaults to the provider's default_directory_permissions",
+			},
+			"owner": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The user name or numeric ID owning the root of the tmpfs. Defaults to the provider's default_owner",
+			},
+			"group": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The group name or numeric ID owning the root of the tmpfs. Defaults to the provider's default_group",
+			},
+			"bytes_used": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of bytes the content of the tmpfs takes up",
+			},
+			"bytes_available": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of bytes that can still be written to the tmpfs",
+			},
+		},
+	}
+}
+
+// tmpfsMount returns the tmpfs mounted on path, or nil when there is none.
+func tmpfsMount(fsys fileSystem, path string) (*mountInfo, error) {
+	resolved, err := fsys.Resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	mounts, err := fsys.Mounts()
+	if err != nil {
+		return nil, err
+	}
+
+	// The last mount on the path is the one that is visible
+	var found *mountInfo
+	for i, m := range mounts {
+		if m.mountpoint == resolved {
+			found = &mounts[i]
+		}
+	}
+	if found == nil || found.fstype != "tmpfs" {
+		return nil, nil
+	}
+	return found, nil
+}
+
+// tmpfsSize returns the size a tmpfs was mounted with, which the kernel
+// reports in KiB, or 0 when it has no limit.
+func tmpfsSize(m *mountInfo) int64 {
+	for _, option := range m.superOptions {
+		v, ok := strings.CutPrefix(option, "size=")
+		if !ok {
+			continue
+		}
+		scale := int64(1)
+		switch {
+		case strings.HasSuffix(v, "k"):
+			scale = 1 << 10
+		case strings.HasSuffix(v, "m"):
+			scale = 1 << 20
+		case strings.HasSuffix(v, "g"):
+			scale = 1 << 30
+		}
+		n, err := strconv.ParseInt(strings.TrimRight(v, "kmg"), 10, 64)
+		if err == nil {
+			return n * scale
+		}
+	}
+	return 0
+}
+
+// tmpfsOptions returns the options to mount a tmpfs with, starting with
+// extra ones such as "remount".
+func tmpfsOptions(d *schema.ResourceData, extra ...string) string {
+	options := append(extra, "size="+strconv.Itoa(d.Get("size").(int)))
+	for _, v := range d.Get("mount_options").([]interface{}) {
+		options = append(options, v.(string))
+	}
+	return strings.Join(options, ",")
+}
+
+// applyTmpfsRootFromResourceData sets the permissions and ownership of the
+// root of the tmpfs.
+func applyTmpfsRootFromResourceData(fsys fileSystem, d *schema.ResourceData, path string) error {
+	perm, err := parsePermissions(d.Get("permissions").(string))
+	if err != nil {
+		return err
+	}
+	err = fsys.Chmod(path, perm)
+	if err != nil {
+		return fmt.Errorf("error setting permissions for %s: %s", path, err)
+	}
+	err = chownFromResourceData(fsys, d, path)
+	if err != nil {
+		return fmt.Errorf("error setting ownership of %s: %s", path, err)
+	}
+	return nil
+}
+
+func resourceTmpfsCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conf := meta.(*providerConfig)
+	fsys := withContext(ctx, conf.fs)
+	path := d.Get("path").(string)
+
+	err := fsys.MkdirAll(path, conf.defaults.dirPerm())
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error creating directory %s: %s", path, err))
+	}
+
+	// Mounting over an existing tmpfs would hide its content
+	m, err := tmpfsMount(fsys, path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading mounts: %s", err))
+	}
+	if m != nil {
+		return diag.FromErr(fmt.Errorf("a tmpfs is already mounted on %s", path))
+	}
+
+	target, err := fsys.Resolve(path)
+	if err == nil {
+		_, err = fsys.Run(ctx, commandLine(fsys, "mount", "-t", "tmpfs", "-o", tmpfsOptions(d), "tmpfs", target))
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error mounting tmpfs on %s: %s", path, err))
+	}
+
+	// Use the path as the ID
+	d.SetId(pathID(fsys, path))
+
+	err = applyTmpfsRootFromResourceData(fsys, d, path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceTmpfsRead(ctx, d, meta)
+}
+
+func resourceTmpfsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Check if the tmpfs is mounted
+	m, err := tmpfsMount(fsys, path)
+	if err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("error reading mounts: %s", err))
+	}
+	if m == nil {
+		// The tmpfs was unmounted outside of Terraform
+		d.SetId("")
+		return diags
+	}
+
+	fileInfo, err := fsys.Stat(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading directory %s: %s", path, err))
+	}
+	usage, err := fsys.DiskUsage(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("error reading usage of %s: %s", path, err))
+	}
+
+	// The kernel rounds the size up to whole pages
+	size := tmpfsSize(m)
+	if configured := int64(d.Get("size").(int)); size >= configured && size-configured < 64<<10 {
+		size = configured
+	}
+
+	for k, v := range map[string]interface{}{
+		"size":            int(size),
+		"permissions":     formatPermissions(fileInfo.Mode()),
+		"bytes_used":      int(usage.totalBytes - usage.freeBytes),
+		"bytes_available": int(usage.availableBytes),
+	} {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return readOwnershipIntoResourceData(fsys, d, fileInfo)
+}
+
+func resourceTmpfsUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Resizing keeps the content, unless it no longer fits
+	if d.HasChange("size") {
+		target, err := fsys.Resolve(path)
+		if err == nil {
+			_, err = fsys.Run(ctx, commandLine(fsys, "mount", "-o", tmpfsOptions(d, "remount"), target))
+		}
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error resizing tmpfs on %s: %s", path, err))
+		}
+	}
+
+	if d.HasChanges("permissions", "owner", "group") {
+		err := applyTmpfsRootFromResourceData(fsys, d, path)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return resourceTmpfsRead(ctx, d, meta)
+}
+
+func resourceTmpfsDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fsys := withContext(ctx, meta.(*providerConfig).fs)
+	path := d.Get("path").(string)
+
+	// Unmounting discards the content, and leaves the directory in place
+	m, err := tmpfsMount(fsys, path)
+	if err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("error reading mounts: %s", err))
+	}
+	if m != nil {
+		_, err := fsys.Run(ctx, commandLine(fsys, "umount", m.mountpoint))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("error unmounting tmpfs on %s: %s", path, err))
+		}
+	}
+
+	// Remove ID from state
+	d.SetId("")
+
+	return diags
+}
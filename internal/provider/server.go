@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-mux/tf5to6server"
+	"github.com/hashicorp/terraform-plugin-mux/tf6muxserver"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// NewServer returns the provider as it is served to Terraform: the SDK
+// provider upgraded to protocol 6 and muxed with the framework provider.
+func NewServer(ctx context.Context) (func() tfprotov6.ProviderServer, error) {
+	sdk := New()
+	upgraded, err := tf5to6server.UpgradeServer(ctx, func() tfprotov5.ProviderServer {
+		return schema.NewGRPCProviderServer(sdk)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mux, err := tf6muxserver.NewMuxServer(ctx,
+		func() tfprotov6.ProviderServer { return upgraded },
+		providerserver.NewProtocol6(NewFramework(sdk)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return mux.ProviderServer, nil
+}
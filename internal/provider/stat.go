@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// fileOwner returns the numeric owner and group of a file on any target.
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	switch stat := info.Sys().(type) {
+	case *sftp.FileStat:
+		return int(stat.UID), int(stat.GID), true
+	case *dockerFileStat:
+		return stat.uid, stat.gid, true
+	}
+	return localFileOwner(info)
+}
+
+// fileAccessTime returns the last access time of a file on any target.
+func fileAccessTime(info os.FileInfo) time.Time {
+	switch stat := info.Sys().(type) {
+	case *sftp.FileStat:
+		return time.Unix(int64(stat.Atime), 0)
+	case *dockerFileStat:
+		return stat.atime
+	}
+	return localFileAccessTime(info)
+}
+
+// fileInode returns the inode number of a file on any target.
+func fileInode(info os.FileInfo) (uint64, bool) {
+	if stat, ok := info.Sys().(*dockerFileStat); ok {
+		return stat.inode, true
+	}
+	return localFileInode(info)
+}
+
+// fileFlags returns the file flags of macOS and FreeBSD, which only local
+// files have.
+func fileFlags(info os.FileInfo) (uint32, bool) {
+	return localFileFlags(info)
+}
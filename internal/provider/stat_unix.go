@@ -0,0 +1,26 @@
+//go:build !windows
+
+package provider
+
+import (
+	"os"
+	"syscall"
+)
+
+// localFileOwner returns the numeric owner and group of a file.
+func localFileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+// localFileInode returns the inode number of a file.
+func localFileInode(info os.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Ino), true
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package provider
+
+import "os"
+
+// localFileOwner is not supported on Windows, where ownership is expressed
+// through security descriptors instead of numeric IDs.
+func localFileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
+
+func localFileInode(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// withPathID upgrades states written before the ID of r became the path in
+// its key attribute, rather than the SHA-256 of that path. v0 is r as it was
+// then. Attributes added since then get their defaults, so that the upgrade
+// doesn't show up as a change in the next plan.
+func withPathID(r *schema.Resource, key string, v0 *schema.Resource) *schema.Resource {
+	r.SchemaVersion = 1
+	r.StateUpgraders = []schema.StateUpgrader{
+		{
+			Version: 0,
+			Type:    v0.CoreConfigSchema().ImpliedType(),
+			Upgrade: func(ctx context.Context, rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+				// The ID can only be resolved the way the provider is
+				// configured to resolve paths once it's configured. Until
+				// then, it is the path as it is, which Read resolves
+				if path, ok := rawState[key].(string); ok && path != "" {
+					if conf, ok := meta.(*providerConfig); ok {
+						rawState["id"] = pathID(conf.fs, path)
+					} else {
+						rawState["id"] = filepath.Clean(path)
+					}
+				}
+				for k, s := range r.Schema {
+					if rawState[k] == nil && s.Default != nil {
+						rawState[k] = s.Default
+					}
+				}
+				return rawState, nil
+			},
+		},
+	}
+	return r
+}
+
+// resourceFileV0 is the schema of filesystem_file in version 0 of its state.
+func resourceFileV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"path":             {Type: schema.TypeString, Required: true},
+			"content":          {Type: schema.TypeString, Optional: true},
+			"store_content":    {Type: schema.TypeBool, Optional: true},
+			"sha256":           {Type: schema.TypeString, Computed: true},
+			"atomic":           {Type: schema.TypeBool, Optional: true},
+			"sync_directory":   {Type: schema.TypeBool, Optional: true},
+			"backup":           {Type: schema.TypeBool, Optional: true},
+			"backup_path":      {Type: schema.TypeString, Optional: true, Computed: true},
+			"backup_suffix":    {Type: schema.TypeString, Optional: true},
+			"permissions":      {Type: schema.TypeString, Optional: true, Computed: true},
+			"owner":            {Type: schema.TypeString, Optional: true, Computed: true},
+			"group":            {Type: schema.TypeString, Optional: true, Computed: true},
+			"acl":              aclSchemaV0(),
+			"destroy_behavior": {Type: schema.TypeString, Optional: true},
+		},
+	}
+}
+
+// resourceDirectoryV0 is the schema of filesystem_directory in version 0 of
+// its state.
+func resourceDirectoryV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"path":                  {Type: schema.TypeString, Required: true},
+			"permissions":           {Type: schema.TypeString, Optional: true, Computed: true},
+			"owner":                 {Type: schema.TypeString, Optional: true, Computed: true},
+			"group":                 {Type: schema.TypeString, Optional: true, Computed: true},
+			"force_destroy":         {Type: schema.TypeBool, Optional: true},
+			"recursive":             {Type: schema.TypeBool, Optional: true},
+			"file_permissions":      {Type: schema.TypeString, Optional: true},
+			"directory_permissions": {Type: schema.TypeString, Optional: true},
+			"acl":                   aclSchemaV0(),
+			"destroy_behavior":      {Type: schema.TypeString, Optional: true},
+		},
+	}
+}
+
+// aclSchemaV0 is the acl block in version 0 of the states.
+func aclSchemaV0() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"owner":     {Type: schema.TypeString, Optional: true},
+				"protected": {Type: schema.TypeBool, Optional: true},
+				"entry": {
+					Type:     schema.TypeSet,
+					Optional: true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"principal":   {Type: schema.TypeString, Required: true},
+							"type":        {Type: schema.TypeString, Optional: true},
+							"rights":      {Type: schema.TypeSet, Required: true, Elem: &schema.Schema{Type: schema.TypeString}},
+							"inheritance": {Type: schema.TypeSet, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithPathID(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a", "..", "file")
+	sum := sha256.Sum256([]byte(path))
+	oldID := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name     string
+		resource string
+		meta     interface{}
+		state    map[string]interface{}
+		want     string
+	}{
+		{
+			name:     "file",
+			resource: "filesystem_file",
+			meta:     &providerConfig{fs: localFileSystem{}},
+			state:    map[string]interface{}{"id": oldID, "path": path},
+			want:     filepath.Join(dir, "file"),
+		},
+		{
+			name:     "directory",
+			resource: "filesystem_directory",
+			meta:     &providerConfig{fs: localFileSystem{}},
+			state:    map[string]interface{}{"id": oldID, "path": path},
+			want:     filepath.Join(dir, "file"),
+		},
+		{
+			name:     "unconfigured provider",
+			resource: "filesystem_file",
+			state:    map[string]interface{}{"id": oldID, "path": path},
+			want:     filepath.Join(dir, "file"),
+		},
+		{
+			name:     "no path",
+			resource: "filesystem_file",
+			meta:     &providerConfig{fs: localFileSystem{}},
+			state:    map[string]interface{}{"id": oldID},
+			want:     oldID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New().ResourcesMap[tt.resource]
+			if r.SchemaVersion != 1 || len(r.StateUpgraders) != 1 || r.StateUpgraders[0].Version != 0 {
+				t.Fatalf("%s has no upgrader from version 0", tt.resource)
+			}
+
+			state, err := r.StateUpgraders[0].Upgrade(context.Background(), tt.state, tt.meta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state["id"] != tt.want {
+				t.Errorf("id = %v, want %s", state["id"], tt.want)
+			}
+
+			// Attributes added since version 0 get their defaults
+			for k, s := range r.Schema {
+				if s.Default != nil && state[k] != s.Default {
+					t.Errorf("%s = %v, want %v", k, state[k], s.Default)
+				}
+			}
+		})
+	}
+}
+
+func TestWithPathIDKeepsAttributes(t *testing.T) {
+	r := New().ResourcesMap["filesystem_file"]
+	state := map[string]interface{}{"id": "x", "path": "file", "atomic": false}
+	state, err := r.StateUpgraders[0].Upgrade(context.Background(), state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state["atomic"] != false {
+		t.Errorf("atomic = %v, want false", state["atomic"])
+	}
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// streamBufferSize is the buffer that file contents are streamed through,
+// so that copying or hashing a file takes the same memory whatever its size.
+const streamBufferSize = 1 << 20
+
+// progressInterval is how often a long copy logs how far it got.
+const progressInterval = 10 * time.Second
+
+// streamCopy copies src to dst through a fixed-size buffer, logging the
+// progress of op on path every progressInterval. size is the expected
+// length, or -1 when it isn't known.
+func streamCopy(dst io.Writer, src io.Reader, op, path string, size int64) (int64, error) {
+	p := &progressWriter{w: dst, op: op, path: path, size: size, start: time.Now()}
+	p.next = p.start.Add(progressInterval)
+	n, err := io.CopyBuffer(p, src, make([]byte, streamBufferSize))
+	if err == nil && time.Since(p.start) >= progressInterval {
+		log.Printf("[INFO] %s %s: done, %s in %s", op, path, formatBytes(n), time.Since(p.start).Round(time.Second))
+	}
+	return n, err
+}
+
+type progressWriter struct {
+	w        io.Writer
+	op, path string
+	size     int64
+	written  int64
+	start    time.Time
+	next     time.Time
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+
+	if now := time.Now(); now.After(p.next) {
+		p.next = now.Add(progressInterval)
+		if p.size > 0 {
+			log.Printf("[INFO] %s %s: %s of %s (%d%%)", p.op, p.path, formatBytes(p.written), formatBytes(p.size), p.written*100/p.size)
+		} else {
+			log.Printf("[INFO] %s %s: %s", p.op, p.path, formatBytes(p.written))
+		}
+	}
+	return n, err
+}
+
+// formatBytes returns n in the largest binary unit that keeps it above 1.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func validateValidateCommand(v interface{}, k string) ([]string, []error) {
+	if !strings.Contains(v.(string), "%s") {
+		return nil, []error{fmt.Errorf("%s must contain %%s, which is replaced by the path of the file to validate", k)}
+	}
+	return nil, nil
+}
+
+// validateCommandCheck returns a check that runs command with %s replaced
+// by the file to check, or nil when there is no command.
+func validateCommandCheck(ctx context.Context, fsys fileSystem, command string) func(tmp string) error {
+	if command == "" {
+		return nil
+	}
+	return func(tmp string) error {
+		target, err := fsys.Resolve(tmp)
+		if err != nil {
+			return fmt.Errorf("validate_command failed: %s", err)
+		}
+		if _, err := fsys.Run(ctx, strings.ReplaceAll(command, "%s", fsys.Quote(target))); err != nil {
+			return fmt.Errorf("validate_command failed: %s", err)
+		}
+		return nil
+	}
+}
+
+// checkTempFile writes data to a temporary file next to path, calls check
+// with it and removes it again.
+func checkTempFile(fsys fileSystem, path string, data []byte, perm os.FileMode, check func(tmp string) error) error {
+	tmp, err := tempName(path)
+	if err != nil {
+		return err
+	}
+	defer fsys.Remove(tmp)
+
+	if err := fsys.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return check(tmp)
+}
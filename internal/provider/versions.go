@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// versionsDir returns where the earlier versions of the file at path are
+// kept: versions_dir, or the directory of the file when it isn't set.
+func versionsDir(d *schema.ResourceData, path string) string {
+	if dir := d.Get("versions_dir").(string); dir != "" {
+		return dir
+	}
+	return filepath.Dir(path)
+}
+
+// customizeDiffLatestVersion plans a new latest_version_path for files that
+// keep versions and are about to be rewritten.
+func customizeDiffLatestVersion(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" || d.Get("keep_versions").(int) == 0 || !d.Get("manage_content").(bool) {
+		return nil
+	}
+	if !d.HasChanges(append([]string{"content", "sha256"}, fileFormatKeys...)...) {
+		return nil
+	}
+	return d.SetNewComputed("latest_version_path")
+}
+
+// archiveVersionFromResourceData copies the file at path, which is about to
+// be rewritten, to a timestamped name in versions_dir when keep_versions is
+// set, then removes the oldest versions beyond keep_versions. A symlink is
+// archived as the file it points to. fsys is the filesystem without the
+// provider's backups, as only keep_versions decides which versions are kept.
+func archiveVersionFromResourceData(fsys fileSystem, d *schema.ResourceData, path string, dirPerm os.FileMode) error {
+	keep := d.Get("keep_versions").(int)
+	if keep == 0 {
+		return nil
+	}
+
+	// Nothing to archive yet
+	if _, err := fsys.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	source, err := resolveSymlinks(fsys, path, true)
+	if err != nil {
+		return err
+	}
+
+	dir := versionsDir(d, path)
+	if err := fsys.MkdirAll(dir, dirPerm); err != nil {
+		return fmt.Errorf("error creating directory %s: %s", dir, err)
+	}
+
+	version := filepath.Join(dir, timestampedName(path, time.Now(), ""))
+	err = copyPath(fsys, source, version, copyOptions{mode: true, timestamps: true})
+	if err != nil {
+		return err
+	}
+	if err := d.Set("latest_version_path", version); err != nil {
+		return err
+	}
+
+	return pruneTimestamped(fsys, dir, path, "", keep)
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !darwin
+
+package provider
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func getXattr(path, name string) ([]byte, error) {
+	return nil, fmt.Errorf("extended attributes are not supported on %s", runtime.GOOS)
+}
+
+func setXattr(path, name string, value []byte) error {
+	return fmt.Errorf("extended attributes are not supported on %s", runtime.GOOS)
+}
+
+func removeXattr(path, name string) error {
+	return fmt.Errorf("extended attributes are not supported on %s", runtime.GOOS)
+}
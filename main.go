@@ -1,12 +1,31 @@
 package main
 
 import (
-	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
-	"github.com/thirai/terraform-provider-filesystem/internal/provider"
+	"context"
+	"flag"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6/tf6server"
+	"github.com/jedipunkz/terraform-provider-filesystem/internal/provider"
 )
 
 func main() {
-	plugin.Serve(&plugin.ServeOpts{
-		ProviderFunc: provider.New,
-	})
-}
\ No newline at end of file
+	var debug bool
+	flag.BoolVar(&debug, "debug", false, "Start the provider in debug mode for use with a debugger")
+	flag.Parse()
+
+	server, err := provider.NewServer(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var opts []tf6server.ServeOpt
+	if debug {
+		opts = append(opts, tf6server.WithManagedDebug())
+	}
+
+	err = tf6server.Serve("registry.terraform.io/jedipunkz/filesystem", server, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+}